@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/NP-Dat/net-centric-project/internal/client"
+	"github.com/NP-Dat/net-centric-project/internal/network"
 	"github.com/NP-Dat/net-centric-project/pkg/logger"
 )
 
@@ -18,6 +19,11 @@ func main() {
 	host := flag.String("host", "localhost", "Server host to connect to")
 	port := flag.Int("port", 8080, "Server port to connect to")
 	logLevel := flag.String("logLevel", "info", "Log level (debug, info, warn, error)")
+	framing := flag.String("framing", "binary", "Message framing to request from the server (binary, json)")
+	maxFrameSize := flag.Int("maxFrameSize", network.DefaultMaxFrameSize, "Largest message in bytes accepted from the server")
+	useTLS := flag.Bool("tls", false, "Connect to the server over TLS")
+	tlsCA := flag.String("tlsCA", "", "CA certificate to verify the server with (e.g. the server's development certificate)")
+	tlsInsecure := flag.Bool("tlsInsecureSkipVerify", false, "Do not verify the server certificate (development only)")
 
 	flag.Parse()
 
@@ -30,6 +36,21 @@ func main() {
 	// Create and connect client
 	c := client.NewClient(*host, *port)
 	c.SetupDefaultHandlers()
+	c.MaxFrameSize = *maxFrameSize
+	c.TLS = *useTLS || *tlsCA != "" || *tlsInsecure
+	c.TLSCAFile = *tlsCA
+	c.InsecureSkipVerify = *tlsInsecure
+	if *tlsInsecure {
+		logger.Client.Warn("TLS certificate verification is disabled")
+	}
+	switch *framing {
+	case "binary":
+		c.Framing = network.FramingLengthPrefixed
+	case "json":
+		c.Framing = network.FramingJSONLine
+	default:
+		logger.Client.Fatal("Unknown framing %q, expected binary or json", *framing)
+	}
 
 	fmt.Printf("Connecting to server at %s:%d...\n", *host, *port)
 	err := c.Connect()
@@ -38,6 +59,11 @@ func main() {
 	}
 	fmt.Println("Connected to server!")
 
+	// Pick up where a recent session left off without asking for the password again
+	if c.LoginWithStoredSession() {
+		fmt.Printf("Resuming saved session of %s...\n", c.Username)
+	}
+
 	// Start CLI loop in a goroutine
 	go cliLoop(c)
 
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/client"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/pkg/logger"
+)
+
+// config is what every scripted client is told to do
+type config struct {
+	host         string
+	port         int
+	mode         string
+	bot          string
+	prefix       string
+	password     string
+	matches      int
+	timeout      time.Duration
+	matchTimeout time.Duration
+	troop        string
+	troopMana    int
+}
+
+func main() {
+	// Command line flags
+	host := flag.String("host", "localhost", "Server host to connect to")
+	port := flag.Int("port", 8080, "Server port to connect to")
+	clients := flag.Int("clients", 10, "Number of scripted clients")
+	matches := flag.Int("matches", 1, "Matches each client plays")
+	mode := flag.String("mode", network.GameModeSimple, "Game mode to play (simple or enhanced)")
+	bot := flag.String("bot", "", "Play against server bots of this difficulty (easy, medium or hard) instead of each other")
+	prefix := flag.String("prefix", "load", "Username prefix, clients are <prefix>1 to <prefix>N")
+	password := flag.String("password", "loadtest123", "Password of the load test accounts")
+	timeout := flag.Duration("timeout", client.DefaultRequestTimeout, "How long a request waits for its answer")
+	matchTimeout := flag.Duration("matchTimeout", 5*time.Minute, "How long a client waits for its match to end")
+	rampUp := flag.Duration("rampUp", 0, "Spread the client connections over this long")
+	troop := flag.String("troop", "pawn", "Troop deployed in Enhanced mode")
+	troopMana := flag.Int("troopMana", 3, "Mana needed to deploy -troop in Enhanced mode")
+	logLevel := flag.String("logLevel", "off", "Client log level (debug, info, warn, error, off)")
+
+	flag.Parse()
+
+	if *clients < 1 || *matches < 1 {
+		log.Fatalf("-clients and -matches must be at least 1")
+	}
+	if *mode != network.GameModeSimple && *mode != network.GameModeEnhanced {
+		log.Fatalf("Invalid -mode %q, must be simple or enhanced", *mode)
+	}
+	if *bot == "" && *clients%2 != 0 {
+		fmt.Println("Warning: an odd number of clients leaves one waiting for an opponent")
+	}
+	setLogLevel(*logLevel)
+
+	cfg := &config{
+		host:         *host,
+		port:         *port,
+		mode:         *mode,
+		bot:          *bot,
+		prefix:       *prefix,
+		password:     *password,
+		matches:      *matches,
+		timeout:      *timeout,
+		matchTimeout: *matchTimeout,
+		troop:        *troop,
+		troopMana:    *troopMana,
+	}
+
+	fmt.Printf("Starting %d clients against %s:%d, %d %s match(es) each...\n", *clients, *host, *port, *matches, *mode)
+	rec := newRecorder()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < *clients; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			time.Sleep(*rampUp * time.Duration(index) / time.Duration(*clients))
+			runClient(cfg, index, rec)
+		}(i)
+	}
+	wg.Wait()
+
+	rec.report(time.Since(start), *clients)
+	if rec.errorCount() > 0 {
+		os.Exit(1)
+	}
+}
+
+// runClient connects one scripted client, logs it in and plays its matches
+func runClient(cfg *config, index int, rec *recorder) {
+	h := client.NewHeadless(cfg.host, cfg.port)
+	h.Timeout = cfg.timeout
+
+	start := time.Now()
+	err := h.Connect()
+	rec.observe("connect", start, err)
+	if err != nil {
+		return
+	}
+	defer h.Close()
+
+	username := fmt.Sprintf("%s%d", cfg.prefix, index+1)
+	if err := logIn(h, username, cfg.password, rec); err != nil {
+		return
+	}
+
+	for i := 0; i < cfg.matches; i++ {
+		if err := playMatch(h, cfg, rec); err != nil {
+			rec.fail("match", err)
+			return
+		}
+		rec.matchPlayed()
+	}
+}
+
+// logIn registers the account, or logs in if it exists from an earlier run
+func logIn(h *client.Headless, username, password string, rec *recorder) error {
+	start := time.Now()
+	_, err := h.Register(username, password)
+	var serverErr *client.ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != network.AuthCodeUsernameTaken {
+		rec.observe("register", start, err)
+		return err
+	}
+
+	start = time.Now()
+	_, err = h.Login(username, password)
+	rec.observe("login", start, err)
+	return err
+}
+
+// playMatch finds an opponent and plays until the game is over
+func playMatch(h *client.Headless, cfg *config, rec *recorder) error {
+	start := time.Now()
+	if cfg.bot != "" {
+		_, err := h.PlayBot(cfg.bot, cfg.mode)
+		rec.observe("play_bot", start, err)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err := h.JoinQueue(cfg.mode)
+		rec.observe("join_queue", start, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	deadline := time.NewTimer(cfg.matchTimeout)
+	defer deadline.Stop()
+
+	var lastDeploy time.Time
+	for {
+		var event client.Event
+		select {
+		case event = <-h.Events():
+		case <-h.Done():
+			return client.ErrDisconnected
+		case <-deadline.C:
+			return fmt.Errorf("game not over after %v", cfg.matchTimeout)
+		}
+
+		troopID := ""
+		switch payload := event.Payload.(type) {
+		case *network.MatchFoundPayload:
+			if err := h.AnswerMatch(payload.MatchID, true); err != nil {
+				return err
+			}
+		case *network.GameStartPayload:
+			if cfg.bot == "" {
+				rec.record("matchmaking", time.Since(start))
+			}
+		case *network.TroopChoicesPayload:
+			// Only the player whose turn it is gets choices in Simple mode
+			if cfg.mode == network.GameModeSimple && len(payload.Choices) > 0 {
+				troopID = payload.Choices[0].ID
+			}
+		case *network.GameStatePayload:
+			// Skip updates sent before our last deployment, their mana is out of date
+			if cfg.mode == network.GameModeEnhanced && payload.YourMana >= cfg.troopMana && event.Received.After(lastDeploy) {
+				troopID = cfg.troop
+			}
+		case *network.GameOverPayload:
+			return nil
+		case *network.ErrorPayload:
+			rec.fail("server", &client.ServerError{Code: payload.Code, Message: payload.Message})
+		}
+		if troopID == "" {
+			continue
+		}
+
+		deployStart := time.Now()
+		reply, err := h.Deploy(troopID)
+		rec.observe("deploy", deployStart, err)
+		lastDeploy = time.Now()
+		var serverErr *client.ServerError
+		if err != nil && !errors.As(err, &serverErr) {
+			return err
+		}
+		if reply.Type == network.MessageTypeGameOver {
+			return nil
+		}
+	}
+}
+
+// recorder collects latencies and errors from all clients
+type recorder struct {
+	mutex     sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	firstErr  map[string]string
+	requests  int
+	matches   int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		firstErr:  make(map[string]string),
+	}
+}
+
+// observe records the outcome of a request started at start
+func (r *recorder) observe(op string, start time.Time, err error) {
+	if err != nil {
+		r.fail(op, err)
+	} else {
+		r.record(op, time.Since(start))
+	}
+	r.mutex.Lock()
+	r.requests++
+	r.mutex.Unlock()
+}
+
+// record adds a latency sample to an operation
+func (r *recorder) record(op string, latency time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.latencies[op] = append(r.latencies[op], latency)
+}
+
+// fail counts an error of an operation, remembering the first one for the report
+func (r *recorder) fail(op string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errors[op]++
+	if _, exists := r.firstErr[op]; !exists {
+		r.firstErr[op] = err.Error()
+	}
+}
+
+// matchPlayed counts a match a client played to the end
+func (r *recorder) matchPlayed() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.matches++
+}
+
+// errorCount returns the number of errors of all operations
+func (r *recorder) errorCount() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	total := 0
+	for _, count := range r.errors {
+		total += count
+	}
+	return total
+}
+
+// report prints the latency percentiles, throughput and errors
+func (r *recorder) report(elapsed time.Duration, clients int) {
+	total := r.errorCount()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seconds := elapsed.Seconds()
+	fmt.Printf("\n📈 ===== LOAD TEST RESULTS ===== 📈\n")
+	fmt.Printf("Clients: %d  |  Duration: %v\n", clients, elapsed.Round(time.Millisecond))
+	fmt.Printf("Matches played: %d (%.2f/s)  |  Requests: %d (%.1f/s)\n",
+		r.matches, float64(r.matches)/seconds, r.requests, float64(r.requests)/seconds)
+
+	ops := make([]string, 0, len(r.latencies))
+	for op := range r.latencies {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	fmt.Printf("\n%-12s %7s %10s %10s %10s %10s\n", "Operation", "Count", "p50", "p90", "p99", "max")
+	for _, op := range ops {
+		samples := r.latencies[op]
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		fmt.Printf("%-12s %7d %10v %10v %10v %10v\n", op, len(samples),
+			percentile(samples, 50), percentile(samples, 90), percentile(samples, 99), samples[len(samples)-1].Round(time.Microsecond))
+	}
+
+	fmt.Printf("\nErrors: %d\n", total)
+	errOps := make([]string, 0, len(r.errors))
+	for op := range r.errors {
+		errOps = append(errOps, op)
+	}
+	sort.Strings(errOps)
+	for _, op := range errOps {
+		fmt.Printf("  %-12s %5d  (first: %s)\n", op, r.errors[op], r.firstErr[op])
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)].Round(time.Microsecond)
+}
+
+// setLogLevel sets the level of the client loggers
+func setLogLevel(levelStr string) {
+	var logLevel logger.LogLevel
+	switch strings.ToLower(levelStr) {
+	case "debug":
+		logLevel = logger.DEBUG
+	case "info":
+		logLevel = logger.INFO
+	case "warn":
+		logLevel = logger.WARN
+	case "error":
+		logLevel = logger.ERROR
+	case "off":
+		// Closing hundreds of connections logs a read error for each
+		logLevel = logger.FATAL
+	default:
+		log.Fatalf("Unknown log level %q, expected debug, info, warn, error or off", levelStr)
+	}
+	logger.SetGlobalLogLevel(logLevel)
+}
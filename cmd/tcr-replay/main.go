@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/client"
+	"github.com/NP-Dat/net-centric-project/internal/game"
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+func main() {
+	// Command line flags
+	gameID := flag.String("game", "", "ID of the game to replay (reads data/replays/<game>.json)")
+	file := flag.String("file", "", "Path to a replay file (overrides -game)")
+	basePath := flag.String("basePath", getDefaultBasePath(), "Base path for config and data files")
+	viewer := flag.Int("viewer", 1, "Show the board from this player's point of view (1 or 2)")
+	showState := flag.Bool("state", true, "Print the board state after every command")
+
+	flag.Parse()
+
+	replayPath := *file
+	if replayPath == "" {
+		if *gameID == "" {
+			fmt.Println("Usage: tcr-replay -game <gameID> | -file <replay.json>")
+			flag.PrintDefaults()
+			os.Exit(2)
+		}
+		replayPath = persistence.ReplayPath(*basePath, *gameID)
+	}
+	if *viewer != 1 && *viewer != 2 {
+		log.Fatalf("Invalid -viewer %d, must be 1 or 2", *viewer)
+	}
+
+	replay, err := persistence.LoadReplay(replayPath)
+	if err != nil {
+		log.Fatalf("Failed to load replay: %v", err)
+	}
+
+	printHeader(replay)
+
+	if replay.GameMode != string(game.GameModeSimple) {
+		// Real-time games depend on tick timing and cannot be re-simulated; show the command log
+		fmt.Printf("\n%s mode games cannot be re-simulated. Recorded commands:\n", replay.GameMode)
+		for _, cmd := range replay.Commands {
+			fmt.Printf("  [+%6.1fs] %s: %s %s\n", cmd.Time.Sub(replay.StartTime).Seconds(),
+				replay.Players[cmd.PlayerIndex].Username, cmd.Type, cmd.TroopID)
+		}
+		return
+	}
+
+	viewerUsername := replay.Players[*viewer-1].Username
+	_, err = game.ReplaySimple(replay, func(step game.ReplayStep) {
+		printStep(replay, step, viewerUsername, *showState)
+	})
+	if err != nil {
+		log.Fatalf("Failed to replay game: %v", err)
+	}
+}
+
+// printHeader prints the recorded match details
+func printHeader(replay *models.Replay) {
+	fmt.Println("\n🎬 ========= MATCH REPLAY ========= 🎬")
+	fmt.Printf("Game ID: %s\n", replay.GameID)
+	fmt.Printf("Mode: %s\n", replay.GameMode)
+	fmt.Printf("Seed: %d\n", replay.Seed)
+	fmt.Printf("Players: %s (Lv %d) vs %s (Lv %d)\n",
+		replay.Players[0].Username, replay.Players[0].Level,
+		replay.Players[1].Username, replay.Players[1].Level)
+	fmt.Printf("Played: %s (%s)\n", replay.StartTime.Format("2006-01-02 15:04:05"),
+		replay.EndTime.Sub(replay.StartTime).Round(time.Second))
+	if replay.Winner != "" {
+		fmt.Printf("Recorded result: %s wins (%s)\n", replay.Winner, replay.EndReason)
+	} else if replay.EndReason != "" {
+		fmt.Printf("Recorded result: %s\n", replay.EndReason)
+	}
+	fmt.Printf("Commands: %d\n", len(replay.Commands))
+}
+
+// printStep prints the events and state produced by one replayed command
+func printStep(replay *models.Replay, step game.ReplayStep, viewerUsername string, showState bool) {
+	if step.Command == nil {
+		fmt.Println("\n===== INITIAL GAME STATE =====")
+	} else {
+		cmd := step.Command
+		fmt.Printf("\n▶ [+%6.1fs] %s: %s %s\n", cmd.Time.Sub(replay.StartTime).Seconds(),
+			replay.Players[cmd.PlayerIndex].Username, cmd.Type, cmd.TroopID)
+	}
+
+	if step.Err != nil {
+		fmt.Printf("  ❌ Rejected: %v\n", step.Err)
+	}
+	for _, event := range step.Events {
+		fmt.Printf("  📢 %s\n", event.Message)
+	}
+
+	if showState && step.Err == nil {
+		client.PrintGameState(step.State, viewerUsername)
+	}
+
+	if step.GameOver {
+		fmt.Println("\n🏁 ========= GAME OVER ========= 🏁")
+		if step.WinnerIndex >= 0 {
+			fmt.Printf("Winner: %s\n", replay.Players[step.WinnerIndex].Username)
+		} else {
+			fmt.Println("It's a draw!")
+		}
+		return
+	}
+
+	if step.Choices != nil {
+		fmt.Printf("  📋 Choices offered to %s:", replay.Players[step.ChoicesFor].Username)
+		for _, choice := range step.Choices.Choices {
+			fmt.Printf(" %s", choice.ID)
+		}
+		fmt.Println()
+	}
+}
+
+// getDefaultBasePath returns the default base path for config and data files
+func getDefaultBasePath() string {
+	// Get the current working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Printf("Warning: Failed to get current working directory: %v", err)
+		return "."
+	}
+
+	// If we're in cmd/tcr-replay, go up two levels
+	if _, err := os.Stat(filepath.Join(cwd, "..", "..", "config", "towers.json")); err == nil {
+		return filepath.Join(cwd, "..", "..")
+	}
+
+	return cwd
+}
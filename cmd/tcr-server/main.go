@@ -7,8 +7,11 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
 	"github.com/NP-Dat/net-centric-project/internal/server"
 	"github.com/NP-Dat/net-centric-project/pkg/logger"
 )
@@ -19,6 +22,24 @@ func main() {
 	port := flag.Int("port", 8080, "Port to listen on")
 	basePath := flag.String("basePath", getDefaultBasePath(), "Base path for config and data files")
 	logLevel := flag.String("logLevel", "info", "Log level (debug, info, warn, error)")
+	turnTimeout := flag.Duration("turnTimeout", server.DefaultTurnTimeout, "Time limit per turn in Simple mode (0 disables)")
+	maxTurnTimeouts := flag.Int("maxTurnTimeouts", server.DefaultMaxTurnTimeouts, "Consecutive turn timeouts before a player forfeits (0 never forfeits)")
+	disconnectGrace := flag.Duration("disconnectGrace", server.DefaultDisconnectGracePeriod, "Time a disconnected player has to return before forfeiting")
+	maxFrameSize := flag.Int("maxFrameSize", network.DefaultMaxFrameSize, "Largest message in bytes accepted from a client")
+	ratingWindow := flag.Int("ratingWindow", server.DefaultRatingWindow, "Largest rating gap matchmaking accepts as soon as a player joins")
+	ratingWindowGrowth := flag.Int("ratingWindowGrowth", server.DefaultRatingWindowGrowth, "Rating points the matchmaking window widens per second of waiting")
+	readyCheckTimeout := flag.Duration("readyCheckTimeout", server.DefaultReadyCheckTimeout, "Time matched players have to accept a match (0 starts games without asking)")
+	queueStatusInterval := flag.Duration("queueStatusInterval", server.DefaultQueueStatusInterval, "How often waiting players are sent their queue position (0 only on request)")
+	challengeTimeout := flag.Duration("challengeTimeout", server.DefaultChallengeTimeout, "How long a challenge to another player waits for an answer")
+	botFallbackWait := flag.Duration("botFallbackWait", server.DefaultBotFallbackWait, "Matchmaking wait after which a player gets a bot opponent (0 never)")
+	sessionTokenTTL := flag.Duration("sessionTokenTTL", server.DefaultSessionTokenTTL, "How long a login session token lets a player reconnect without their password")
+	autoCreateAccounts := flag.Bool("autoCreateAccounts", false, "Create an account when an unknown username logs in, instead of requiring 'register'")
+	tlsCert := flag.String("tlsCert", "", "TLS certificate file (enables TLS together with -tlsKey)")
+	tlsKey := flag.String("tlsKey", "", "TLS private key file")
+	tlsGenerate := flag.Bool("tlsGenerateDevCert", false, "Generate a self-signed development certificate at -tlsCert/-tlsKey if they do not exist")
+	playerStoreKind := flag.String("playerStore", "file", "Player storage backend ("+strings.Join(persistence.PlayerStoreKinds(), ", ")+")")
+	sessionSecretFile := flag.String("sessionSecretFile", "", "File keeping the session token secret across restarts, unless "+server.SessionSecretEnv+" is set (default <basePath>/data/session_secret)")
+	playerStorePath := flag.String("playerStorePath", "", "Database file for the sqlite player store (default <basePath>/data/players.db)")
 
 	flag.Parse()
 
@@ -29,9 +50,52 @@ func main() {
 	logger.Server.Info("Server starting up...")
 	logger.Server.Info("Host: %s, Port: %d", *host, *port)
 	logger.Server.Info("Base path: %s", *basePath)
+	logger.Server.Info("Turn timeout: %v, max consecutive timeouts: %d", *turnTimeout, *maxTurnTimeouts)
+	logger.Server.Info("Disconnect grace period: %v", *disconnectGrace)
+	logger.Server.Info("Max frame size: %d bytes", *maxFrameSize)
+	logger.Server.Info("Rating window: %d, widening by %d per second of waiting", *ratingWindow, *ratingWindowGrowth)
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		logger.Server.Fatal("-tlsCert and -tlsKey must be given together")
+	}
+	if *tlsGenerate {
+		if *tlsCert == "" {
+			logger.Server.Fatal("-tlsGenerateDevCert needs -tlsCert and -tlsKey to know where to write")
+		}
+		if err := ensureDevCert(*tlsCert, *tlsKey, *host); err != nil {
+			logger.Server.Fatal("Failed to generate development certificate: %v", err)
+		}
+	}
+	if *tlsCert != "" {
+		logger.Server.Info("TLS enabled with certificate %s", *tlsCert)
+	}
+
+	playerStore, err := persistence.OpenPlayerStore(*playerStoreKind, *basePath, *playerStorePath)
+	if err != nil {
+		logger.Server.Fatal("Failed to open player store: %v", err)
+	}
+	logger.Server.Info("Player store: %s", *playerStoreKind)
 
 	// Create and start the server
 	srv := server.NewServer(*host, *port, *basePath)
+	srv.PlayerStore = playerStore
+	srv.TurnTimeout = *turnTimeout
+	srv.MaxTurnTimeouts = *maxTurnTimeouts
+	srv.DisconnectGracePeriod = *disconnectGrace
+	srv.MaxFrameSize = *maxFrameSize
+	srv.SessionTokenTTL = *sessionTokenTTL
+	if *sessionSecretFile != "" {
+		srv.SessionSecretFile = *sessionSecretFile
+	}
+	srv.RatingWindow = *ratingWindow
+	srv.RatingWindowGrowth = *ratingWindowGrowth
+	srv.ReadyCheckTimeout = *readyCheckTimeout
+	srv.QueueStatusInterval = *queueStatusInterval
+	srv.ChallengeTimeout = *challengeTimeout
+	srv.BotFallbackWait = *botFallbackWait
+	srv.AutoCreateAccounts = *autoCreateAccounts
+	srv.TLSCertFile = *tlsCert
+	srv.TLSKeyFile = *tlsKey
 	if err := srv.Start(); err != nil {
 		logger.Server.Fatal("Failed to start server: %v", err)
 	}
@@ -49,6 +113,26 @@ func main() {
 	logger.Server.Info("Server stopped gracefully")
 }
 
+// ensureDevCert generates a self-signed certificate for host unless both files already exist
+func ensureDevCert(certFile, keyFile, host string) error {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if certErr == nil && keyErr == nil {
+		logger.Server.Info("Using existing development certificate %s", certFile)
+		return nil
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host != "" && host != "localhost" {
+		hosts = append(hosts, host)
+	}
+	if err := network.GenerateDevCert(certFile, keyFile, hosts); err != nil {
+		return err
+	}
+	logger.Server.Info("Generated self-signed development certificate %s for %v", certFile, hosts)
+	return nil
+}
+
 // getDefaultBasePath returns the default base path for config and data files
 func getDefaultBasePath() string {
 	// Get the current working directory
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+// tcr-usercheck reports player files that break the username policy, so they can be renamed
+// (or the accounts retired) before the server refuses to load them. It changes nothing.
+func main() {
+	basePath := flag.String("basePath", getDefaultBasePath(), "Base path for config and data files")
+
+	flag.Parse()
+
+	dir := persistence.PlayersDir(*basePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Fatalf("Failed to read players directory %s: %v", dir, err)
+	}
+
+	problems := 0
+	report := func(file string, format string, args ...interface{}) {
+		problems++
+		fmt.Printf("%-30s %s\n", file, fmt.Sprintf(format, args...))
+	}
+
+	// Files whose names fold to the same canonical username, which only one can keep
+	byCanonical := make(map[string][]string)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		file := entry.Name()
+		name := strings.TrimSuffix(file, ".json")
+
+		canonical, err := models.CanonicalUsername(name)
+		switch {
+		case err != nil:
+			report(file, "%v", err)
+		case canonical != name:
+			report(file, "file name is not lower case, rename to %s.json", canonical)
+		}
+		if err == nil {
+			byCanonical[canonical] = append(byCanonical[canonical], file)
+		}
+
+		// The username stored in the file must match its name
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			report(file, "cannot be read: %v", err)
+			continue
+		}
+		var playerData models.PlayerData
+		if err := json.Unmarshal(data, &playerData); err != nil {
+			report(file, "is not valid player data: %v", err)
+			continue
+		}
+		if playerData.Username != name {
+			report(file, "stores username %q, which does not match the file name", playerData.Username)
+		}
+	}
+
+	canonicalNames := make([]string, 0, len(byCanonical))
+	for canonical := range byCanonical {
+		canonicalNames = append(canonicalNames, canonical)
+	}
+	sort.Strings(canonicalNames)
+	for _, canonical := range canonicalNames {
+		if files := byCanonical[canonical]; len(files) > 1 {
+			report(canonical, "is claimed by %d files: %s", len(files), strings.Join(files, ", "))
+		}
+	}
+
+	if problems > 0 {
+		fmt.Printf("\n%d problem(s) found in %s\n", problems, dir)
+		os.Exit(1)
+	}
+	fmt.Printf("All player files in %s follow the username policy\n", dir)
+}
+
+// getDefaultBasePath returns the default base path for config and data files
+func getDefaultBasePath() string {
+	// Get the current working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Printf("Warning: Failed to get current working directory: %v", err)
+		return "."
+	}
+
+	// If we're in cmd/tcr-usercheck, go up two levels
+	if _, err := os.Stat(filepath.Join(cwd, "..", "..", "config", "towers.json")); err == nil {
+		return filepath.Join(cwd, "..", "..")
+	}
+
+	return cwd
+}
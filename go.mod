@@ -3,6 +3,8 @@ module github.com/NP-Dat/net-centric-project
 go 1.23.6
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
+	github.com/google/uuid v1.6.0
+	golang.org/x/crypto v0.37.0
 )
+
+require github.com/mattn/go-sqlite3 v1.14.33
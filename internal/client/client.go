@@ -1,9 +1,13 @@
 package client
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/NP-Dat/net-centric-project/internal/network"
 	"github.com/NP-Dat/net-centric-project/pkg/logger"
@@ -18,58 +22,218 @@ type Client struct {
 	codec               *network.Codec
 	messageHandlers     map[network.MessageType]MessageHandler
 	handlersMutex       sync.RWMutex
-	connected           bool
+	fallbackHandler     MessageHandler // Handles messages of types with no registered handler, guarded by handlersMutex
+	connected           atomic.Bool    // Written by Disconnect and the receive loop, read by every request
 	disconnectChan      chan struct{}
+	disconnectOnce      sync.Once                 // Closes disconnectChan exactly once
 	currentTroopChoices []network.TroopChoiceInfo // Stores the troop choices received from the server
+	countdownStop       chan struct{}             // Closed to cancel the running turn countdown
+	countdownMutex      sync.Mutex
+	pendingMatchID      string // Match found by matchmaking that awaits our answer, guarded by handlersMutex
+	pendingChallengeID  string // Latest challenge received that awaits our answer, guarded by handlersMutex
+
+	spectatedGameID  string    // Game we are watching, guarded by handlersMutex
+	spectatedPlayers [2]string // Players of the watched game, guarded by handlersMutex
+
+	Framing      int // Framing version to request from the server, network.FramingJSONLine skips the handshake
+	MaxFrameSize int // Largest message in bytes accepted from the server
+
+	serverFeatures []string // Features the server advertised in its hello
+
+	TLS                bool   // Connect over TLS
+	TLSCAFile          string // CA certificate to verify the server with, system roots when empty
+	InsecureSkipVerify bool   // Accept any server certificate (development only)
+
+	SessionStorePath string // File remembering session tokens per server, "" disables it
+	tokenLogin       bool   // Set while a login with a remembered session token is pending
 }
 
+// ClientFeatures are the protocol features this client advertises in its hello
+var ClientFeatures = []string{network.FeatureEnhancedMode, network.FeatureLengthFraming, network.FeatureReadyCheck, network.FeatureSpectate,
+	network.FeatureQueueStatus, network.FeatureChallenge}
+
+// clientName identifies this client in its hello
+const clientName = "tcr-client"
+
+// handshakeTimeout bounds how long Connect waits for the server to answer a handshake message
+const handshakeTimeout = 5 * time.Second
+
 // MessageHandler is a function that handles a specific type of message
 type MessageHandler func(msg *network.Message) error
 
 // NewClient creates a new TCR client
 func NewClient(host string, port int) *Client {
 	return &Client{
-		Host:            host,
-		Port:            port,
-		messageHandlers: make(map[network.MessageType]MessageHandler),
-		disconnectChan:  make(chan struct{}),
+		Host:             host,
+		Port:             port,
+		messageHandlers:  make(map[network.MessageType]MessageHandler),
+		disconnectChan:   make(chan struct{}),
+		Framing:          network.FramingLengthPrefixed,
+		MaxFrameSize:     network.DefaultMaxFrameSize,
+		SessionStorePath: DefaultSessionStorePath(),
 	}
 }
 
 // Connect connects to the server
 func (c *Client) Connect() error {
 	// Check if already connected
-	if c.connected {
+	if c.connected.Load() {
 		logger.Client.Warn("Attempted to connect while already connected to server")
 		return fmt.Errorf("already connected to server")
 	}
 
-	addr := fmt.Sprintf("%s:%d", c.Host, c.Port)
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
 	var err error
-	c.conn, err = net.Dial("tcp", addr)
+	if c.TLS {
+		tlsConfig, tlsErr := network.ClientTLSConfig(c.Host, c.TLSCAFile, c.InsecureSkipVerify)
+		if tlsErr != nil {
+			logger.Client.Error("Failed to set up TLS: %v", tlsErr)
+			return tlsErr
+		}
+		c.conn, err = tls.Dial("tcp", addr, tlsConfig)
+	} else {
+		c.conn, err = net.Dial("tcp", addr)
+	}
 	if err != nil {
 		logger.Client.Error("Failed to connect to server at %s: %v", addr, err)
 		return fmt.Errorf("failed to connect to server at %s: %w", addr, err)
 	}
 
 	c.codec = network.NewCodec(c.conn)
-	c.connected = true
+	c.codec.SetMaxFrameSize(c.MaxFrameSize)
+	c.connected.Store(true)
 
 	logger.Client.Info("Connected to server at %s", addr)
 
+	// Agree on the protocol before handing the connection to the receive loop
+	if err := c.sayHello(); err != nil {
+		c.conn.Close()
+		c.connected.Store(false)
+		return err
+	}
+	if c.Framing != network.FramingJSONLine && c.ServerSupports(network.FeatureLengthFraming) {
+		c.negotiateFraming()
+	}
+
 	// Start receiving messages in a goroutine
 	go c.receiveMessages()
 
 	return nil
 }
 
+// sayHello announces the client's protocol version and features and records the server's.
+// Servers that predate the hello exchange answer with an error or not at all; the client
+// then carries on assuming the server supports no optional features.
+func (c *Client) sayHello() error {
+	err := c.codec.Send(network.MessageTypeHello, &network.HelloPayload{
+		ProtocolVersion: network.ProtocolVersion,
+		ClientName:      clientName,
+		Features:        ClientFeatures,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send hello: %w", err)
+	}
+
+	msg, err := c.awaitReply(network.MessageTypeHello)
+	if err != nil {
+		logger.Client.Warn("No hello from server, assuming no optional features: %v", err)
+		return nil
+	}
+
+	if msg.Type == network.MessageTypeError {
+		errorPayload, err := network.Decode[network.ErrorPayload](msg)
+		if err != nil {
+			return fmt.Errorf("invalid hello answer from server: %w", err)
+		}
+		if errorPayload.Code == network.ErrorCodeIncompatibleVersion {
+			logger.Client.Error("Server rejected protocol version %d: %s", network.ProtocolVersion, errorPayload.Message)
+			return fmt.Errorf("incompatible server: it accepts protocol versions %d to %d, this client speaks %d",
+				errorPayload.MinProtocolVersion, errorPayload.MaxProtocolVersion, network.ProtocolVersion)
+		}
+		logger.Client.Info("Server does not support hello, assuming no optional features")
+		return nil
+	}
+
+	helloPayload, err := network.Decode[network.HelloPayload](msg)
+	if err != nil {
+		return fmt.Errorf("invalid hello from server: %w", err)
+	}
+	c.serverFeatures = helloPayload.Features
+	logger.Client.Info("Server is %s, protocol version %d, features %v", helloPayload.ServerName, helloPayload.ProtocolVersion, helloPayload.Features)
+	return nil
+}
+
+// ServerSupports reports whether the server advertised the feature in its hello
+func (c *Client) ServerSupports(feature string) bool {
+	return network.HasFeature(c.serverFeatures, feature)
+}
+
+// negotiateFraming asks the server to switch to the configured framing. If the server
+// declines or does not answer, the connection stays on JSON lines.
+func (c *Client) negotiateFraming() {
+	err := c.codec.Send(network.MessageTypeFraming, &network.FramingPayload{
+		Versions:     []int{c.Framing, network.FramingJSONLine},
+		MaxFrameSize: c.MaxFrameSize,
+	})
+	if err != nil {
+		logger.Client.Warn("Failed to request framing version %d: %v", c.Framing, err)
+		return
+	}
+
+	msg, err := c.awaitReply(network.MessageTypeFraming)
+	if err != nil {
+		logger.Client.Warn("No framing answer from server, staying on JSON lines: %v", err)
+		return
+	}
+	if msg.Type == network.MessageTypeError {
+		logger.Client.Info("Server declined framing negotiation, staying on JSON lines")
+		return
+	}
+
+	payload, err := network.Decode[network.FramingPayload](msg)
+	if err != nil {
+		logger.Client.Warn("Invalid framing answer from server, staying on JSON lines: %v", err)
+		return
+	}
+	if payload.MaxFrameSize > 0 {
+		c.codec.SetMaxFrameSize(payload.MaxFrameSize)
+	}
+	if err := c.codec.SetFraming(payload.Version); err != nil {
+		logger.Client.Warn("Server chose unusable framing: %v", err)
+		return
+	}
+	logger.Client.Info("Using framing version %d (max frame size %d bytes)", payload.Version, c.codec.MaxFrameSize())
+}
+
+// awaitReply reads messages until one of the expected type or an error message arrives,
+// handling anything else (e.g. the welcome message) as usual. It runs before the receive
+// loop starts and gives up after handshakeTimeout.
+func (c *Client) awaitReply(expected network.MessageType) (*network.Message, error) {
+	c.conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	for {
+		msg, err := c.codec.Receive()
+		if err != nil {
+			return nil, err
+		}
+		if msg.Type == expected || msg.Type == network.MessageTypeError {
+			return msg, nil
+		}
+		c.processMessage(msg)
+	}
+}
+
 // Disconnect disconnects from the server
 func (c *Client) Disconnect() error {
-	if !c.connected {
+	if !c.connected.Load() {
 		logger.Client.Debug("Disconnect called but client is not connected")
 		return nil
 	}
 
+	// Quitting logs out, which revokes the session token on the server
+	c.forgetSession()
+
 	// Try to send a quit message
 	if c.codec != nil {
 		logger.Client.Info("Sending quit message to server")
@@ -86,12 +250,17 @@ func (c *Client) Disconnect() error {
 		logger.Client.Info("Connection closed successfully")
 	}
 
-	c.connected = false
-	close(c.disconnectChan)
+	c.connected.Store(false)
+	c.closeDisconnectChan()
 
 	return err
 }
 
+// closeDisconnectChan signals the disconnect; both Disconnect and the receive loop may call it
+func (c *Client) closeDisconnectChan() {
+	c.disconnectOnce.Do(func() { close(c.disconnectChan) })
+}
+
 // RegisterHandler registers a handler for a specific message type
 func (c *Client) RegisterHandler(msgType network.MessageType, handler MessageHandler) {
 	c.handlersMutex.Lock()
@@ -100,6 +269,14 @@ func (c *Client) RegisterHandler(msgType network.MessageType, handler MessageHan
 	logger.Client.Debug("Registered handler for message type: %s", msgType)
 }
 
+// SetFallbackHandler registers a handler for messages of types with no registered handler.
+// A nil handler restores the default of logging them.
+func (c *Client) SetFallbackHandler(handler MessageHandler) {
+	c.handlersMutex.Lock()
+	defer c.handlersMutex.Unlock()
+	c.fallbackHandler = handler
+}
+
 // RemoveHandler removes a handler for a specific message type
 func (c *Client) RemoveHandler(msgType network.MessageType) {
 	c.handlersMutex.Lock()
@@ -110,7 +287,7 @@ func (c *Client) RemoveHandler(msgType network.MessageType) {
 
 // Send sends a message to the server
 func (c *Client) Send(msgType network.MessageType, payload interface{}) error {
-	if !c.connected {
+	if !c.connected.Load() {
 		logger.Client.Error("Attempted to send message when not connected")
 		return fmt.Errorf("not connected to server")
 	}
@@ -121,7 +298,7 @@ func (c *Client) Send(msgType network.MessageType, payload interface{}) error {
 
 // IsConnected returns whether the client is connected to the server
 func (c *Client) IsConnected() bool {
-	return c.connected
+	return c.connected.Load()
 }
 
 // WaitForDisconnect blocks until the client is disconnected
@@ -131,7 +308,7 @@ func (c *Client) WaitForDisconnect() {
 
 // LoginWithCredentials attempts to log in with the provided username and password
 func (c *Client) LoginWithCredentials(username, password string) error {
-	if !c.connected {
+	if !c.connected.Load() {
 		logger.Client.Error("Attempted to login when not connected")
 		return fmt.Errorf("not connected to server")
 	}
@@ -148,9 +325,50 @@ func (c *Client) LoginWithCredentials(username, password string) error {
 	return c.Send(network.MessageTypeLogin, loginPayload)
 }
 
-// JoinMatchmaking sends a request to join the matchmaking queue
-func (c *Client) JoinMatchmaking() error {
-	if !c.connected {
+// RegisterAccount asks the server to create an account, which is logged in on success
+func (c *Client) RegisterAccount(username, password string) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to register when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	c.Username = username
+	logger.Client.Info("Attempting to register username: %s", username)
+
+	return c.Send(network.MessageTypeRegister, &network.RegisterPayload{
+		Username: username,
+		Password: password,
+	})
+}
+
+// LoginWithStoredSession logs in with the session token remembered for this server, so a
+// short reconnect does not ask for the password again. It reports whether a token was sent.
+func (c *Client) LoginWithStoredSession() bool {
+	if !c.connected.Load() {
+		return false
+	}
+
+	session, exists := c.storedSessionForServer()
+	if !exists {
+		return false
+	}
+
+	c.Username = session.Username
+	c.tokenLogin = true
+	logger.Client.Info("Attempting to resume session of %s", session.Username)
+
+	if err := c.Send(network.MessageTypeResume, &network.ResumePayload{Token: session.Token}); err != nil {
+		logger.Client.Error("Failed to send session token: %v", err)
+		c.tokenLogin = false
+		return false
+	}
+	return true
+}
+
+// JoinMatchmaking sends a request to join the matchmaking queue of a game mode
+// (network.GameModeSimple or network.GameModeEnhanced)
+func (c *Client) JoinMatchmaking(mode string) error {
+	if !c.connected.Load() {
 		logger.Client.Error("Attempted to join matchmaking when not connected")
 		return fmt.Errorf("not connected to server")
 	}
@@ -160,9 +378,260 @@ func (c *Client) JoinMatchmaking() error {
 		return fmt.Errorf("must be logged in to join matchmaking")
 	}
 
-	logger.Client.Info("Requesting to join matchmaking queue")
-	// Send join queue message - empty payload is fine for Sprint 1
-	return c.Send(network.MessageTypeJoinQueue, map[string]interface{}{})
+	if mode == network.GameModeEnhanced && !c.ServerSupports(network.FeatureEnhancedMode) {
+		logger.Client.Warn("Server does not support enhanced mode")
+		return fmt.Errorf("this server does not support enhanced mode")
+	}
+
+	logger.Client.Info("Requesting to join %s matchmaking queue", mode)
+	return c.Send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{Mode: mode})
+}
+
+// PlayBot starts a game against a server-side bot of a difficulty (one of the
+// network.BotDifficulty constants) in a game mode
+func (c *Client) PlayBot(difficulty, mode string) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to play a bot when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to play a bot while not logged in")
+		return fmt.Errorf("must be logged in to play against a bot")
+	}
+
+	if mode == network.GameModeEnhanced && !c.ServerSupports(network.FeatureEnhancedMode) {
+		logger.Client.Warn("Server does not support enhanced mode")
+		return fmt.Errorf("this server does not support enhanced mode")
+	}
+
+	logger.Client.Info("Requesting a %s game against a %s bot", mode, difficulty)
+	return c.Send(network.MessageTypePlayBot, &network.PlayBotPayload{Mode: mode, Difficulty: difficulty})
+}
+
+// LeaveMatchmaking sends a request to leave the matchmaking queue
+func (c *Client) LeaveMatchmaking() error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to leave matchmaking when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to leave matchmaking while not logged in")
+		return fmt.Errorf("must be logged in to leave matchmaking")
+	}
+
+	logger.Client.Info("Requesting to leave the matchmaking queue")
+	c.setPendingMatch("")
+	return c.Send(network.MessageTypeLeaveQueue, &network.LeaveQueuePayload{})
+}
+
+// RequestQueueStatus asks for our place in the matchmaking queue
+func (c *Client) RequestQueueStatus() error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to request queue status when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to request queue status while not logged in")
+		return fmt.Errorf("must be logged in to view your queue status")
+	}
+
+	if !c.ServerSupports(network.FeatureQueueStatus) {
+		return fmt.Errorf("this server does not report queue status")
+	}
+
+	return c.Send(network.MessageTypeQueueStatus, &network.QueueStatusPayload{})
+}
+
+// AnswerMatch accepts or declines the match matchmaking found for us
+func (c *Client) AnswerMatch(accept bool) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to answer a match when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	matchID := c.pendingMatch()
+	if matchID == "" {
+		logger.Client.Warn("Attempted to answer a match while none is pending")
+		return fmt.Errorf("no match is waiting for your answer")
+	}
+
+	logger.Client.Info("Answering match %s, accept: %v", matchID, accept)
+	c.setPendingMatch("")
+	return c.Send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: matchID, Accept: accept})
+}
+
+// ChallengePlayer invites an online player to a private game of a mode
+// (network.GameModeSimple or network.GameModeEnhanced)
+func (c *Client) ChallengePlayer(username, mode string) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to challenge a player when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to challenge a player while not logged in")
+		return fmt.Errorf("must be logged in to challenge a player")
+	}
+
+	if !c.ServerSupports(network.FeatureChallenge) {
+		return fmt.Errorf("this server does not support challenges")
+	}
+
+	if mode == network.GameModeEnhanced && !c.ServerSupports(network.FeatureEnhancedMode) {
+		logger.Client.Warn("Server does not support enhanced mode")
+		return fmt.Errorf("this server does not support enhanced mode")
+	}
+
+	logger.Client.Info("Challenging %s to a %s game", username, mode)
+	return c.Send(network.MessageTypeChallenge, &network.ChallengePayload{Username: username, Mode: mode})
+}
+
+// AnswerChallenge accepts or declines the latest challenge we received
+func (c *Client) AnswerChallenge(accept bool) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to answer a challenge when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	challengeID := c.pendingChallenge()
+	if challengeID == "" {
+		logger.Client.Warn("Attempted to answer a challenge while none is pending")
+		return fmt.Errorf("no challenge is waiting for your answer")
+	}
+
+	logger.Client.Info("Answering challenge %s, accept: %v", challengeID, accept)
+	c.setPendingChallenge("")
+	return c.Send(network.MessageTypeChallengeResponse, &network.ChallengeResponsePayload{ChallengeID: challengeID, Accept: accept})
+}
+
+// setPendingChallenge remembers the challenge awaiting our answer, "" once answered
+func (c *Client) setPendingChallenge(challengeID string) {
+	c.handlersMutex.Lock()
+	defer c.handlersMutex.Unlock()
+	c.pendingChallengeID = challengeID
+}
+
+// pendingChallenge returns the challenge awaiting our answer, if any
+func (c *Client) pendingChallenge() string {
+	c.handlersMutex.RLock()
+	defer c.handlersMutex.RUnlock()
+	return c.pendingChallengeID
+}
+
+// setPendingMatch remembers the match awaiting our answer, "" once answered
+func (c *Client) setPendingMatch(matchID string) {
+	c.handlersMutex.Lock()
+	defer c.handlersMutex.Unlock()
+	c.pendingMatchID = matchID
+}
+
+// pendingMatch returns the match awaiting our answer, if any
+func (c *Client) pendingMatch() string {
+	c.handlersMutex.RLock()
+	defer c.handlersMutex.RUnlock()
+	return c.pendingMatchID
+}
+
+// RequestStats asks for a player's statistics, the logged in player's own if username is empty
+func (c *Client) RequestStats(username string) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to request stats when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to request stats while not logged in")
+		return fmt.Errorf("must be logged in to view stats")
+	}
+
+	logger.Client.Info("Requesting stats for %q", username)
+	return c.Send(network.MessageTypeStats, &network.StatsRequestPayload{Username: username})
+}
+
+// RequestLeaderboard asks for a page (1-based) of the leaderboard sorted by sortBy
+func (c *Client) RequestLeaderboard(sortBy string, page int) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to request the leaderboard when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to request the leaderboard while not logged in")
+		return fmt.Errorf("must be logged in to view the leaderboard")
+	}
+
+	logger.Client.Info("Requesting leaderboard by %s, page %d", sortBy, page)
+	return c.Send(network.MessageTypeLeaderboard, &network.LeaderboardRequestPayload{SortBy: sortBy, Page: page})
+}
+
+// ListGames asks for the games in progress
+func (c *Client) ListGames() error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to list games when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to list games while not logged in")
+		return fmt.Errorf("must be logged in to list games")
+	}
+
+	if !c.ServerSupports(network.FeatureSpectate) {
+		return fmt.Errorf("this server does not support watching games")
+	}
+
+	logger.Client.Info("Requesting the games in progress")
+	return c.Send(network.MessageTypeListGames, &network.ListGamesPayload{})
+}
+
+// Spectate asks to watch a game in progress
+func (c *Client) Spectate(gameID string) error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to spectate when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	if c.Username == "" {
+		logger.Client.Warn("Attempted to spectate while not logged in")
+		return fmt.Errorf("must be logged in to watch a game")
+	}
+
+	if !c.ServerSupports(network.FeatureSpectate) {
+		return fmt.Errorf("this server does not support watching games")
+	}
+
+	logger.Client.Info("Requesting to watch game %s", gameID)
+	return c.Send(network.MessageTypeSpectate, &network.SpectatePayload{GameID: gameID})
+}
+
+// StopSpectating stops watching the current game
+func (c *Client) StopSpectating() error {
+	if !c.connected.Load() {
+		logger.Client.Error("Attempted to stop spectating when not connected")
+		return fmt.Errorf("not connected to server")
+	}
+
+	logger.Client.Info("Requesting to stop watching the game")
+	c.setSpectating("", [2]string{})
+	return c.Send(network.MessageTypeStopSpectating, &network.StopSpectatingPayload{})
+}
+
+// setSpectating records the game we are watching and its players, "" when none
+func (c *Client) setSpectating(gameID string, players [2]string) {
+	c.handlersMutex.Lock()
+	defer c.handlersMutex.Unlock()
+	c.spectatedGameID = gameID
+	c.spectatedPlayers = players
+}
+
+// spectating returns the game we are watching and its players, "" when none
+func (c *Client) spectating() (string, [2]string) {
+	c.handlersMutex.RLock()
+	defer c.handlersMutex.RUnlock()
+	return c.spectatedGameID, c.spectatedPlayers
 }
 
 // receiveMessages continuously receives and processes messages from the server
@@ -173,15 +642,10 @@ func (c *Client) receiveMessages() {
 			logger.Client.Error("Panic in receiveMessages: %v", r)
 		}
 
-		c.connected = false
+		c.connected.Store(false)
 
 		// Notify about disconnection if not already notified
-		select {
-		case <-c.disconnectChan:
-			// Already closed
-		default:
-			close(c.disconnectChan)
-		}
+		c.closeDisconnectChan()
 
 		logger.Client.Info("Stopped receiving messages from server")
 	}()
@@ -207,6 +671,9 @@ func (c *Client) receiveMessages() {
 func (c *Client) processMessage(msg *network.Message) {
 	c.handlersMutex.RLock()
 	handler, exists := c.messageHandlers[msg.Type]
+	if !exists && c.fallbackHandler != nil {
+		handler, exists = c.fallbackHandler, true
+	}
 	c.handlersMutex.RUnlock()
 
 	if exists {
@@ -250,3 +717,50 @@ func (c *Client) GetCurrentTroopChoices() []network.TroopChoiceInfo {
 	copy(choicesCopy, c.currentTroopChoices)
 	return choicesCopy
 }
+
+// startTurnCountdown prints reminders of the time left in the current turn until the
+// deadline passes or the countdown is stopped. Any previous countdown is cancelled.
+func (c *Client) startTurnCountdown(deadline time.Time) {
+	c.stopTurnCountdown()
+	if deadline.IsZero() {
+		return
+	}
+
+	stop := make(chan struct{})
+	c.countdownMutex.Lock()
+	c.countdownStop = stop
+	c.countdownMutex.Unlock()
+
+	fmt.Printf("  ⏰ You have %ds to deploy before a troop is chosen for you.\n", int(time.Until(deadline).Seconds()))
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				left := int(time.Until(deadline).Round(time.Second).Seconds())
+				if left <= 0 {
+					return
+				}
+				// Remind every 10 seconds, then every second for the last 5
+				if left%10 == 0 || left <= 5 {
+					fmt.Printf("\n⏰ %ds left in your turn!\n> ", left)
+				}
+			}
+		}
+	}()
+}
+
+// stopTurnCountdown cancels the running turn countdown, if any
+func (c *Client) stopTurnCountdown() {
+	c.countdownMutex.Lock()
+	defer c.countdownMutex.Unlock()
+	if c.countdownStop != nil {
+		close(c.countdownStop)
+		c.countdownStop = nil
+	}
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,12 +22,33 @@ func (c *Client) SetupDefaultHandlers() {
 			return err
 		}
 
+		tokenLogin := c.tokenLogin
+		c.tokenLogin = false
+
 		if payload.Success {
+			if payload.Username != "" {
+				c.Username = payload.Username
+			}
+			if payload.SessionToken != "" {
+				c.rememberSession(storedSession{
+					Username:  c.Username,
+					Token:     payload.SessionToken,
+					ExpiresAt: payload.TokenExpiresAt,
+				})
+			}
 			logger.Client.Info("Authentication successful for user: %s", c.Username)
 			fmt.Printf("\n✓ Authentication successful. Welcome, %s!\n", c.Username)
+		} else if tokenLogin {
+			// The remembered session expired or was revoked; fall back to the password
+			c.forgetSession()
+			logger.Client.Warn("Saved session for %s rejected: %s", c.Username, payload.Message)
+			fmt.Printf("\n⚠️ Your saved session is no longer valid (%s). Please log in again.\n", payload.Message)
 		} else {
 			logger.Client.Warn("Authentication failed for user: %s - %s", c.Username, payload.Message)
 			fmt.Printf("\n❌ Authentication failed: %s\n", payload.Message)
+			if payload.Code == network.AuthCodeUnknownUser {
+				fmt.Println("  New here? Create an account with 'register <username> <password>'")
+			}
 		}
 
 		return nil
@@ -68,22 +90,30 @@ func (c *Client) SetupDefaultHandlers() {
 			return err
 		}
 
-		logger.Client.Info("Game started - ID: %s, Opponent: %s, Mode: %s",
-			payload.GameID, payload.OpponentUsername, payload.GameMode)
+		c.setPendingMatch("")
+		c.setPendingChallenge("")
+		c.setSpectating("", [2]string{})
+		logger.Client.Info("Game started - ID: %s, Opponent: %s, Mode: %s, Seed: %d",
+			payload.GameID, payload.OpponentUsername, payload.GameMode, payload.Seed)
 
 		// Enhanced game start notification
 		fmt.Printf("\n🎮 === GAME STARTED! === 🎮\n")
 		fmt.Printf("Game ID: %s\n", payload.GameID)
 		fmt.Printf("Opponent: %s\n", payload.OpponentUsername)
 		fmt.Printf("Mode: %s\n", payload.GameMode)
+		fmt.Printf("Seed: %d\n", payload.Seed)
 
-		if payload.GameMode == "simple" {
+		if payload.GameMode == network.GameModeSimple {
 			if payload.YourTurn {
 				fmt.Println("\n➤ It's your turn to play!")
 				fmt.Println("  Use 'deploy <troop>' to deploy a troop (pawn, bishop, rook, knight, prince, queen)")
+				c.startTurnCountdown(payload.TurnTimeoutAt)
 			} else {
 				fmt.Println("\n⏳ Waiting for opponent's turn...")
 			}
+		} else if payload.GameMode == network.GameModeEnhanced {
+			fmt.Println("\n⏱️ Real-time match: 3 minutes, no turns!")
+			fmt.Println("  Use 'deploy <troop>' at any time. Each troop costs MANA (1 MANA regenerates every 2s, max 10)")
 		}
 
 		fmt.Println("\n===== INITIAL GAME STATE =====")
@@ -105,7 +135,11 @@ func (c *Client) SetupDefaultHandlers() {
 			len(payload.Troops), len(payload.Towers))
 
 		fmt.Println("\n===== GAME STATE UPDATED =====")
-		printGameState(&payload, c.Username)
+		if gameID, players := c.spectating(); gameID != "" {
+			printSpectatedState(&payload, players[0], players[1])
+		} else {
+			printGameState(&payload, c.Username)
+		}
 
 		return nil
 	})
@@ -121,10 +155,18 @@ func (c *Client) SetupDefaultHandlers() {
 
 		logger.Client.Debug("Turn changed - Your turn: %v", payload.YourTurn)
 
+		// Spectators are told whose turn it is by name
+		if payload.Player != "" {
+			fmt.Printf("\n⏳ It's %s's turn now.\n", payload.Player)
+			return nil
+		}
+
 		if payload.YourTurn {
 			fmt.Println("\n➤ It's your turn now!")
 			fmt.Println("  Use 'deploy <troop>' to deploy a troop (pawn, bishop, rook, knight, prince, queen)")
+			c.startTurnCountdown(payload.TimeoutAt)
 		} else {
+			c.stopTurnCountdown()
 			fmt.Println("\n⏳ It's your opponent's turn now.")
 		}
 
@@ -143,9 +185,23 @@ func (c *Client) SetupDefaultHandlers() {
 		logger.Client.Info("Game over - Winner: %s, Reason: %s, EXP earned: %d",
 			payload.Winner, payload.Reason, payload.ExpEarned)
 
+		c.stopTurnCountdown()
+
 		fmt.Println("\n🏁 ========= GAME OVER ========= 🏁")
 		fmt.Printf("Reason: %s\n", payload.Reason)
 
+		// Spectators only see the result
+		if gameID, _ := c.spectating(); gameID != "" {
+			c.setSpectating("", [2]string{})
+			if payload.Winner == "" {
+				fmt.Println("\n🤝 It's a draw! 🤝")
+			} else {
+				fmt.Printf("\n🏆 %s wins the game\n", payload.Winner)
+			}
+			fmt.Println("\n=================================")
+			return nil
+		}
+
 		if payload.Winner == c.Username {
 			fmt.Println("\n🏆 You win! 🏆")
 		} else if payload.Winner == "" {
@@ -158,6 +214,9 @@ func (c *Client) SetupDefaultHandlers() {
 		fmt.Printf("  ⭐ EXP earned: %d\n", payload.ExpEarned)
 		fmt.Printf("  ⭐ Total EXP: %d\n", payload.NewTotalExp)
 		fmt.Printf("  ⭐ Current level: %d\n", payload.NewLevel)
+		if payload.NewRating != 0 {
+			fmt.Printf("  ⭐ Rating: %d (%+d)\n", payload.NewRating, payload.RatingChange)
+		}
 
 		if payload.LeveledUp {
 			fmt.Println("\n🎉 CONGRATULATIONS! You leveled up! 🎉")
@@ -213,11 +272,221 @@ func (c *Client) SetupDefaultHandlers() {
 		return nil
 	})
 
+	// Handle stats results
+	c.RegisterHandler(network.MessageTypeStatsResult, func(msg *network.Message) error {
+		var payload network.StatsPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse stats: %v", err)
+			fmt.Printf("Error: Could not process player stats\n")
+			return err
+		}
+
+		printStats(&payload)
+		return nil
+	})
+
+	// Handle leaderboard pages
+	c.RegisterHandler(network.MessageTypeLeaderboardResult, func(msg *network.Message) error {
+		var payload network.LeaderboardPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse leaderboard: %v", err)
+			fmt.Printf("Error: Could not process the leaderboard\n")
+			return err
+		}
+
+		printLeaderboard(&payload, c.Username)
+		return nil
+	})
+
+	// Handle queue status, sent on request and periodically while waiting
+	c.RegisterHandler(network.MessageTypeQueueStatus, func(msg *network.Message) error {
+		var payload network.QueueStatusPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse queue status: %v", err)
+			fmt.Printf("Error: Could not process queue status\n")
+			return err
+		}
+
+		if !payload.InQueue {
+			fmt.Println("\n⌛ You are not in the matchmaking queue. Type 'join' to queue for a game.")
+			return nil
+		}
+		estimate := "unknown"
+		if payload.EstimatedWaitSeconds >= 0 {
+			estimate = fmt.Sprintf("~%ds", payload.EstimatedWaitSeconds)
+		}
+		fmt.Printf("\n⌛ %s queue: position %d of %d | waited %ds | estimated wait %s\n",
+			payload.Mode, payload.Position, payload.QueueSize, payload.WaitedSeconds, estimate)
+		return nil
+	})
+
+	// Handle matches found by matchmaking, which must be accepted before the game starts
+	c.RegisterHandler(network.MessageTypeMatchFound, func(msg *network.Message) error {
+		var payload network.MatchFoundPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse match found: %v", err)
+			fmt.Printf("Error: Could not process match found\n")
+			return err
+		}
+
+		logger.Client.Info("Match %s found against %s (%d)", payload.MatchID, payload.OpponentUsername, payload.OpponentRating)
+		c.setPendingMatch(payload.MatchID)
+
+		fmt.Println("\n🔔 ===== MATCH FOUND! ===== 🔔")
+		fmt.Printf("Mode: %s\n", payload.Mode)
+		fmt.Printf("Opponent: %s (rating %d)\n", payload.OpponentUsername, payload.OpponentRating)
+		fmt.Printf("Type 'accept' or 'decline' within %ds\n", max(int(time.Until(payload.AcceptBy).Seconds()), 0))
+		return nil
+	})
+
+	// Handle challenges from other players
+	c.RegisterHandler(network.MessageTypeChallengeReceived, func(msg *network.Message) error {
+		var payload network.ChallengeReceivedPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse challenge: %v", err)
+			fmt.Printf("Error: Could not process challenge\n")
+			return err
+		}
+
+		logger.Client.Info("Challenge %s received from %s (%d)", payload.ChallengeID, payload.FromUsername, payload.FromRating)
+		c.setPendingChallenge(payload.ChallengeID)
+
+		fmt.Println("\n⚔️ ===== CHALLENGE! ===== ⚔️")
+		fmt.Printf("%s (rating %d) challenges you to a %s game\n", payload.FromUsername, payload.FromRating, payload.Mode)
+		fmt.Printf("Type 'accept' or 'decline' within %ds\n", max(int(time.Until(payload.ExpiresAt).Seconds()), 0))
+		return nil
+	})
+
+	// Handle the list of games in progress
+	c.RegisterHandler(network.MessageTypeGameList, func(msg *network.Message) error {
+		var payload network.GameListPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse game list: %v", err)
+			fmt.Printf("Error: Could not process game list\n")
+			return err
+		}
+
+		fmt.Println("\n📺 ===== GAMES IN PROGRESS ===== 📺")
+		if len(payload.Games) == 0 {
+			fmt.Println("No games in progress.")
+			return nil
+		}
+		fmt.Printf("  %-16s %-9s %-20s %-20s %8s %9s\n", "Game", "Mode", "Player 1", "Player 2", "Playing", "Watching")
+		for _, g := range payload.Games {
+			fmt.Printf("  %-16s %-9s %-20s %-20s %7ds %9d\n", g.GameID, g.GameMode, g.Player1, g.Player2,
+				int(time.Since(g.StartedAt).Seconds()), g.Spectators)
+		}
+		fmt.Println("Type 'spectate <game>' to watch a game")
+		return nil
+	})
+
+	// Handle the start of watching a game
+	c.RegisterHandler(network.MessageTypeSpectateStart, func(msg *network.Message) error {
+		var payload network.SpectateStartPayload
+		if err := network.ParsePayload(msg, &payload); err != nil {
+			logger.Client.Error("Failed to parse spectate start: %v", err)
+			fmt.Printf("Error: Could not process spectate start\n")
+			return err
+		}
+
+		logger.Client.Info("Watching game %s between %s and %s", payload.GameID, payload.Player1, payload.Player2)
+		c.setSpectating(payload.GameID, [2]string{payload.Player1, payload.Player2})
+
+		fmt.Printf("\n📺 === WATCHING %s === 📺\n", payload.GameID)
+		fmt.Printf("%s vs %s (%s)\n", payload.Player1, payload.Player2, payload.GameMode)
+		if payload.CurrentTurn != "" {
+			fmt.Printf("It's %s's turn.\n", payload.CurrentTurn)
+		}
+		printSpectatedState(payload.State, payload.Player1, payload.Player2)
+		fmt.Println("Type 'spectate stop' to stop watching")
+		return nil
+	})
+
 	logger.Client.Info("Default message handlers set up")
 }
 
+// printLeaderboard prints a page of the leaderboard, marking the viewer's own entry
+func printLeaderboard(leaderboard *network.LeaderboardPayload, viewerUsername string) {
+	fmt.Printf("\n🏆 ===== LEADERBOARD (by %s) ===== 🏆\n", leaderboard.SortBy)
+	if len(leaderboard.Entries) == 0 {
+		fmt.Println("No players on this page.")
+	} else {
+		fmt.Printf("  %4s  %-20s %6s %5s %5s %6s %5s\n", "Rank", "Player", "Rating", "Level", "Wins", "Losses", "Draws")
+		for _, entry := range leaderboard.Entries {
+			marker := " "
+			if entry.Username == viewerUsername {
+				marker = "➤"
+			}
+			fmt.Printf("%s %4d  %-20s %6d %5d %5d %6d %5d\n", marker, entry.Rank, entry.Username,
+				entry.Rating, entry.Level, entry.Wins, entry.Losses, entry.Draws)
+		}
+	}
+	fmt.Printf("Page %d of %d (%d players)\n", leaderboard.Page, max(leaderboard.TotalPages, 1), leaderboard.TotalPlayers)
+	if leaderboard.Page < leaderboard.TotalPages {
+		fmt.Printf("  Use 'leaderboard %s %d' for the next page\n", leaderboard.SortBy, leaderboard.Page+1)
+	}
+}
+
+// printStats prints a player's account statistics and recent matches
+func printStats(stats *network.StatsPayload) {
+	gamesPlayed := stats.Wins + stats.Losses + stats.Draws
+
+	fmt.Printf("\n📊 ===== STATS: %s ===== 📊\n", stats.Username)
+	fmt.Printf("Level %d (%d EXP)  |  Rating: %d\n", stats.Level, stats.EXP, stats.Rating)
+	fmt.Printf("Games: %d  |  Wins: %d  Losses: %d  Draws: %d\n", gamesPlayed, stats.Wins, stats.Losses, stats.Draws)
+	if gamesPlayed > 0 {
+		fmt.Printf("Win rate: %.0f%%\n", float64(stats.Wins)*100/float64(gamesPlayed))
+	}
+	fmt.Printf("Towers destroyed: %d\n", stats.TowersDestroyed)
+
+	if len(stats.TroopsDeployed) > 0 {
+		troopIDs := make([]string, 0, len(stats.TroopsDeployed))
+		for troopID := range stats.TroopsDeployed {
+			troopIDs = append(troopIDs, troopID)
+		}
+		// Most deployed first
+		sort.Slice(troopIDs, func(i, j int) bool {
+			a, b := stats.TroopsDeployed[troopIDs[i]], stats.TroopsDeployed[troopIDs[j]]
+			if a != b {
+				return a > b
+			}
+			return troopIDs[i] < troopIDs[j]
+		})
+		fmt.Println("Troops deployed:")
+		for _, troopID := range troopIDs {
+			fmt.Printf("  %-8s %d\n", troopID, stats.TroopsDeployed[troopID])
+		}
+	}
+
+	if len(stats.RecentMatches) > 0 {
+		fmt.Println("Recent matches:")
+		for _, match := range stats.RecentMatches {
+			fmt.Printf("  %s  %-4s vs %-20s %-8s +%d EXP\n",
+				match.EndedAt.Local().Format("2006-01-02 15:04"), match.Result, match.Opponent,
+				strings.ToLower(match.GameMode), match.ExpEarned)
+		}
+	}
+}
+
+// PrintGameState prints a game state from the given player's point of view,
+// using the same board rendering as the interactive client
+func PrintGameState(state *network.GameStatePayload, viewerUsername string) {
+	printGameState(state, viewerUsername)
+}
+
 // printGameState prints the current game state
 func printGameState(state *network.GameStatePayload, clientUsername string) {
+	printBoard(state, clientUsername, nil)
+}
+
+// printSpectatedState prints the state of a watched game, player 1 on the left
+func printSpectatedState(state *network.GameStatePayload, player1, player2 string) {
+	printBoard(state, "", []string{player1, player2})
+}
+
+// printBoard prints a game state as seen by clientUsername, or by a spectator when the
+// players of a watched game are given
+func printBoard(state *network.GameStatePayload, clientUsername string, spectated []string) {
 	if state == nil {
 		logger.Client.Warn("Attempted to print nil game state")
 		fmt.Println("No game state available")
@@ -261,14 +530,22 @@ func printGameState(state *network.GameStatePayload, clientUsername string) {
 		}
 	}
 
+	// Spectators see both players by name, in a fixed order
+	youLabel, opponentLabel := fmt.Sprintf("YOU (%s)", you), fmt.Sprintf("OPPONENT (%s)", opponent)
+	yourTroopsLabel, opponentTroopsLabel := "YOUR TROOPS:", "OPPONENT TROOPS:"
+	if spectated != nil {
+		you, opponent = spectated[0], spectated[1]
+		youLabel, opponentLabel = strings.ToUpper(you), strings.ToUpper(opponent)
+		yourTroopsLabel = fmt.Sprintf("%s'S TROOPS:", strings.ToUpper(you))
+		opponentTroopsLabel = fmt.Sprintf("%s'S TROOPS:", strings.ToUpper(opponent))
+	}
+
 	// Print stylized game board header
 	fmt.Println("\n╔══════════════ TEXT CLASH ROYALE ══════════════╗")
 
 	// Print board header with player information
 	if you != "" && opponent != "" {
-		fmt.Printf("║ %-23s  VS  %-23s ║\n",
-			fmt.Sprintf("YOU (%s)", you),
-			fmt.Sprintf("OPPONENT (%s)", opponent))
+		fmt.Printf("║ %-23s  VS  %-23s ║\n", youLabel, opponentLabel)
 	} else if you != "" {
 		fmt.Printf("║ YOU (%s) %-40s ║\n", you, "")
 	} else if opponent != "" {
@@ -349,7 +626,7 @@ func printGameState(state *network.GameStatePayload, clientUsername string) {
 		fmt.Println("║          (No active troops on either side)     ║")
 	} else {
 		// Print your troops header with clearer formatting
-		fmt.Printf("║ YOUR TROOPS:%-34s║\n", "")
+		fmt.Printf("║ %-46s║\n", yourTroopsLabel)
 
 		// Print your troops or "None"
 		if len(yourTroops) == 0 {
@@ -362,7 +639,7 @@ func printGameState(state *network.GameStatePayload, clientUsername string) {
 		}
 
 		// Print opponent troops header
-		fmt.Printf("║ OPPONENT TROOPS:%-30s║\n", "")
+		fmt.Printf("║ %-46s║\n", opponentTroopsLabel)
 
 		// Print opponent troops or "None"
 		if len(opponentTroops) == 0 {
@@ -376,7 +653,12 @@ func printGameState(state *network.GameStatePayload, clientUsername string) {
 	}
 
 	// Print mana info for Enhanced mode (if applicable) with better formatting
-	if state.YourMana > 0 || state.OpponentMana > 0 || state.TimeLeft > 0 {
+	if spectated != nil && (len(state.Mana) > 0 || state.TimeLeft > 0) {
+		fmt.Println("╟─────────────────────────────────────────────╢")
+		fmt.Println("║             ENHANCED MODE INFO              ║")
+		fmt.Printf("║  %s Mana: %-2d | %s Mana: %-2d | Time: %-3ds ║\n",
+			you, state.Mana[you], opponent, state.Mana[opponent], state.TimeLeft)
+	} else if state.YourMana > 0 || state.OpponentMana > 0 || state.TimeLeft > 0 {
 		fmt.Println("╟─────────────────────────────────────────────╢")
 		fmt.Println("║             ENHANCED MODE INFO              ║")
 		fmt.Printf("║  Your Mana: %-2d | Opponent Mana: %-2d | Time: %-3ds ║\n",
@@ -531,11 +813,122 @@ func (c *Client) ParseCommand(input string) error {
 		}
 		return c.LoginWithCredentials(args[0], args[1])
 
+	case "register":
+		// Create a new account
+		if len(args) != 2 {
+			logger.Client.Warn("Invalid register command format")
+			fmt.Println("\n❌ Usage: register <username> <password>")
+			return fmt.Errorf("usage: register <username> <password>")
+		}
+		return c.RegisterAccount(args[0], args[1])
+
 	case "join":
-		// Join matchmaking queue
-		logger.Client.Info("User requested to join matchmaking")
-		fmt.Println("\n⌛ Requesting to join matchmaking queue...")
-		return c.JoinMatchmaking()
+		// Play a bot right away: join bot [easy|medium|hard] [simple|enhanced]
+		if len(args) > 0 && strings.ToLower(args[0]) == "bot" {
+			difficulty := network.BotDifficultyMedium
+			mode := network.GameModeSimple
+			for _, arg := range args[1:] {
+				switch arg = strings.ToLower(arg); arg {
+				case network.BotDifficultyEasy, network.BotDifficultyMedium, network.BotDifficultyHard:
+					difficulty = arg
+				case network.GameModeSimple, network.GameModeEnhanced:
+					mode = arg
+				default:
+					fmt.Println("\n❌ Usage: join bot [easy|medium|hard] [simple|enhanced]")
+					return fmt.Errorf("usage: join bot [easy|medium|hard] [simple|enhanced]")
+				}
+			}
+			fmt.Printf("\n🤖 Starting a %s game against a %s bot...\n", mode, difficulty)
+			return c.PlayBot(difficulty, mode)
+		}
+
+		// Join the matchmaking queue of a game mode, simple by default
+		mode := network.GameModeSimple
+		if len(args) > 0 {
+			mode = strings.ToLower(args[0])
+		}
+		if len(args) > 1 || (mode != network.GameModeSimple && mode != network.GameModeEnhanced) {
+			logger.Client.Warn("Invalid join command format")
+			fmt.Println("\n❌ Usage: join [simple|enhanced]")
+			return fmt.Errorf("usage: join [simple|enhanced]")
+		}
+		logger.Client.Info("User requested to join %s matchmaking", mode)
+		fmt.Printf("\n⌛ Requesting to join %s matchmaking queue...\n", mode)
+		return c.JoinMatchmaking(mode)
+
+	case "leave":
+		// Leave the matchmaking queue
+		return c.LeaveMatchmaking()
+
+	case "queue":
+		// Show our place in the matchmaking queue
+		return c.RequestQueueStatus()
+
+	case "accept", "decline":
+		// Answer the ready check of a match found by matchmaking, or else a challenge
+		if c.pendingMatch() == "" && c.pendingChallenge() != "" {
+			return c.AnswerChallenge(command == "accept")
+		}
+		return c.AnswerMatch(command == "accept")
+
+	case "challenge":
+		// Challenge an online player to a private game, simple by default
+		mode := network.GameModeSimple
+		if len(args) > 1 {
+			mode = strings.ToLower(args[1])
+		}
+		if len(args) < 1 || len(args) > 2 || (mode != network.GameModeSimple && mode != network.GameModeEnhanced) {
+			logger.Client.Warn("Invalid challenge command format")
+			fmt.Println("\n❌ Usage: challenge <username> [simple|enhanced]")
+			return fmt.Errorf("usage: challenge <username> [simple|enhanced]")
+		}
+		return c.ChallengePlayer(args[0], mode)
+
+	case "stats":
+		// Show account statistics, your own or another player's
+		if len(args) > 1 {
+			fmt.Println("\n❌ Usage: stats [username]")
+			return fmt.Errorf("usage: stats [username]")
+		}
+		username := ""
+		if len(args) == 1 {
+			username = args[0]
+		}
+		return c.RequestStats(username)
+
+	case "leaderboard":
+		// Show a page of the leaderboard: leaderboard [rating|level|wins] [page]
+		sortBy := network.LeaderboardByRating
+		page := 1
+		for _, arg := range args {
+			switch strings.ToLower(arg) {
+			case network.LeaderboardByRating, network.LeaderboardByLevel, network.LeaderboardByWins:
+				sortBy = strings.ToLower(arg)
+				continue
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				fmt.Println("\n❌ Usage: leaderboard [rating|level|wins] [page]")
+				return fmt.Errorf("usage: leaderboard [rating|level|wins] [page]")
+			}
+			page = n
+		}
+		return c.RequestLeaderboard(sortBy, page)
+
+	case "games":
+		// List the games in progress
+		return c.ListGames()
+
+	case "spectate":
+		// Watch a game in progress, or stop watching with 'spectate stop'
+		if len(args) != 1 {
+			fmt.Println("\n❌ Usage: spectate <game_id> | spectate stop")
+			return fmt.Errorf("usage: spectate <game_id> | spectate stop")
+		}
+		if strings.ToLower(args[0]) == "stop" {
+			return c.StopSpectating()
+		}
+		return c.Spectate(args[0])
 
 	case "deploy":
 		// Deploy a troop
@@ -578,8 +971,38 @@ func (c *Client) ParseCommand(input string) error {
 		fmt.Println("║  login <username> <password>                  ║")
 		fmt.Println("║    Log in to the server                       ║")
 		fmt.Println("║                                               ║")
-		fmt.Println("║  join                                         ║")
-		fmt.Println("║    Join the matchmaking queue                 ║")
+		fmt.Println("║  register <username> <password>               ║")
+		fmt.Println("║    Create an account and log in               ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  join [simple|enhanced]                       ║")
+		fmt.Println("║    Join the matchmaking queue of a game mode  ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  join bot [easy|medium|hard] [mode]           ║")
+		fmt.Println("║    Play against a bot right away              ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  queue                                        ║")
+		fmt.Println("║    Show your place in the matchmaking queue   ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  leave                                        ║")
+		fmt.Println("║    Leave the matchmaking queue                ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  challenge <username> [simple|enhanced]       ║")
+		fmt.Println("║    Challenge an online player to a game       ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  accept | decline                             ║")
+		fmt.Println("║    Answer a found match or a challenge        ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  stats [username]                             ║")
+		fmt.Println("║    Show your (or a player's) statistics       ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  leaderboard [rating|level|wins] [page]       ║")
+		fmt.Println("║    Show the player rankings                   ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  games                                        ║")
+		fmt.Println("║    List the games in progress                 ║")
+		fmt.Println("║                                               ║")
+		fmt.Println("║  spectate <game_id> | spectate stop           ║")
+		fmt.Println("║    Watch a game in progress                   ║")
 		fmt.Println("║                                               ║")
 		fmt.Println("║  deploy <troop>                               ║")
 		fmt.Println("║    Deploy a troop in the current game         ║")
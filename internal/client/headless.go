@@ -0,0 +1,312 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/pkg/logger"
+)
+
+// DefaultRequestTimeout bounds how long a Headless request waits for the server's answer
+const DefaultRequestTimeout = 10 * time.Second
+
+// headlessEventBuffer is how many events a Headless client holds for a slow consumer
+// before it starts dropping them
+const headlessEventBuffer = 1024
+
+// ErrRequestTimeout is returned when the server does not answer a request in time
+var ErrRequestTimeout = errors.New("timed out waiting for the server")
+
+// ErrDisconnected is returned when the connection closes while a request waits for its answer
+var ErrDisconnected = errors.New("disconnected from server")
+
+// Event is a message the server sent that was not the answer to a request
+type Event struct {
+	Type     network.MessageType
+	Payload  interface{} // Pointer to the payload struct registered for Type, nil if it could not be decoded
+	Received time.Time
+}
+
+// ServerError is an error message the server answered a request with
+type ServerError struct {
+	Code    int
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error %d: %s", e.Code, e.Message)
+}
+
+// Headless is a client without a user interface, for bots, tests and load generators.
+// Requests are typed methods that wait for the server's answer; everything else the server
+// sends is delivered on Events, which the caller must keep draining.
+type Headless struct {
+	client  *Client
+	events  chan Event
+	dropped atomic.Int64
+
+	Timeout time.Duration // How long a request waits for its answer
+
+	requestMutex sync.Mutex // Allows one request in flight at a time
+	waitMutex    sync.Mutex // Guards waiter
+	waiter       *pendingReply
+}
+
+// pendingReply is a request waiting for a message of one of the types it expects
+type pendingReply struct {
+	types []network.MessageType
+	reply chan *network.Message
+}
+
+// NewHeadless creates a headless client for the server at host:port. It does not remember
+// session tokens between runs.
+func NewHeadless(host string, port int) *Headless {
+	h := &Headless{
+		client:  NewClient(host, port),
+		events:  make(chan Event, headlessEventBuffer),
+		Timeout: DefaultRequestTimeout,
+	}
+	h.client.SessionStorePath = ""
+	h.client.SetFallbackHandler(h.handleMessage)
+	return h
+}
+
+// Client returns the underlying client, e.g. to configure TLS or framing before Connect
+func (h *Headless) Client() *Client {
+	return h.client
+}
+
+// Connect connects to the server and completes the handshake
+func (h *Headless) Connect() error {
+	return h.client.Connect()
+}
+
+// Close quits and closes the connection
+func (h *Headless) Close() error {
+	return h.client.Disconnect()
+}
+
+// Events delivers the messages the server sent that were not answers to requests
+func (h *Headless) Events() <-chan Event {
+	return h.events
+}
+
+// Dropped returns how many events were dropped because Events was not drained
+func (h *Headless) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+// Done is closed when the connection to the server is lost or closed
+func (h *Headless) Done() <-chan struct{} {
+	return h.client.disconnectChan
+}
+
+// Username returns the logged in user, empty before a successful login
+func (h *Headless) Username() string {
+	return h.client.Username
+}
+
+// Register creates an account, which is logged in on success
+func (h *Headless) Register(username, password string) (*network.AuthResultPayload, error) {
+	return h.authenticate(network.MessageTypeRegister, &network.RegisterPayload{Username: username, Password: password})
+}
+
+// Login logs in with a username and password
+func (h *Headless) Login(username, password string) (*network.AuthResultPayload, error) {
+	return h.authenticate(network.MessageTypeLogin, &network.LoginPayload{Username: username, Password: password})
+}
+
+// authenticate sends a login or register request. A rejected login is returned as a
+// ServerError carrying the AuthCode.
+func (h *Headless) authenticate(msgType network.MessageType, payload interface{}) (*network.AuthResultPayload, error) {
+	msg, err := h.request(msgType, payload, network.MessageTypeAuthResult)
+	if err != nil {
+		return nil, err
+	}
+	result, err := network.Decode[network.AuthResultPayload](msg)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return result, &ServerError{Code: result.Code, Message: result.Message}
+	}
+	h.client.Username = result.Username
+	return result, nil
+}
+
+// JoinQueue joins the matchmaking queue of a game mode and returns the queue status
+func (h *Headless) JoinQueue(mode string) (*network.QueueStatusPayload, error) {
+	msg, err := h.request(network.MessageTypeJoinQueue, &network.JoinQueuePayload{Mode: mode}, network.MessageTypeQueueStatus)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.QueueStatusPayload](msg)
+}
+
+// LeaveQueue leaves the matchmaking queue
+func (h *Headless) LeaveQueue() error {
+	_, err := h.request(network.MessageTypeLeaveQueue, &network.LeaveQueuePayload{}, network.MessageTypeGameEvent)
+	return err
+}
+
+// QueueStatus returns our place in the matchmaking queue
+func (h *Headless) QueueStatus() (*network.QueueStatusPayload, error) {
+	msg, err := h.request(network.MessageTypeQueueStatus, &network.QueueStatusPayload{}, network.MessageTypeQueueStatus)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.QueueStatusPayload](msg)
+}
+
+// AnswerMatch accepts or declines a match found by matchmaking. The server does not answer
+// directly; the game start or a notice arrives on Events.
+func (h *Headless) AnswerMatch(matchID string, accept bool) error {
+	return h.client.Send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: matchID, Accept: accept})
+}
+
+// PlayBot starts a game against a server-side bot and returns the game start
+func (h *Headless) PlayBot(difficulty, mode string) (*network.GameStartPayload, error) {
+	msg, err := h.request(network.MessageTypePlayBot, &network.PlayBotPayload{Mode: mode, Difficulty: difficulty}, network.MessageTypeGameStart)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.GameStartPayload](msg)
+}
+
+// Deploy deploys a troop in the current game. It returns the state update that follows the
+// deployment, or the game over notice if the deployment ended the game.
+func (h *Headless) Deploy(troopID string) (Event, error) {
+	msg, err := h.request(network.MessageTypeDeployTroop, &network.DeployTroopPayload{TroopID: troopID},
+		network.MessageTypeStateUpdate, network.MessageTypeGameOver)
+	if err != nil {
+		return Event{}, err
+	}
+	return decodeEvent(msg), nil
+}
+
+// Stats returns a player's statistics, our own when username is empty
+func (h *Headless) Stats(username string) (*network.StatsPayload, error) {
+	msg, err := h.request(network.MessageTypeStats, &network.StatsRequestPayload{Username: username}, network.MessageTypeStatsResult)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.StatsPayload](msg)
+}
+
+// Leaderboard returns a page (1-based) of the leaderboard sorted by sortBy
+func (h *Headless) Leaderboard(sortBy string, page int) (*network.LeaderboardPayload, error) {
+	msg, err := h.request(network.MessageTypeLeaderboard, &network.LeaderboardRequestPayload{SortBy: sortBy, Page: page},
+		network.MessageTypeLeaderboardResult)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.LeaderboardPayload](msg)
+}
+
+// ListGames returns the games in progress
+func (h *Headless) ListGames() (*network.GameListPayload, error) {
+	msg, err := h.request(network.MessageTypeListGames, &network.ListGamesPayload{}, network.MessageTypeGameList)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.GameListPayload](msg)
+}
+
+// Spectate starts watching a game in progress. Its updates then arrive on Events.
+func (h *Headless) Spectate(gameID string) (*network.SpectateStartPayload, error) {
+	msg, err := h.request(network.MessageTypeSpectate, &network.SpectatePayload{GameID: gameID}, network.MessageTypeSpectateStart)
+	if err != nil {
+		return nil, err
+	}
+	return network.Decode[network.SpectateStartPayload](msg)
+}
+
+// request sends a message and waits for the first message of one of the expected types or
+// an error message, which is returned as a ServerError
+func (h *Headless) request(msgType network.MessageType, payload interface{}, expected ...network.MessageType) (*network.Message, error) {
+	h.requestMutex.Lock()
+	defer h.requestMutex.Unlock()
+
+	waiter := &pendingReply{types: expected, reply: make(chan *network.Message, 1)}
+	h.setWaiter(waiter)
+	defer h.setWaiter(nil)
+
+	if err := h.client.Send(msgType, payload); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(h.Timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-waiter.reply:
+		if msg.Type == network.MessageTypeError {
+			errorPayload, err := network.Decode[network.ErrorPayload](msg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid error from server: %w", err)
+			}
+			return nil, &ServerError{Code: errorPayload.Code, Message: errorPayload.Message}
+		}
+		return msg, nil
+	case <-timer.C:
+		logger.Client.Warn("No answer to %s within %v", msgType, h.Timeout)
+		return nil, fmt.Errorf("%s: %w", msgType, ErrRequestTimeout)
+	case <-h.client.disconnectChan:
+		return nil, fmt.Errorf("%s: %w", msgType, ErrDisconnected)
+	}
+}
+
+// setWaiter sets the request waiting for an answer, nil when none is
+func (h *Headless) setWaiter(waiter *pendingReply) {
+	h.waitMutex.Lock()
+	defer h.waitMutex.Unlock()
+	h.waiter = waiter
+}
+
+// handleMessage hands a message to the waiting request if it expects it, and delivers it
+// on Events otherwise
+func (h *Headless) handleMessage(msg *network.Message) error {
+	h.waitMutex.Lock()
+	waiter := h.waiter
+	if waiter != nil && waiter.expects(msg.Type) {
+		h.waiter = nil
+		h.waitMutex.Unlock()
+		waiter.reply <- msg
+		return nil
+	}
+	h.waitMutex.Unlock()
+
+	select {
+	case h.events <- decodeEvent(msg):
+	default:
+		h.dropped.Add(1)
+		logger.Client.Warn("Event buffer full, dropped %s", msg.Type)
+	}
+	return nil
+}
+
+// expects reports whether a message of the type answers the request
+func (w *pendingReply) expects(msgType network.MessageType) bool {
+	if msgType == network.MessageTypeError {
+		return true
+	}
+	for _, t := range w.types {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeEvent decodes a message into an Event
+func decodeEvent(msg *network.Message) Event {
+	payload, err := network.DecodeMessage(msg)
+	if err != nil {
+		logger.Client.Warn("Failed to decode %s from server: %v", msg.Type, err)
+	}
+	return Event{Type: msg.Type, Payload: payload, Received: time.Now()}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// startHelloServer listens on a local port and answers each connection's hello. It closes
+// the connection when the client quits, as the real server does, or right after the hello
+// when hangUp is set.
+func startHelloServer(t *testing.T, hangUp bool) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				codec := network.NewCodec(conn)
+				for {
+					msg, err := codec.Receive()
+					if err != nil {
+						return
+					}
+					switch msg.Type {
+					case network.MessageTypeHello:
+						codec.Send(network.MessageTypeHello, &network.HelloPayload{
+							ProtocolVersion: network.ProtocolVersion,
+							ServerName:      "test-server",
+						})
+						if hangUp {
+							return
+						}
+					case network.MessageTypeQuit:
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber
+}
+
+// TestHeadlessConnectAndClose is meant to run with -race: Close and the receive loop both
+// mark the client disconnected while requests check whether it is connected
+func TestHeadlessConnectAndClose(t *testing.T) {
+	tests := []struct {
+		name   string
+		hangUp bool
+	}{
+		{name: "client closes", hangUp: false},
+		{name: "server hangs up", hangUp: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := startHelloServer(t, tt.hangUp)
+
+			for i := 0; i < 10; i++ {
+				h := NewHeadless(host, port)
+				if err := h.Connect(); err != nil {
+					t.Fatalf("Connect: %v", err)
+				}
+
+				// Keep sending requests until the connection goes away
+				polled := make(chan struct{})
+				go func() {
+					defer close(polled)
+					for h.Client().IsConnected() {
+						h.Client().Send(network.MessageTypeQueueStatus, &network.QueueStatusPayload{})
+					}
+				}()
+
+				if !tt.hangUp {
+					if err := h.Close(); err != nil {
+						t.Fatalf("Close: %v", err)
+					}
+				}
+				select {
+				case <-h.Done():
+				case <-time.After(time.Second):
+					t.Fatal("Done was not closed")
+				}
+				<-polled
+				if h.Client().IsConnected() {
+					t.Error("client is still connected")
+				}
+				if err := h.Close(); err != nil {
+					t.Errorf("Close after disconnect: %v", err)
+				}
+				if err := h.Client().Send(network.MessageTypeQueueStatus, &network.QueueStatusPayload{}); err == nil {
+					t.Error("Send succeeded after the connection closed")
+				}
+			}
+		})
+	}
+}
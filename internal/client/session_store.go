@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/pkg/logger"
+)
+
+// storedSession is a session token remembered for one server
+type storedSession struct {
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DefaultSessionStorePath returns ~/.tcr/sessions.json, or "" if there is no home directory
+func DefaultSessionStorePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tcr", "sessions.json")
+}
+
+// serverKey identifies the server the client talks to in the session store
+func (c *Client) serverKey() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// loadSessions reads all remembered sessions, keyed by server
+func (c *Client) loadSessions() map[string]storedSession {
+	sessions := make(map[string]storedSession)
+	if c.SessionStorePath == "" {
+		return sessions
+	}
+
+	data, err := os.ReadFile(c.SessionStorePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Client.Warn("Failed to read session store %s: %v", c.SessionStorePath, err)
+		}
+		return sessions
+	}
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		logger.Client.Warn("Ignoring corrupt session store %s: %v", c.SessionStorePath, err)
+		return make(map[string]storedSession)
+	}
+	return sessions
+}
+
+// saveSessions writes the remembered sessions, readable by the current user only
+func (c *Client) saveSessions(sessions map[string]storedSession) {
+	if c.SessionStorePath == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		logger.Client.Warn("Failed to encode session store: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.SessionStorePath), 0700); err != nil {
+		logger.Client.Warn("Failed to create session store directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(c.SessionStorePath, data, 0600); err != nil {
+		logger.Client.Warn("Failed to write session store %s: %v", c.SessionStorePath, err)
+	}
+}
+
+// storedSessionForServer returns the unexpired session remembered for this server, if any
+func (c *Client) storedSessionForServer() (storedSession, bool) {
+	session, exists := c.loadSessions()[c.serverKey()]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return storedSession{}, false
+	}
+	return session, true
+}
+
+// rememberSession stores the session token issued by this server
+func (c *Client) rememberSession(session storedSession) {
+	sessions := c.loadSessions()
+	sessions[c.serverKey()] = session
+	c.saveSessions(sessions)
+}
+
+// forgetSession removes the session remembered for this server
+func (c *Client) forgetSession() {
+	sessions := c.loadSessions()
+	if _, exists := sessions[c.serverKey()]; !exists {
+		return
+	}
+	delete(sessions, c.serverKey())
+	c.saveSessions(sessions)
+}
@@ -1,5 +1,21 @@
 package game
 
+import (
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// CritMultiplier is applied to the attacker's ATK on a critical hit
+const CritMultiplier = 1.2
+
+// LevelMultiplier returns the factor a player's level applies to the base stats of their
+// towers and troops: 10% more per level above 1.
+// Stat Formula: Level N Stat = BaseStat * (1 + 0.1 * (N-1))
+func LevelMultiplier(level int) float64 {
+	return 1.0 + 0.1*float64(level-1)
+}
+
 // CalculateDamage calculates the damage dealt based on attacker's ATK and defender's DEF.
 // Damage Formula: DMG = Attacker_ATK - Defender_DEF. If DMG < 0, DMG = 0.
 func CalculateDamage(attackerATK, defenderDEF int) int {
@@ -9,3 +25,56 @@ func CalculateDamage(attackerATK, defenderDEF int) int {
 	}
 	return damage
 }
+
+// CalculateCritDamage calculates the damage dealt by a critical hit.
+// Damage Formula: DMG = (Attacker_ATK * 1.2) - Defender_DEF. If DMG < 0, DMG = 0.
+func CalculateCritDamage(attackerATK, defenderDEF int) int {
+	return CalculateDamage(int(float64(attackerATK)*CritMultiplier), defenderDEF)
+}
+
+// RandomSource provides the random numbers used for critical hit rolls.
+// *rand.Rand satisfies it; tests can supply a fixed source to force outcomes.
+type RandomSource interface {
+	Float64() float64 // Returns a number in [0.0, 1.0)
+}
+
+// AttackResult describes the outcome of a single attack
+type AttackResult struct {
+	Damage   int
+	Critical bool
+}
+
+// CombatResolver resolves attacks, rolling for critical hits with its random source
+type CombatResolver struct {
+	rng RandomSource
+}
+
+// NewCombatResolver creates a combat resolver using the given random source
+func NewCombatResolver(rng RandomSource) *CombatResolver {
+	return &CombatResolver{
+		rng: rng,
+	}
+}
+
+// ResolveAttack rolls for a critical hit and returns the damage dealt.
+// critChance is a percentage (e.g., 5.0 for 5%), as in the tower/troop config files.
+func (r *CombatResolver) ResolveAttack(attackerATK int, critChance float64, defenderDEF int) AttackResult {
+	if critChance > 0 && r.rng.Float64()*100 < critChance {
+		return AttackResult{Damage: CalculateCritDamage(attackerATK, defenderDEF), Critical: true}
+	}
+	return AttackResult{Damage: CalculateDamage(attackerATK, defenderDEF)}
+}
+
+// attackEvent returns the event describing an attack, flagged and worded as a critical hit
+// when the attack was one
+func attackEvent(message string, result AttackResult) network.GameEventPayload {
+	event := network.GameEventPayload{
+		Message:  message,
+		Time:     time.Now(),
+		Critical: result.Critical,
+	}
+	if event.Critical {
+		event.Message += " CRITICAL HIT!"
+	}
+	return event
+}
@@ -0,0 +1,409 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/google/uuid"
+)
+
+// Enhanced mode tuning values (see documents/proposed-plan.md, "Enhanced TCR Rules")
+const (
+	EnhancedGameDuration   = 3 * time.Minute // Length of a real-time match
+	EnhancedTickInterval   = time.Second     // Troops and towers attack once per tick
+	EnhancedStartingMana   = 5
+	EnhancedMaxMana        = 10
+	EnhancedManaRegenTicks = 2 // 1 MANA every 2 ticks (2 seconds)
+)
+
+// EnhancedModeHandler handles the logic for the Enhanced (real-time) TCR mode
+type EnhancedModeHandler struct {
+	game   *Game
+	base   *SimpleModeHandler // Shared board helpers (towers, targeting, cleanup)
+	combat *CombatResolver    // Resolves attacks including CRIT rolls
+	ticks  int                // Number of ticks processed since the game started
+}
+
+// NewEnhancedModeHandler creates a new handler for Enhanced TCR mode
+func NewEnhancedModeHandler(game *Game) *EnhancedModeHandler {
+	return &EnhancedModeHandler{
+		game:   game,
+		base:   NewSimpleModeHandler(game),
+		combat: NewCombatResolver(game.Rand()),
+	}
+}
+
+// SetRandomSource replaces the random source used for critical hit rolls
+func (h *EnhancedModeHandler) SetRandomSource(rng RandomSource) {
+	h.combat = NewCombatResolver(rng)
+}
+
+// StartGame initializes the game state for Enhanced mode
+func (h *EnhancedModeHandler) StartGame(player1 *models.Player, player2 *models.Player, towerSpecs map[string]*models.TowerSpec, troopSpecs map[string]*models.TroopSpec) error {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+
+	// Initialize towers for both players using the same rules as Simple mode
+	if err := h.base.initializeTowers(player1, player2, towerSpecs); err != nil {
+		return fmt.Errorf("failed to initialize towers: %w", err)
+	}
+
+	for _, player := range h.game.Players {
+		player.CurrentMana = EnhancedStartingMana
+	}
+
+	h.game.GameState = GameStateRunningEnhanced
+	h.game.StartTime = time.Now()
+	h.game.EndTime = h.game.StartTime.Add(EnhancedGameDuration)
+	h.ticks = 0
+
+	return nil
+}
+
+// DeployTroop deploys a troop for the given player if they have enough mana.
+// Troops can be deployed at any time; they start attacking on the next tick.
+func (h *EnhancedModeHandler) DeployTroop(playerIndex int, troopID string) ([]network.GameEventPayload, error) {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+
+	if h.game.GameState != GameStateRunningEnhanced {
+		return nil, fmt.Errorf("game is not running in enhanced mode")
+	}
+	if playerIndex < 0 || playerIndex > 1 {
+		return nil, fmt.Errorf("invalid player index: %d", playerIndex)
+	}
+
+	player := h.game.Players[playerIndex]
+	opponent := h.game.Players[(playerIndex+1)%2]
+
+	troopSpec, exists := h.game.TroopSpecs[troopID]
+	if !exists {
+		return nil, fmt.Errorf("troop spec not found for ID: %s", troopID)
+	}
+
+	if player.CurrentMana < troopSpec.ManaCost {
+		return nil, fmt.Errorf("not enough mana to deploy %s (have %d, need %d)", troopSpec.Name, player.CurrentMana, troopSpec.ManaCost)
+	}
+	player.CurrentMana -= troopSpec.ManaCost
+
+	var events []network.GameEventPayload
+	events = append(events, network.GameEventPayload{
+		Message: fmt.Sprintf("%s deployed %s (-%d mana)", player.Username, troopSpec.Name, troopSpec.ManaCost),
+		Time:    time.Now(),
+	})
+
+	// Queen is a one-time heal and does not persist on the board
+	if troopID == "queen" {
+		healEvents, err := h.base.processQueenHealing(player)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process queen healing: %w", err)
+		}
+		return append(events, healEvents...), nil
+	}
+
+	// Calculate level multiplier for player stats (10% increase per level)
+	levelMultiplier := LevelMultiplier(player.Level)
+
+	instanceID := uuid.New().String()
+	troop := &ActiveTroop{
+		InstanceID:    instanceID,
+		SpecID:        troopID,
+		Name:          troopSpec.Name,
+		CurrentHP:     int(float64(troopSpec.BaseHP) * levelMultiplier),
+		MaxHP:         int(float64(troopSpec.BaseHP) * levelMultiplier),
+		ATK:           int(float64(troopSpec.BaseATK) * levelMultiplier),
+		DEF:           int(float64(troopSpec.BaseDEF) * levelMultiplier),
+		CritChance:    troopSpec.CritChance,
+		OwnerPlayerID: player.ID,
+		DeployedTime:  time.Now(),
+		DeploySeq:     h.game.nextDeploySeq(),
+	}
+
+	if targetTower := h.base.findValidTarget(opponent); targetTower != nil {
+		troop.TargetID = targetTower.ID
+		events = append(events, network.GameEventPayload{
+			Message: fmt.Sprintf("%s's %s is targeting %s's %s", player.Username, troop.Name, opponent.Username, targetTower.Name),
+			Time:    time.Now(),
+		})
+	}
+
+	player.ActiveTroops[instanceID] = troop
+	h.game.BoardState.ActiveTroops[instanceID] = troop
+
+	return events, nil
+}
+
+// Tick advances the real-time simulation by one step: mana regeneration,
+// troop attacks, tower attacks and win/timeout checks.
+func (h *EnhancedModeHandler) Tick(now time.Time) []network.GameEventPayload {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+
+	if h.game.GameState != GameStateRunningEnhanced {
+		return nil
+	}
+
+	var events []network.GameEventPayload
+	h.ticks++
+
+	// 1. Mana regeneration
+	if h.ticks%EnhancedManaRegenTicks == 0 {
+		for _, player := range h.game.Players {
+			if player.CurrentMana < EnhancedMaxMana {
+				player.CurrentMana++
+			}
+		}
+	}
+
+	// 2. Troop attacks (each troop attacks once per tick)
+	for i, player := range h.game.Players {
+		opponent := h.game.Players[(i+1)%2]
+		events = append(events, h.processTroopAttacks(player, opponent)...)
+		if h.game.GameState == GameStateFinished {
+			return events
+		}
+	}
+
+	// 3. Tower attacks (each tower attacks once per tick if it has a valid target)
+	for i, player := range h.game.Players {
+		opponent := h.game.Players[(i+1)%2]
+		events = append(events, h.processTowerAttacks(player, opponent)...)
+	}
+
+	// 4. Cleanup defeated troops
+	h.base.cleanupDefeatedUnits(h.game.Players[0], h.game.Players[1])
+
+	// 5. Timeout check
+	if !now.Before(h.game.EndTime) {
+		events = append(events, h.resolveTimeout()...)
+	}
+
+	return events
+}
+
+// Run drives the real-time game loop, calling Tick once per EnhancedTickInterval until
+// the game finishes or stop is closed. Events produced by each tick are delivered on
+// the returned channel, which is closed when the loop exits.
+func (h *EnhancedModeHandler) Run(stop <-chan struct{}) <-chan []network.GameEventPayload {
+	updates := make(chan []network.GameEventPayload)
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(EnhancedTickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				events := h.Tick(now)
+				select {
+				case updates <- events:
+				case <-stop:
+					return
+				}
+				if h.IsFinished() {
+					return
+				}
+			}
+		}
+	}()
+
+	return updates
+}
+
+// IsFinished reports whether the game has ended
+func (h *EnhancedModeHandler) IsFinished() bool {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+	return h.game.GameState == GameStateFinished
+}
+
+// TimeLeft returns the remaining match time, never negative
+func (h *EnhancedModeHandler) TimeLeft(now time.Time) time.Duration {
+	remaining := h.game.EndTime.Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// processTroopAttacks makes each of the player's troops attack the opponent's
+// lowest-HP valid tower once (respecting the Guard Tower 1 rule)
+func (h *EnhancedModeHandler) processTroopAttacks(player *PlayerInGame, opponent *PlayerInGame) []network.GameEventPayload {
+	var events []network.GameEventPayload
+
+	for _, troop := range sortedTroops(player.ActiveTroops) {
+		if troop.CurrentHP <= 0 {
+			continue
+		}
+
+		targetTower := h.base.findValidTarget(opponent)
+		if targetTower == nil {
+			troop.TargetID = ""
+			continue
+		}
+		troop.TargetID = targetTower.ID
+
+		result := h.combat.ResolveAttack(troop.ATK, troop.CritChance, targetTower.DEF)
+		originalTowerHP := targetTower.CurrentHP
+		targetTower.CurrentHP -= result.Damage
+		if targetTower.CurrentHP < 0 {
+			targetTower.CurrentHP = 0
+		}
+		targetTower.LastAttackedByTroopID = troop.InstanceID
+
+		events = append(events, attackEvent(fmt.Sprintf("%s's %s attacks %s's %s for %d damage (HP: %d -> %d/%d)",
+			player.Username, troop.Name, opponent.Username, targetTower.Name, result.Damage, originalTowerHP, targetTower.CurrentHP, targetTower.MaxHP), result))
+
+		if targetTower.CurrentHP <= 0 {
+			events = append(events, network.GameEventPayload{
+				Message: fmt.Sprintf("%s's %s was destroyed!", opponent.Username, targetTower.Name),
+				Time:    time.Now(),
+			})
+
+			if gameOver, winnerIdx, loserIdx := h.base.checkGameOver(); gameOver {
+				events = append(events, h.finishGame(winnerIdx, loserIdx, "King Tower destroyed"))
+				return events
+			}
+		}
+	}
+
+	return events
+}
+
+// processTowerAttacks makes each of the player's living towers attack one enemy troop:
+// the troop that last attacked it, or otherwise the oldest troop targeting it.
+func (h *EnhancedModeHandler) processTowerAttacks(player *PlayerInGame, opponent *PlayerInGame) []network.GameEventPayload {
+	var events []network.GameEventPayload
+
+	for _, tower := range sortedTowers(player.Towers) {
+		if tower.CurrentHP <= 0 {
+			continue
+		}
+
+		targetTroop := h.findTowerTarget(tower, opponent)
+		if targetTroop == nil {
+			continue
+		}
+
+		result := h.combat.ResolveAttack(tower.ATK, tower.CritChance, targetTroop.DEF)
+		originalTroopHP := targetTroop.CurrentHP
+		targetTroop.CurrentHP -= result.Damage
+
+		events = append(events, attackEvent(fmt.Sprintf("%s's %s attacks %s's %s for %d damage (HP: %d -> %d/%d)",
+			player.Username, tower.Name, opponent.Username, targetTroop.Name, result.Damage, originalTroopHP, targetTroop.CurrentHP, targetTroop.MaxHP), result))
+
+		if targetTroop.CurrentHP <= 0 {
+			targetTroop.CurrentHP = 0
+			events = append(events, network.GameEventPayload{
+				Message: fmt.Sprintf("%s's %s was defeated by %s's %s!",
+					opponent.Username, targetTroop.Name, player.Username, tower.Name),
+				Time: time.Now(),
+			})
+		}
+	}
+
+	return events
+}
+
+// findTowerTarget returns the enemy troop a tower should attack this tick, or nil
+func (h *EnhancedModeHandler) findTowerTarget(tower *Tower, opponent *PlayerInGame) *ActiveTroop {
+	// Prefer the last attacker if it is still alive
+	if troop, exists := opponent.ActiveTroops[tower.LastAttackedByTroopID]; exists && troop.CurrentHP > 0 {
+		return troop
+	}
+
+	// Otherwise pick the oldest living troop targeting this tower
+	for _, troop := range sortedTroops(opponent.ActiveTroops) {
+		if troop.CurrentHP > 0 && troop.TargetID == tower.ID {
+			return troop
+		}
+	}
+	return nil
+}
+
+// resolveTimeout ends the game when time runs out: the player who destroyed more
+// towers wins, otherwise the match is a draw
+func (h *EnhancedModeHandler) resolveTimeout() []network.GameEventPayload {
+	destroyed := [2]int{}
+	for i := range h.game.Players {
+		opponent := h.game.Players[(i+1)%2]
+		for _, tower := range opponent.Towers {
+			if tower.CurrentHP <= 0 {
+				destroyed[i]++
+			}
+		}
+	}
+
+	switch {
+	case destroyed[0] > destroyed[1]:
+		return []network.GameEventPayload{h.finishGame(0, 1, "Time expired")}
+	case destroyed[1] > destroyed[0]:
+		return []network.GameEventPayload{h.finishGame(1, 0, "Time expired")}
+	}
+
+	h.game.GameState = GameStateFinished
+	h.game.EndTime = time.Now()
+	h.game.EndReason = "Time expired (draw)"
+	return []network.GameEventPayload{{
+		Message: fmt.Sprintf("Game Over: time expired with %d towers destroyed each. It's a draw!", destroyed[0]),
+		Time:    time.Now(),
+	}}
+}
+
+// finishGame marks the game as finished with a winner and returns the game over event
+func (h *EnhancedModeHandler) finishGame(winnerIdx, loserIdx int, reason string) network.GameEventPayload {
+	h.game.GameState = GameStateFinished
+	h.game.EndTime = time.Now()
+	h.game.WinnerID = h.game.Players[winnerIdx].ID
+	h.game.LoserID = h.game.Players[loserIdx].ID
+	h.game.EndReason = reason
+	return network.GameEventPayload{
+		Message: fmt.Sprintf("Game Over: %s wins! (%s)", h.game.Players[winnerIdx].Username, reason),
+		Time:    time.Now(),
+	}
+}
+
+// GetGameState prepares a game state message for the given player, including mana and time left
+func (h *EnhancedModeHandler) GetGameState(playerIndex int) *network.GameStatePayload {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+
+	gameState := h.base.GetGameState(playerIndex)
+	gameState.YourMana = h.game.Players[playerIndex].CurrentMana
+	gameState.OpponentMana = h.game.Players[(playerIndex+1)%2].CurrentMana
+	gameState.TimeLeft = int(h.TimeLeft(time.Now()).Seconds())
+	return gameState
+}
+
+// sortedTroops returns troops in deployment order, so attacks (and their CRIT rolls)
+// happen in the same order for the same game seed and deploys. The deploy sequence
+// numbers every troop in the game, unlike clock times or random instance IDs.
+func sortedTroops(troops map[string]*ActiveTroop) []*ActiveTroop {
+	sorted := make([]*ActiveTroop, 0, len(troops))
+	for _, troop := range troops {
+		sorted = append(sorted, troop)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DeploySeq < sorted[j].DeploySeq
+	})
+	return sorted
+}
+
+// sortedTowers returns towers ordered by ID, for deterministic attack order
+func sortedTowers(towers map[string]*Tower) []*Tower {
+	sorted := make([]*Tower, 0, len(towers))
+	for _, tower := range towers {
+		sorted = append(sorted, tower)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+)
+
+// testSpecs returns tower and troop specs like the ones in config/
+func testSpecs() (map[string]*models.TowerSpec, map[string]*models.TroopSpec) {
+	return SpecPointers(&models.GameConfig{
+		Towers: map[string]models.TowerSpec{
+			"king_tower":  {ID: "king_tower", Name: "King Tower", BaseHP: 2000, BaseATK: 500, BaseDEF: 300, CritChance: 10},
+			"guard_tower": {ID: "guard_tower", Name: "Guard Tower", BaseHP: 1000, BaseATK: 300, BaseDEF: 100, CritChance: 5},
+		},
+		Troops: map[string]models.TroopSpec{
+			"pawn":   {ID: "pawn", Name: "Pawn", BaseHP: 50, BaseATK: 150, BaseDEF: 100, ManaCost: 3},
+			"bishop": {ID: "bishop", Name: "Bishop", BaseHP: 100, BaseATK: 200, BaseDEF: 150, ManaCost: 4},
+			"rook":   {ID: "rook", Name: "Rook", BaseHP: 250, BaseATK: 200, BaseDEF: 200, ManaCost: 5},
+			"knight": {ID: "knight", Name: "Knight", BaseHP: 200, BaseATK: 300, BaseDEF: 150, ManaCost: 5},
+			"prince": {ID: "prince", Name: "Prince", BaseHP: 500, BaseATK: 400, BaseDEF: 300, ManaCost: 6},
+			"queen":  {ID: "queen", Name: "Queen", ManaCost: 5, HasSpecial: true},
+		},
+	})
+}
+
+// newEnhancedTestGame starts an Enhanced mode game between alice and bob
+func newEnhancedTestGame(t *testing.T) *EnhancedModeHandler {
+	t.Helper()
+	towerSpecs, troopSpecs := testSpecs()
+	alice := &models.Player{ID: "alice", Username: "alice", Level: 1}
+	bob := &models.Player{ID: "bob", Username: "bob", Level: 1}
+	g := NewGame("test-game", alice, bob, GameModeEnhanced, towerSpecs, troopSpecs, 1)
+	h := NewEnhancedModeHandler(g)
+	if err := h.StartGame(alice, bob, towerSpecs, troopSpecs); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	return h
+}
+
+func TestEnhancedTickRegeneratesMana(t *testing.T) {
+	tests := []struct {
+		name     string
+		mana     int
+		ticks    int
+		wantMana int
+	}{
+		{name: "no mana after one tick", mana: 5, ticks: 1, wantMana: 5},
+		{name: "one mana every two ticks", mana: 5, ticks: 4, wantMana: 7},
+		{name: "capped at max mana", mana: EnhancedMaxMana - 1, ticks: 6, wantMana: EnhancedMaxMana},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newEnhancedTestGame(t)
+			for _, player := range h.game.Players {
+				player.CurrentMana = tt.mana
+			}
+
+			for i := 0; i < tt.ticks; i++ {
+				h.Tick(h.game.StartTime)
+			}
+
+			for _, player := range h.game.Players {
+				if player.CurrentMana != tt.wantMana {
+					t.Errorf("%s has %d mana, want %d", player.Username, player.CurrentMana, tt.wantMana)
+				}
+			}
+		})
+	}
+}
+
+func TestEnhancedTickResolvesTimeout(t *testing.T) {
+	tests := []struct {
+		name          string
+		destroyed     []string // Towers destroyed before the final tick
+		afterEnd      bool
+		wantFinished  bool
+		wantWinner    string
+		wantEndReason string
+	}{
+		{name: "still running before the end", destroyed: []string{"guard1_bob"}, afterEnd: false, wantFinished: false},
+		{name: "more towers destroyed wins", destroyed: []string{"guard1_bob", "guard1_alice", "guard2_bob"}, afterEnd: true, wantFinished: true, wantWinner: "alice", wantEndReason: "Time expired"},
+		{name: "equal towers destroyed is a draw", destroyed: []string{"guard1_bob", "guard1_alice"}, afterEnd: true, wantFinished: true, wantEndReason: "Time expired (draw)"},
+		{name: "no towers destroyed is a draw", afterEnd: true, wantFinished: true, wantEndReason: "Time expired (draw)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newEnhancedTestGame(t)
+			for _, id := range tt.destroyed {
+				h.game.BoardState.Towers[id].CurrentHP = 0
+			}
+
+			now := h.game.EndTime.Add(-time.Second)
+			if tt.afterEnd {
+				now = h.game.EndTime
+			}
+			events := h.Tick(now)
+
+			if h.IsFinished() != tt.wantFinished {
+				t.Fatalf("finished = %v, want %v", h.IsFinished(), tt.wantFinished)
+			}
+			if !tt.wantFinished {
+				return
+			}
+			if h.game.WinnerID != tt.wantWinner {
+				t.Errorf("winner = %q, want %q", h.game.WinnerID, tt.wantWinner)
+			}
+			if h.game.EndReason != tt.wantEndReason {
+				t.Errorf("end reason = %q, want %q", h.game.EndReason, tt.wantEndReason)
+			}
+			if len(events) == 0 || !strings.HasPrefix(events[len(events)-1].Message, "Game Over") {
+				t.Errorf("last event is not the game over: %+v", events)
+			}
+			if h.Tick(now) != nil {
+				t.Error("Tick produced events after the game finished")
+			}
+		})
+	}
+}
+
+func TestEnhancedTroopsAttackInDeployOrder(t *testing.T) {
+	h := newEnhancedTestGame(t)
+	h.game.Players[0].CurrentMana = EnhancedMaxMana
+	for _, troopID := range []string{"rook", "knight"} {
+		if _, err := h.DeployTroop(0, troopID); err != nil {
+			t.Fatalf("DeployTroop(%s): %v", troopID, err)
+		}
+	}
+
+	var attackers []string
+	for _, event := range h.Tick(h.game.StartTime) {
+		if strings.HasPrefix(event.Message, "alice's ") && strings.Contains(event.Message, " attacks ") {
+			attackers = append(attackers, strings.Fields(event.Message)[1])
+		}
+	}
+	if strings.Join(attackers, ",") != "Rook,Knight" {
+		t.Errorf("troops attacked in order %v, want [Rook Knight]", attackers)
+	}
+}
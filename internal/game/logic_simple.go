@@ -3,7 +3,7 @@ package game
 
 import (
 	"fmt"
-	"math/rand"
+	"sort"
 	"time"
 
 	"github.com/NP-Dat/net-centric-project/internal/models"
@@ -13,16 +13,23 @@ import (
 
 // SimpleModeHandler handles the logic for the Simple TCR mode
 type SimpleModeHandler struct {
-	game *Game
+	game   *Game
+	combat *CombatResolver // Resolves attacks including CRIT rolls
 }
 
 // NewSimpleModeHandler creates a new handler for Simple TCR mode
 func NewSimpleModeHandler(game *Game) *SimpleModeHandler {
 	return &SimpleModeHandler{
-		game: game,
+		game:   game,
+		combat: NewCombatResolver(game.Rand()),
 	}
 }
 
+// SetRandomSource replaces the random source used for critical hit rolls
+func (h *SimpleModeHandler) SetRandomSource(rng RandomSource) {
+	h.combat = NewCombatResolver(rng)
+}
+
 // StartGame initializes the game state for Simple mode
 func (h *SimpleModeHandler) StartGame(player1 *models.Player, player2 *models.Player, towerSpecs map[string]*models.TowerSpec, troopSpecs map[string]*models.TroopSpec) error {
 	// Change game state to Running Simple
@@ -68,7 +75,7 @@ func (h *SimpleModeHandler) createPlayerTowers(player *PlayerInGame, towerSpecs
 	}
 
 	// Calculate level multiplier for player stats (10% increase per level)
-	levelMultiplier := 1.0 + 0.1*float64(player.Level-1)
+	levelMultiplier := LevelMultiplier(player.Level)
 
 	// Create King Tower
 	kingTower := &Tower{
@@ -144,8 +151,12 @@ func (h *SimpleModeHandler) GenerateAndStoreTroopChoices(player *PlayerInGame) (
 		return &network.TroopChoicesPayload{Choices: []network.TroopChoiceInfo{}}, nil // No troops to offer (excluding Queen)
 	}
 
-	rand.Seed(time.Now().UnixNano()) // Seed random number generator
-	rand.Shuffle(len(availableSpecs), func(i, j int) {
+	// Sort before shuffling: map iteration order is random, and the shuffle must
+	// depend only on the game's seed for matches to be reproducible
+	sort.Slice(availableSpecs, func(i, j int) bool {
+		return availableSpecs[i].ID < availableSpecs[j].ID
+	})
+	h.game.Rand().Shuffle(len(availableSpecs), func(i, j int) {
 		availableSpecs[i], availableSpecs[j] = availableSpecs[j], availableSpecs[i]
 	})
 
@@ -164,6 +175,35 @@ func (h *SimpleModeHandler) ProcessTurn(playerIndex int, action string, actionDa
 	h.game.Mutex.Lock() // Lock for thread safety
 	defer h.game.Mutex.Unlock()
 
+	return h.processTurnLocked(playerIndex, action, actionData)
+}
+
+// AutoDeploy plays a timed out turn by deploying one of the player's offered troops. The
+// troop is picked with the game's seeded source, so a replay picks the same one. The troop
+// is returned along with the turn's events; it is empty if none was picked, and set even if
+// deploying it failed.
+func (h *SimpleModeHandler) AutoDeploy(playerIndex int) (string, []network.GameEventPayload, error) {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+
+	if h.game.CurrentTurnPlayerIndex != playerIndex {
+		return "", nil, fmt.Errorf("not your turn")
+	}
+	if h.game.GameState != GameStateRunningSimple {
+		return "", nil, fmt.Errorf("game is not running in simple mode")
+	}
+	offered := h.game.Players[playerIndex].OfferedTroopChoices
+	if len(offered) == 0 {
+		return "", nil, fmt.Errorf("no troop choices were offered")
+	}
+
+	troopID := offered[h.game.Rand().Intn(len(offered))].ID
+	events, err := h.processTurnLocked(playerIndex, "deploy_troop", map[string]interface{}{"troop_id": troopID})
+	return troopID, events, err
+}
+
+// processTurnLocked processes a player's action. The caller holds the game's Mutex.
+func (h *SimpleModeHandler) processTurnLocked(playerIndex int, action string, actionData map[string]interface{}) ([]network.GameEventPayload, error) {
 	// Ensure it's the correct player's turn
 	if h.game.CurrentTurnPlayerIndex != playerIndex {
 		return nil, fmt.Errorf("not your turn")
@@ -252,6 +292,7 @@ func (h *SimpleModeHandler) ProcessTurn(playerIndex int, action string, actionDa
 		h.game.EndTime = time.Now()
 		h.game.WinnerID = h.game.Players[winnerIdx].ID
 		h.game.LoserID = h.game.Players[loserIdx].ID
+		h.game.EndReason = "King Tower destroyed"
 		events = append(events, network.GameEventPayload{
 			Message: fmt.Sprintf("Game Over: %s wins! (King Tower destroyed)", h.game.Players[winnerIdx].Username),
 			Time:    time.Now(),
@@ -280,6 +321,7 @@ func (h *SimpleModeHandler) ProcessTurn(playerIndex int, action string, actionDa
 		h.game.EndTime = time.Now()
 		h.game.WinnerID = h.game.Players[winnerIdx].ID
 		h.game.LoserID = h.game.Players[loserIdx].ID
+		h.game.EndReason = "King Tower destroyed"
 		events = append(events, network.GameEventPayload{
 			Message: fmt.Sprintf("Game Over: %s wins! (King Tower destroyed)", h.game.Players[winnerIdx].Username),
 			Time:    time.Now(),
@@ -302,6 +344,28 @@ func (h *SimpleModeHandler) ProcessTurn(playerIndex int, action string, actionDa
 	return events, nil
 }
 
+// SkipTurn passes the player's turn to the opponent without an action, e.g. when a timed
+// out turn cannot be played automatically
+func (h *SimpleModeHandler) SkipTurn(playerIndex int) ([]network.GameEventPayload, error) {
+	h.game.Mutex.Lock()
+	defer h.game.Mutex.Unlock()
+
+	if h.game.CurrentTurnPlayerIndex != playerIndex {
+		return nil, fmt.Errorf("not your turn")
+	}
+	if h.game.GameState != GameStateRunningSimple {
+		return nil, fmt.Errorf("game is not running in simple mode")
+	}
+
+	h.game.Players[playerIndex].OfferedTroopChoices = nil
+	h.nextTurn()
+
+	return []network.GameEventPayload{{
+		Message: fmt.Sprintf("%s's turn was skipped. Turn changed to player %s", h.game.Players[playerIndex].Username, h.game.Players[h.game.CurrentTurnPlayerIndex].Username),
+		Time:    time.Now(),
+	}}, nil
+}
+
 // deployTroop creates a new troop instance for the player
 func (h *SimpleModeHandler) deployTroop(player *PlayerInGame, opponent *PlayerInGame, troopID string) ([]network.GameEventPayload, error) {
 	var events []network.GameEventPayload
@@ -346,7 +410,7 @@ func (h *SimpleModeHandler) deployTroop(player *PlayerInGame, opponent *PlayerIn
 	instanceID := uuid.New().String()
 
 	// Calculate level multiplier for player stats (10% increase per level)
-	levelMultiplier := 1.0 + 0.1*float64(player.Level-1)
+	levelMultiplier := LevelMultiplier(player.Level)
 
 	// Create new troop instance using stats from spec and player level
 	troop := &ActiveTroop{
@@ -357,8 +421,11 @@ func (h *SimpleModeHandler) deployTroop(player *PlayerInGame, opponent *PlayerIn
 		MaxHP:         int(float64(troopSpec.BaseHP) * levelMultiplier),
 		ATK:           int(float64(troopSpec.BaseATK) * levelMultiplier),
 		DEF:           int(float64(troopSpec.BaseDEF) * levelMultiplier),
+		CritChance:    troopSpec.CritChance,
 		OwnerPlayerID: player.ID,
 		DeployedTime:  time.Now(), // Mark deployment time
+		DeployedTurn:  h.game.TurnNumber,
+		DeploySeq:     h.game.nextDeploySeq(),
 	}
 
 	// Determine target tower based on Guard Tower 1 rule
@@ -437,16 +504,21 @@ func (h *SimpleModeHandler) findValidTarget(opponent *PlayerInGame) *Tower {
 	return lowestHPTarget
 }
 
+// NextTarget returns the tower a troop deployed now by the player at playerIndex would
+// attack, or nil if the opponent has no towers left. The caller holds the game mutex.
+func (g *Game) NextTarget(playerIndex int) *Tower {
+	return (&SimpleModeHandler{game: g}).findValidTarget(g.Players[(playerIndex+1)%2])
+}
+
 // processTroopAttacks processes attacks from troops deployed in previous turns
 func (h *SimpleModeHandler) processTroopAttacks(player *PlayerInGame, opponent *PlayerInGame) ([]network.GameEventPayload, error) {
 	var events []network.GameEventPayload
 
-	// Troops attack towers they're targeting
-	for _, troop := range player.ActiveTroops {
+	// Troops attack towers they're targeting, oldest first so replays resolve in the same order
+	for _, troop := range sortedTroops(player.ActiveTroops) {
 		// Skip troops deployed this turn (they attack next turn)
 		// Rule: "Deployed troops attack ... on the player's turn *after* the turn they were deployed."
-		// Using a simple time check. A turn counter or flag on the troop would be more robust.
-		if time.Since(troop.DeployedTime) < time.Millisecond*100 { // Effectively, not on the same "processing instant" as deployment
+		if troop.DeployedTurn == h.game.TurnNumber {
 			continue
 		}
 
@@ -477,17 +549,14 @@ func (h *SimpleModeHandler) processTroopAttacks(player *PlayerInGame, opponent *
 				})
 			}
 
-			// Calculate damage using the combat function
-			damage := CalculateDamage(troop.ATK, targetTower.DEF)
+			// Calculate damage, rolling for a critical hit
+			result := h.combat.ResolveAttack(troop.ATK, troop.CritChance, targetTower.DEF)
+			damage := result.Damage
 			originalTowerHP := targetTower.CurrentHP
 			targetTower.CurrentHP -= damage
 
-			attackEvent := network.GameEventPayload{
-				Message: fmt.Sprintf("%s's %s attacks %s's %s for %d damage (HP: %d -> %d/%d)",
-					player.Username, troop.Name, opponent.Username, targetTower.Name, damage, originalTowerHP, targetTower.CurrentHP, targetTower.MaxHP),
-				Time: time.Now(),
-			}
-			events = append(events, attackEvent)
+			events = append(events, attackEvent(fmt.Sprintf("%s's %s attacks %s's %s for %d damage (HP: %d -> %d/%d)",
+				player.Username, troop.Name, opponent.Username, targetTower.Name, damage, originalTowerHP, targetTower.CurrentHP, targetTower.MaxHP), result))
 
 			// Record that this tower was attacked by this troop for counterattack purposes
 			if targetTower != nil && (damage > 0 || (damage == 0 && troop.ATK > 0)) { // Ensure targetTower is not nil and an attack attempt was made
@@ -530,7 +599,7 @@ func (h *SimpleModeHandler) processTowerCounterattacks(player *PlayerInGame, opp
 	var events []network.GameEventPayload
 
 	// Iterate through opponent's towers (the ones doing the counterattacking)
-	for _, tower := range opponent.Towers {
+	for _, tower := range sortedTowers(opponent.Towers) {
 		if tower.CurrentHP <= 0 {
 			continue // Destroyed towers cannot attack
 		}
@@ -558,16 +627,14 @@ func (h *SimpleModeHandler) processTowerCounterattacks(player *PlayerInGame, opp
 			continue // Target is invalid, already gone, or not owned by the player whose troops are being counter-attacked.
 		}
 
-		// Tower attacks the targetTroop
-		damage := CalculateDamage(tower.ATK, targetTroop.DEF)
+		// Tower attacks the targetTroop, rolling for a critical hit
+		result := h.combat.ResolveAttack(tower.ATK, tower.CritChance, targetTroop.DEF)
+		damage := result.Damage
 		originalTroopHP := targetTroop.CurrentHP
 		targetTroop.CurrentHP -= damage
 
-		events = append(events, network.GameEventPayload{
-			Message: fmt.Sprintf("%s's %s counterattacks %s's %s for %d damage (HP: %d -> %d/%d)",
-				opponent.Username, tower.Name, player.Username, targetTroop.Name, damage, originalTroopHP, targetTroop.CurrentHP, targetTroop.MaxHP),
-			Time: time.Now(),
-		})
+		events = append(events, attackEvent(fmt.Sprintf("%s's %s counterattacks %s's %s for %d damage (HP: %d -> %d/%d)",
+			opponent.Username, tower.Name, player.Username, targetTroop.Name, damage, originalTroopHP, targetTroop.CurrentHP, targetTroop.MaxHP), result))
 
 		// Check if troop was defeated
 		if targetTroop.CurrentHP <= 0 {
@@ -678,6 +745,7 @@ func (h *SimpleModeHandler) checkGameOver() (bool, int, int) {
 // nextTurn advances to the next player's turn
 func (h *SimpleModeHandler) nextTurn() {
 	h.game.CurrentTurnPlayerIndex = (h.game.CurrentTurnPlayerIndex + 1) % 2
+	h.game.TurnNumber++
 }
 
 // GetGameState prepares a game state message to send to clients
@@ -0,0 +1,119 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+)
+
+// fixedRoll is a RandomSource that always returns the same number
+type fixedRoll float64
+
+func (r fixedRoll) Float64() float64 {
+	return float64(r)
+}
+
+// newCombatTestGame returns a Simple mode game where alice has a troop attacking bob's
+// guard tower, and the tower last attacked by that troop
+func newCombatTestGame(critChance float64) (*SimpleModeHandler, *ActiveTroop, *Tower) {
+	g := NewGame("test-game",
+		&models.Player{ID: "p1", Username: "alice", Level: 1},
+		&models.Player{ID: "p2", Username: "bob", Level: 1},
+		GameModeSimple, nil, nil, 1)
+	g.TurnNumber = 1
+
+	tower := &Tower{
+		ID:            "guard1_p2",
+		SpecID:        "guard_tower",
+		Name:          "Guard Tower 1",
+		CurrentHP:     1000,
+		MaxHP:         1000,
+		ATK:           100,
+		DEF:           10,
+		CritChance:    critChance,
+		OwnerPlayerID: "p2",
+	}
+	g.Players[1].Towers[tower.ID] = tower
+	g.BoardState.Towers[tower.ID] = tower
+
+	troop := &ActiveTroop{
+		InstanceID:    "troop-1",
+		SpecID:        "knight",
+		Name:          "Knight",
+		CurrentHP:     1000,
+		MaxHP:         1000,
+		ATK:           100,
+		DEF:           10,
+		CritChance:    critChance,
+		OwnerPlayerID: "p1",
+		TargetID:      tower.ID,
+		DeployedTurn:  0,
+	}
+	g.Players[0].ActiveTroops[troop.InstanceID] = troop
+	g.BoardState.ActiveTroops[troop.InstanceID] = troop
+
+	return NewSimpleModeHandler(g), troop, tower
+}
+
+func TestSimpleModeCriticalHits(t *testing.T) {
+	tests := []struct {
+		name       string
+		critChance float64
+		roll       float64
+		wantDamage int
+		wantCrit   bool
+	}{
+		{name: "roll below chance crits", critChance: 50, roll: 0.1, wantDamage: 110, wantCrit: true},
+		{name: "roll above chance does not crit", critChance: 50, roll: 0.9, wantDamage: 90, wantCrit: false},
+		{name: "roll equal to chance does not crit", critChance: 50, roll: 0.5, wantDamage: 90, wantCrit: false},
+		{name: "no crit chance never crits", critChance: 0, roll: 0, wantDamage: 90, wantCrit: false},
+	}
+
+	for _, tt := range tests {
+		t.Run("troop attack/"+tt.name, func(t *testing.T) {
+			h, _, tower := newCombatTestGame(tt.critChance)
+			h.SetRandomSource(fixedRoll(tt.roll))
+
+			events, err := h.processTroopAttacks(h.game.Players[0], h.game.Players[1])
+			if err != nil {
+				t.Fatalf("processTroopAttacks: %v", err)
+			}
+			if got := tower.MaxHP - tower.CurrentHP; got != tt.wantDamage {
+				t.Errorf("damage = %d, want %d", got, tt.wantDamage)
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			if events[0].Critical != tt.wantCrit {
+				t.Errorf("event %q: Critical = %v, want %v", events[0].Message, events[0].Critical, tt.wantCrit)
+			}
+			if got := strings.HasSuffix(events[0].Message, "CRITICAL HIT!"); got != tt.wantCrit {
+				t.Errorf("event %q: critical hit message = %v, want %v", events[0].Message, got, tt.wantCrit)
+			}
+		})
+
+		t.Run("tower counterattack/"+tt.name, func(t *testing.T) {
+			h, troop, tower := newCombatTestGame(tt.critChance)
+			h.SetRandomSource(fixedRoll(tt.roll))
+			tower.LastAttackedByTroopID = troop.InstanceID
+
+			events, err := h.processTowerCounterattacks(h.game.Players[0], h.game.Players[1])
+			if err != nil {
+				t.Fatalf("processTowerCounterattacks: %v", err)
+			}
+			if got := troop.MaxHP - troop.CurrentHP; got != tt.wantDamage {
+				t.Errorf("damage = %d, want %d", got, tt.wantDamage)
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			if events[0].Critical != tt.wantCrit {
+				t.Errorf("event %q: Critical = %v, want %v", events[0].Message, events[0].Critical, tt.wantCrit)
+			}
+			if got := strings.HasSuffix(events[0].Message, "CRITICAL HIT!"); got != tt.wantCrit {
+				t.Errorf("event %q: critical hit message = %v, want %v", events[0].Message, got, tt.wantCrit)
+			}
+		})
+	}
+}
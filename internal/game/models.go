@@ -1,6 +1,7 @@
 package game
 
 import (
+	"math/rand"
 	"sync"
 	"time"
 
@@ -33,6 +34,7 @@ type Game struct {
 	GameState              GameState
 	GameMode               GameMode
 	CurrentTurnPlayerIndex int                          // Index of the player whose turn it is (Simple mode)
+	TurnNumber             int                          // Number of completed turns (Simple mode)
 	StartTime              time.Time                    // Game start time
 	EndTime                time.Time                    // Game end time or expected end time
 	BoardState             *BoardState                  // Current state of the game board
@@ -41,6 +43,10 @@ type Game struct {
 	TroopSpecs             map[string]*models.TroopSpec // Added Troop Specs
 	WinnerID               string                       // ID of the winning player
 	LoserID                string                       // ID of the losing player
+	EndReason              string                       // Why the game ended, e.g. "King Tower destroyed"
+	Seed                   int64                        // Seed of the game's random source, for reproducing a match
+	rng                    *rand.Rand                   // Game-owned random source (troop choices, CRIT rolls)
+	deploys                int                          // Troops deployed so far, numbers each new troop
 }
 
 // PlayerInGame represents a player within the context of a game
@@ -81,9 +87,12 @@ type ActiveTroop struct {
 	MaxHP         int
 	ATK           int
 	DEF           int
+	CritChance    float64
 	OwnerPlayerID string
 	TargetID      string // ID of the tower this troop is targeting
 	DeployedTime  time.Time
+	DeployedTurn  int // Game.TurnNumber at deployment (Simple mode)
+	DeploySeq     int // Order of deployment within the game
 }
 
 // BoardState represents the current state of the game board
@@ -92,8 +101,9 @@ type BoardState struct {
 	ActiveTroops map[string]*ActiveTroop
 }
 
-// NewGame creates a new game between two players
-func NewGame(id string, player1 *models.Player, player2 *models.Player, mode GameMode, towerSpecs map[string]*models.TowerSpec, troopSpecs map[string]*models.TroopSpec) *Game { // Added specs to constructor
+// NewGame creates a new game between two players. All randomness in the game is
+// drawn from a source seeded with seed, so the same seed and inputs replay the same match.
+func NewGame(id string, player1 *models.Player, player2 *models.Player, mode GameMode, towerSpecs map[string]*models.TowerSpec, troopSpecs map[string]*models.TroopSpec, seed int64) *Game { // Added specs to constructor
 	game := &Game{
 		ID:        id,
 		GameState: GameStateWaiting,
@@ -107,6 +117,8 @@ func NewGame(id string, player1 *models.Player, player2 *models.Player, mode Gam
 		CurrentTurnPlayerIndex: 0,          // Player 1 starts in Simple mode
 		TowerSpecs:             towerSpecs, // Store specs
 		TroopSpecs:             troopSpecs, // Store specs
+		Seed:                   seed,
+		rng:                    rand.New(rand.NewSource(seed)),
 	}
 
 	// Initialize players in game
@@ -134,3 +146,31 @@ func NewGame(id string, player1 *models.Player, player2 *models.Player, mode Gam
 
 	return game
 }
+
+// Forfeit ends the game with the player at loserIndex losing, e.g. after repeated turn timeouts.
+// It does nothing if the game has already finished.
+func (g *Game) Forfeit(loserIndex int, reason string) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	if g.GameState == GameStateFinished {
+		return
+	}
+	g.GameState = GameStateFinished
+	g.EndTime = time.Now()
+	g.WinnerID = g.Players[(loserIndex+1)%2].ID
+	g.LoserID = g.Players[loserIndex].ID
+	g.EndReason = reason
+}
+
+// Rand returns the game's seeded random source. Like the rest of the game state,
+// it is not safe for concurrent use without holding Mutex.
+func (g *Game) Rand() *rand.Rand {
+	return g.rng
+}
+
+// nextDeploySeq numbers a newly deployed troop. The caller holds Mutex.
+func (g *Game) nextDeploySeq() int {
+	g.deploys++
+	return g.deploys
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+)
+
+// playSeededMatch plays up to turns Simple mode turns, each deploying the first troop
+// offered, and returns the offered troops and the resulting events of every turn
+func playSeededMatch(t *testing.T, seed int64, turns int) []string {
+	t.Helper()
+	towerSpecs, troopSpecs := testSpecs()
+	for _, spec := range troopSpecs {
+		spec.CritChance = 50 // Make critical hits common enough to compare
+	}
+	alice := &models.Player{ID: "alice", Username: "alice", Level: 1}
+	bob := &models.Player{ID: "bob", Username: "bob", Level: 1}
+	g := NewGame("test-game", alice, bob, GameModeSimple, towerSpecs, troopSpecs, seed)
+	h := NewSimpleModeHandler(g)
+	if err := h.StartGame(alice, bob, towerSpecs, troopSpecs); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+
+	var log []string
+	for turn := 0; turn < turns && g.GameState != GameStateFinished; turn++ {
+		playerIndex := g.CurrentTurnPlayerIndex
+		choices, err := h.GenerateAndStoreTroopChoices(g.Players[playerIndex])
+		if err != nil {
+			t.Fatalf("GenerateAndStoreTroopChoices: %v", err)
+		}
+		offered := ""
+		for _, choice := range choices.Choices {
+			offered += choice.ID + " "
+		}
+		log = append(log, fmt.Sprintf("turn %d offered %s", turn, offered))
+
+		events, err := h.ProcessTurn(playerIndex, "deploy_troop", map[string]interface{}{"troop_id": choices.Choices[0].ID})
+		if err != nil {
+			t.Fatalf("ProcessTurn: %v", err)
+		}
+		for _, event := range events {
+			log = append(log, fmt.Sprintf("%s (critical: %v)", event.Message, event.Critical))
+		}
+	}
+	return log
+}
+
+func TestSameSeedPlaysSameMatch(t *testing.T) {
+	first := playSeededMatch(t, 42, 20)
+	second := playSeededMatch(t, 42, 20)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed played different matches:\n%v\n%v", first, second)
+	}
+
+	crits := 0
+	for _, line := range first {
+		if strings.HasSuffix(line, "(critical: true)") {
+			crits++
+		}
+	}
+	if crits == 0 {
+		t.Error("the match had no critical hits to compare")
+	}
+
+	if reflect.DeepEqual(first, playSeededMatch(t, 43, 20)) {
+		t.Error("a different seed played the same match")
+	}
+}
@@ -0,0 +1,124 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// ReplayStep is one step of a re-simulated match: the initial setup (Command is nil)
+// or the outcome of one recorded command
+type ReplayStep struct {
+	Command     *models.ReplayCommand        // Command that was replayed, nil for the initial state
+	Events      []network.GameEventPayload   // Events produced by the command
+	Err         error                        // Error the player received, if the command was rejected
+	ChoicesFor  int                          // Index of the player the troop choices were offered to
+	Choices     *network.TroopChoicesPayload // Troop choices offered after this step, nil if none
+	State       *network.GameStatePayload    // Board state after this step
+	GameOver    bool                         // True once the replayed game has finished
+	WinnerIndex int                          // Index of the winner when GameOver, -1 for a draw
+}
+
+// SpecPointers converts a loaded GameConfig into the maps of spec pointers used by Game
+func SpecPointers(config *models.GameConfig) (map[string]*models.TowerSpec, map[string]*models.TroopSpec) {
+	towerSpecs := make(map[string]*models.TowerSpec)
+	for id, spec := range config.Towers {
+		specCopy := spec // Create a copy to avoid taking address of loop variable
+		towerSpecs[id] = &specCopy
+	}
+	troopSpecs := make(map[string]*models.TroopSpec)
+	for id, spec := range config.Troops {
+		specCopy := spec // Create a copy
+		troopSpecs[id] = &specCopy
+	}
+	return towerSpecs, troopSpecs
+}
+
+// ReplaySimple re-simulates a recorded Simple mode match through SimpleModeHandler,
+// calling emit for the initial state and after every recorded command.
+// Because the game is rebuilt from the same seed, config and players, it produces the
+// same troop choices, events and states the players saw.
+func ReplaySimple(replay *models.Replay, emit func(step ReplayStep)) (*Game, error) {
+	if replay.GameMode != string(GameModeSimple) {
+		return nil, fmt.Errorf("cannot re-simulate %s mode replay, only %s is supported", replay.GameMode, GameModeSimple)
+	}
+
+	var players [2]*models.Player
+	for i, p := range replay.Players {
+		players[i] = &models.Player{
+			ID:       p.Username, // Same as AuthManager.GetPlayerData: username is the ID
+			Username: p.Username,
+			Level:    p.Level,
+		}
+	}
+
+	towerSpecs, troopSpecs := SpecPointers(&replay.Config)
+	g := NewGame(replay.GameID, players[0], players[1], GameModeSimple, towerSpecs, troopSpecs, replay.Seed)
+	h := NewSimpleModeHandler(g)
+	if err := h.StartGame(players[0], players[1], towerSpecs, troopSpecs); err != nil {
+		return nil, fmt.Errorf("failed to start replayed game: %w", err)
+	}
+
+	step := ReplayStep{}
+	h.offerChoices(&step)
+	emit(step)
+
+	for i := range replay.Commands {
+		if g.GameState == GameStateFinished {
+			break
+		}
+
+		cmd := &replay.Commands[i]
+		step := ReplayStep{Command: cmd}
+
+		switch cmd.Type {
+		case string(network.MessageTypeDeployTroop):
+			step.Events, step.Err = h.ProcessTurn(cmd.PlayerIndex, "deploy_troop", map[string]interface{}{"troop_id": cmd.TroopID})
+		case models.ReplayCommandAutoDeploy:
+			var troopID string
+			troopID, step.Events, step.Err = h.AutoDeploy(cmd.PlayerIndex)
+			if troopID != cmd.TroopID {
+				step.Err = fmt.Errorf("replay diverged: auto-deployed %s, the match deployed %s", troopID, cmd.TroopID)
+			}
+		case models.ReplayCommandSkipTurn:
+			step.Events, step.Err = h.SkipTurn(cmd.PlayerIndex)
+		case models.ReplayCommandForfeit:
+			g.Forfeit(cmd.PlayerIndex, cmd.Reason)
+			step.Events = []network.GameEventPayload{{Message: fmt.Sprintf("Game Over: %s", cmd.Reason)}}
+		default:
+			step.Err = fmt.Errorf("unknown replay command type: %s", cmd.Type)
+		}
+
+		// The session offers new choices only after a turn was actually played
+		if step.Err == nil {
+			h.offerChoices(&step)
+		} else {
+			step.State = h.GetGameState(0)
+		}
+		emit(step)
+	}
+
+	return g, nil
+}
+
+// offerChoices fills in the state and game over details of a replay step, and generates
+// the next troop choices exactly as the session does when a turn starts
+func (h *SimpleModeHandler) offerChoices(step *ReplayStep) {
+	if h.game.GameState == GameStateFinished {
+		step.GameOver = true
+		step.WinnerIndex = -1
+		for i, player := range h.game.Players {
+			if player.ID == h.game.WinnerID {
+				step.WinnerIndex = i
+			}
+		}
+	} else {
+		step.ChoicesFor = h.game.CurrentTurnPlayerIndex
+		choices, err := h.GenerateAndStoreTroopChoices(h.game.Players[step.ChoicesFor])
+		if err == nil {
+			step.Choices = choices
+		}
+	}
+	step.State = h.GetGameState(0)
+}
@@ -0,0 +1,124 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// choiceIDs returns the IDs of the offered troops
+func choiceIDs(choices *network.TroopChoicesPayload) []string {
+	var ids []string
+	for _, choice := range choices.Choices {
+		ids = append(ids, choice.ID)
+	}
+	return ids
+}
+
+// eventMessages returns the messages of the events
+func eventMessages(events []network.GameEventPayload) []string {
+	var messages []string
+	for _, event := range events {
+		messages = append(messages, event.Message)
+	}
+	return messages
+}
+
+func TestReplaySimpleReproducesMatch(t *testing.T) {
+	towerSpecs, troopSpecs := testSpecs()
+	replay := &models.Replay{
+		GameID:   "test-game",
+		GameMode: string(GameModeSimple),
+		Seed:     7,
+		Players:  [2]models.ReplayPlayer{{Username: "alice", Level: 2}, {Username: "bob", Level: 1}},
+		Config:   models.GameConfig{Towers: map[string]models.TowerSpec{}, Troops: map[string]models.TroopSpec{}},
+	}
+	for id, spec := range towerSpecs {
+		replay.Config.Towers[id] = *spec
+	}
+	for id, spec := range troopSpecs {
+		replay.Config.Troops[id] = *spec
+	}
+
+	// Play the match live as the session does, recording each accepted command
+	alice := &models.Player{ID: "alice", Username: "alice", Level: 2}
+	bob := &models.Player{ID: "bob", Username: "bob", Level: 1}
+	g := NewGame(replay.GameID, alice, bob, GameModeSimple, towerSpecs, troopSpecs, replay.Seed)
+	h := NewSimpleModeHandler(g)
+	if err := h.StartGame(alice, bob, towerSpecs, troopSpecs); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+
+	var liveChoices [][]string
+	var liveEvents [][]string
+	for turn := 0; turn < 12; turn++ {
+		playerIndex := g.CurrentTurnPlayerIndex
+		choices, err := h.GenerateAndStoreTroopChoices(g.Players[playerIndex])
+		if err != nil {
+			t.Fatalf("GenerateAndStoreTroopChoices: %v", err)
+		}
+		liveChoices = append(liveChoices, choiceIDs(choices))
+
+		cmd := models.ReplayCommand{PlayerIndex: playerIndex}
+		var events []network.GameEventPayload
+		switch turn % 3 {
+		case 0:
+			cmd.Type = string(network.MessageTypeDeployTroop)
+			cmd.TroopID = choices.Choices[len(choices.Choices)-1].ID
+			events, err = h.ProcessTurn(playerIndex, "deploy_troop", map[string]interface{}{"troop_id": cmd.TroopID})
+		case 1:
+			cmd.Type = models.ReplayCommandAutoDeploy
+			cmd.TroopID, events, err = h.AutoDeploy(playerIndex)
+		case 2:
+			cmd.Type = models.ReplayCommandSkipTurn
+			events, err = h.SkipTurn(playerIndex)
+		}
+		if err != nil {
+			t.Fatalf("turn %d (%s): %v", turn, cmd.Type, err)
+		}
+		replay.Commands = append(replay.Commands, cmd)
+		liveEvents = append(liveEvents, eventMessages(events))
+		if g.GameState == GameStateFinished {
+			t.Fatalf("game finished early on turn %d", turn)
+		}
+	}
+	replay.Commands = append(replay.Commands, models.ReplayCommand{PlayerIndex: 1, Type: models.ReplayCommandForfeit, Reason: "bob forfeited"})
+	g.Forfeit(1, "bob forfeited")
+
+	// Re-simulate it and compare every step
+	var steps []ReplayStep
+	replayed, err := ReplaySimple(replay, func(step ReplayStep) {
+		steps = append(steps, step)
+	})
+	if err != nil {
+		t.Fatalf("ReplaySimple: %v", err)
+	}
+	if len(steps) != len(replay.Commands)+1 {
+		t.Fatalf("got %d steps, want %d", len(steps), len(replay.Commands)+1)
+	}
+
+	for i, step := range steps[:len(liveChoices)] {
+		if step.Err != nil {
+			t.Fatalf("step %d: %v", i, step.Err)
+		}
+		if step.Choices == nil || !reflect.DeepEqual(choiceIDs(step.Choices), liveChoices[i]) {
+			t.Errorf("step %d offered %v, the match offered %v", i, step.Choices, liveChoices[i])
+		}
+		if i > 0 && !reflect.DeepEqual(eventMessages(step.Events), liveEvents[i-1]) {
+			t.Errorf("step %d events:\n%v\nthe match had:\n%v", i, eventMessages(step.Events), liveEvents[i-1])
+		}
+	}
+
+	last := steps[len(steps)-1]
+	if !last.GameOver || last.WinnerIndex != 0 {
+		t.Errorf("last step: game over = %v, winner = %d, want alice (0) to win", last.GameOver, last.WinnerIndex)
+	}
+	if !reflect.DeepEqual(replayed.BoardState.Towers, g.BoardState.Towers) {
+		t.Error("replayed towers differ from the match")
+	}
+	if replayed.WinnerID != g.WinnerID || replayed.EndReason != g.EndReason {
+		t.Errorf("replay ended with winner %q (%s), the match with %q (%s)", replayed.WinnerID, replayed.EndReason, g.WinnerID, g.EndReason)
+	}
+}
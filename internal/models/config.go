@@ -19,13 +19,14 @@ type TowerSpec struct {
 
 // TroopSpec defines the base specifications for a troop type
 type TroopSpec struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	BaseHP     int    `json:"baseHP"`
-	BaseATK    int    `json:"baseATK"`
-	BaseDEF    int    `json:"baseDEF"`
-	ManaCost   int    `json:"manaCost"`
-	ExpYield   int    `json:"expYield"` // EXP gained when this troop is deployed/destroyed
-	Special    string `json:"special"`  // Description of any special ability
-	HasSpecial bool   `json:"hasSpecial"`
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	BaseHP     int     `json:"baseHP"`
+	BaseATK    int     `json:"baseATK"`
+	BaseDEF    int     `json:"baseDEF"`
+	ManaCost   int     `json:"manaCost"`
+	CritChance float64 `json:"critChance"` // Percentage (e.g., 5.0 for 5%), 0 if omitted
+	ExpYield   int     `json:"expYield"`   // EXP gained when this troop is deployed/destroyed
+	Special    string  `json:"special"`    // Description of any special ability
+	HasSpecial bool    `json:"hasSpecial"`
 }
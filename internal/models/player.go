@@ -11,10 +11,20 @@ type Player struct {
 
 // PlayerData represents the data structure for JSON persistence
 type PlayerData struct {
-	Username       string `json:"username"`
-	HashedPassword string `json:"hashedPassword"`
-	EXP            int    `json:"exp"`
-	Level          int    `json:"level"`
+	Username       string      `json:"username"`
+	HashedPassword string      `json:"hashedPassword"`
+	EXP            int         `json:"exp"`
+	Level          int         `json:"level"`
+	Rating         int         `json:"rating,omitempty"` // Elo skill rating, see CurrentRating
+	Stats          PlayerStats `json:"stats"`
+	Version        int64       `json:"version"` // Bumped on every save to detect lost updates
+}
+
+// Clone returns a copy of the player data that shares no maps or slices with it
+func (p *PlayerData) Clone() *PlayerData {
+	clone := *p
+	clone.Stats = p.Stats.Clone()
+	return &clone
 }
 
 // CalculateRequiredExp calculates the EXP required to reach the next level
@@ -0,0 +1,49 @@
+package models
+
+import "math"
+
+// Elo rating settings. New players start at DefaultRating; their rating moves faster
+// (ProvisionalKFactor) until they have played ProvisionalGames matches.
+const (
+	DefaultRating      = 1200
+	ProvisionalGames   = 10
+	ProvisionalKFactor = 40
+	EstablishedKFactor = 20
+)
+
+// CurrentRating returns the player's rating, DefaultRating for players saved before
+// ratings existed
+func (p *PlayerData) CurrentRating() int {
+	if p.Rating == 0 {
+		return DefaultRating
+	}
+	return p.Rating
+}
+
+// ExpectedScore returns the chance of a player rated rating beating one rated opponentRating,
+// counting a draw as half a win
+func ExpectedScore(rating, opponentRating int) float64 {
+	return 1 / (1 + math.Pow(10, float64(opponentRating-rating)/400))
+}
+
+// RatingChange returns how much a player's rating moves after a match against opponentRating.
+// score is 1 for a win, 0.5 for a draw and 0 for a loss; gamesPlayed counts earlier matches.
+func RatingChange(rating, opponentRating int, score float64, gamesPlayed int) int {
+	kFactor := float64(EstablishedKFactor)
+	if gamesPlayed < ProvisionalGames {
+		kFactor = ProvisionalKFactor
+	}
+	return int(math.Round(kFactor * (score - ExpectedScore(rating, opponentRating))))
+}
+
+// MatchScore converts a MatchResult constant to the score used by RatingChange
+func MatchScore(result string) float64 {
+	switch result {
+	case MatchResultWin:
+		return 1
+	case MatchResultLoss:
+		return 0
+	default:
+		return 0.5
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "testing"
+
+func TestRatingChange(t *testing.T) {
+	tests := []struct {
+		name           string
+		rating         int
+		opponentRating int
+		score          float64
+		gamesPlayed    int
+		want           int
+	}{
+		{name: "provisional win against an equal", rating: 1200, opponentRating: 1200, score: 1, gamesPlayed: 0, want: 20},
+		{name: "provisional loss against an equal", rating: 1200, opponentRating: 1200, score: 0, gamesPlayed: ProvisionalGames - 1, want: -20},
+		{name: "established win against an equal", rating: 1200, opponentRating: 1200, score: 1, gamesPlayed: ProvisionalGames, want: 10},
+		{name: "draw against an equal", rating: 1200, opponentRating: 1200, score: 0.5, gamesPlayed: 0, want: 0},
+		{name: "underdog win", rating: 1200, opponentRating: 1600, score: 1, gamesPlayed: 0, want: 36},
+		{name: "favourite win", rating: 1600, opponentRating: 1200, score: 1, gamesPlayed: 0, want: 4},
+		{name: "favourite draw", rating: 1600, opponentRating: 1200, score: 0.5, gamesPlayed: ProvisionalGames, want: -8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RatingChange(tt.rating, tt.opponentRating, tt.score, tt.gamesPlayed); got != tt.want {
+				t.Errorf("RatingChange(%d, %d, %v, %d) = %d, want %d", tt.rating, tt.opponentRating, tt.score, tt.gamesPlayed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatingChangeIsZeroSum(t *testing.T) {
+	for _, gap := range []int{0, 50, 200, 400} {
+		winner := RatingChange(1200+gap, 1200, MatchScore(MatchResultWin), ProvisionalGames)
+		loser := RatingChange(1200, 1200+gap, MatchScore(MatchResultLoss), ProvisionalGames)
+		if winner+loser != 0 {
+			t.Errorf("gap %d: winner gains %d, loser loses %d", gap, winner, -loser)
+		}
+	}
+}
+
+func TestCurrentRating(t *testing.T) {
+	if got := (&PlayerData{}).CurrentRating(); got != DefaultRating {
+		t.Errorf("rating of a player saved before ratings = %d, want %d", got, DefaultRating)
+	}
+	if got := (&PlayerData{Rating: 1350}).CurrentRating(); got != 1350 {
+		t.Errorf("CurrentRating = %d, want 1350", got)
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Replay is the recorded history of a finished match, persisted to data/replays/<gameID>.json.
+// Together with the seed and config snapshot, the command log is enough to re-simulate the game.
+type Replay struct {
+	GameID    string          `json:"gameId"`
+	GameMode  string          `json:"gameMode"` // "SIMPLE" or "ENHANCED"
+	Seed      int64           `json:"seed"`
+	Players   [2]ReplayPlayer `json:"players"`
+	Config    GameConfig      `json:"config"` // Tower and troop specs in effect for the match
+	Commands  []ReplayCommand `json:"commands"`
+	StartTime time.Time       `json:"startTime"`
+	EndTime   time.Time       `json:"endTime"`
+	Winner    string          `json:"winner,omitempty"` // Username of winner, empty if draw or unfinished
+	EndReason string          `json:"endReason,omitempty"`
+}
+
+// ReplayPlayer holds the player details that affect the simulation
+type ReplayPlayer struct {
+	Username string `json:"username"`
+	Level    int    `json:"level"`
+}
+
+// ReplayCommandForfeit is the command type recorded when a player forfeits the match
+// (deploys are recorded with their network message type, "deploy_troop")
+const ReplayCommandForfeit = "forfeit"
+
+// ReplayCommandAutoDeploy is the command type recorded when a timed out turn was played by
+// deploying a troop picked with the game's seeded source. TroopID is the troop picked; it is
+// recorded even if deploying it failed, so a replay draws from the source the same way.
+const ReplayCommandAutoDeploy = "auto_deploy"
+
+// ReplayCommandSkipTurn is the command type recorded when a timed out turn could not be
+// played automatically and was passed to the opponent
+const ReplayCommandSkipTurn = "skip_turn"
+
+// ReplayCommand is a single game command the server accepted, in the order it was played
+type ReplayCommand struct {
+	Time        time.Time `json:"time"`
+	PlayerIndex int       `json:"playerIndex"` // 0 or 1
+	Type        string    `json:"type"`        // e.g. "deploy_troop"
+	TroopID     string    `json:"troopId,omitempty"`
+	Reason      string    `json:"reason,omitempty"` // For "forfeit": why the player forfeited
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// MaxRecentMatches is how many matches a player's history keeps, older ones are dropped
+const MaxRecentMatches = 10
+
+// Results of a match from one player's point of view
+const (
+	MatchResultWin  = "win"
+	MatchResultLoss = "loss"
+	MatchResultDraw = "draw"
+)
+
+// PlayerStats are a player's lifetime match statistics
+type PlayerStats struct {
+	Wins            int            `json:"wins"`
+	Losses          int            `json:"losses"`
+	Draws           int            `json:"draws"`
+	TowersDestroyed int            `json:"towersDestroyed"`
+	TroopsDeployed  map[string]int `json:"troopsDeployed,omitempty"` // By troop spec ID
+	RecentMatches   []MatchRecord  `json:"recentMatches,omitempty"`  // Newest first
+}
+
+// MatchRecord summarizes one finished match for a player's history
+type MatchRecord struct {
+	GameID    string    `json:"gameId"`
+	GameMode  string    `json:"gameMode"`
+	Opponent  string    `json:"opponent"`
+	Result    string    `json:"result"` // One of the MatchResult constants
+	ExpEarned int       `json:"expEarned"`
+	EndedAt   time.Time `json:"endedAt"`
+}
+
+// GamesPlayed returns the number of finished matches
+func (s *PlayerStats) GamesPlayed() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+// Clone returns a copy of the stats that shares no maps or slices with them
+func (s PlayerStats) Clone() PlayerStats {
+	clone := s
+	if s.TroopsDeployed != nil {
+		clone.TroopsDeployed = make(map[string]int, len(s.TroopsDeployed))
+		for troopID, count := range s.TroopsDeployed {
+			clone.TroopsDeployed[troopID] = count
+		}
+	}
+	if s.RecentMatches != nil {
+		clone.RecentMatches = append([]MatchRecord(nil), s.RecentMatches...)
+	}
+	return clone
+}
+
+// RecordMatch adds a finished match: its result, the opponent towers the player destroyed
+// and the troops they deployed
+func (s *PlayerStats) RecordMatch(record MatchRecord, towersDestroyed int, troopsDeployed map[string]int) {
+	switch record.Result {
+	case MatchResultWin:
+		s.Wins++
+	case MatchResultLoss:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+	s.TowersDestroyed += towersDestroyed
+
+	if len(troopsDeployed) > 0 && s.TroopsDeployed == nil {
+		s.TroopsDeployed = make(map[string]int)
+	}
+	for troopID, count := range troopsDeployed {
+		s.TroopsDeployed[troopID] += count
+	}
+
+	s.RecentMatches = append([]MatchRecord{record}, s.RecentMatches...)
+	if len(s.RecentMatches) > MaxRecentMatches {
+		s.RecentMatches = s.RecentMatches[:MaxRecentMatches]
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Username policy. Usernames name the player's data file, so the policy also keeps them
+// safe to use in paths: no separators, dots or other special characters.
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 20
+)
+
+// ErrInvalidUsername is wrapped by all username policy violations
+var ErrInvalidUsername = errors.New("invalid username")
+
+// CanonicalUsername returns the canonical form of a username, case-folded to lower case, or
+// an error if it breaks the policy: 3-20 ASCII letters, digits, '_' or '-', starting with a letter
+func CanonicalUsername(username string) (string, error) {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return "", fmt.Errorf("%w: must be %d to %d characters long", ErrInvalidUsername, MinUsernameLength, MaxUsernameLength)
+	}
+
+	canonical := strings.ToLower(username)
+	for i, r := range canonical {
+		isLetter := r >= 'a' && r <= 'z'
+		if i == 0 && !isLetter {
+			return "", fmt.Errorf("%w: must start with a letter", ErrInvalidUsername)
+		}
+		if !isLetter && !(r >= '0' && r <= '9') && r != '_' && r != '-' {
+			return "", fmt.Errorf("%w: may only contain letters, digits, '_' and '-'", ErrInvalidUsername)
+		}
+	}
+	return canonical, nil
+}
+
+// IsCanonicalUsername reports whether a username follows the policy and is already case-folded
+func IsCanonicalUsername(username string) bool {
+	canonical, err := CanonicalUsername(username)
+	return err == nil && canonical == username
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     string
+		wantErr  bool
+	}{
+		{username: "alice", want: "alice"},
+		{username: "Alice", want: "alice"},
+		{username: "BOB_the-2nd", want: "bob_the-2nd"},
+		{username: "abc", want: "abc"},
+		{username: "a23456789012345678901", wantErr: true},
+		{username: "ab", wantErr: true},
+		{username: "", wantErr: true},
+		{username: "2fast", wantErr: true},
+		{username: "_alice", wantErr: true},
+		{username: "al ice", wantErr: true},
+		{username: "../alice", wantErr: true},
+		{username: "alice.json", wantErr: true},
+		{username: "álice", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			got, err := CanonicalUsername(tt.username)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidUsername) {
+					t.Fatalf("CanonicalUsername(%q) = %q, %v; want an invalid username error", tt.username, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("CanonicalUsername(%q) = %q, %v; want %q", tt.username, got, err, tt.want)
+			}
+			if !IsCanonicalUsername(got) {
+				t.Errorf("IsCanonicalUsername(%q) = false", got)
+			}
+		})
+	}
+
+	if IsCanonicalUsername("Alice") {
+		t.Error(`IsCanonicalUsername("Alice") = true for a name that is not case-folded`)
+	}
+}
@@ -2,34 +2,135 @@ package network
 
 import (
 	"bufio"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"sync"
 
 	"github.com/NP-Dat/net-centric-project/pkg/logger"
 )
 
+// Framing versions a connection can use. Every connection starts with JSON lines; peers
+// may switch to length-prefixed frames with a framing handshake (see MessageTypeFraming).
+const (
+	FramingJSONLine       = 1 // One JSON message per line, terminated by '\n'
+	FramingLengthPrefixed = 2 // 4-byte big-endian length followed by the JSON message
+)
+
+// SupportedFramings lists the framing versions this codec understands, most preferred first
+var SupportedFramings = []int{FramingLengthPrefixed, FramingJSONLine}
+
+// DefaultMaxFrameSize is the largest message a codec accepts unless configured otherwise
+const DefaultMaxFrameSize = 1 << 20 // 1 MiB
+
+// ErrFrameTooLarge is returned when a message exceeds the codec's maximum frame size
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
 // Codec provides functions for encoding and decoding messages over TCP
 type Codec struct {
 	conn   net.Conn
 	reader *bufio.Reader
+
+	mutex        sync.RWMutex // Guards framing, maxFrameSize and peerVersion, which may change mid-connection
+	framing      int
+	maxFrameSize int
+	peerVersion  int // Protocol version the peer speaks, 0 if it never said
 }
 
 // NewCodec creates a new codec for the given connection
 func NewCodec(conn net.Conn) *Codec {
 	logger.Network.Debug("Creating new codec for connection from %s", conn.RemoteAddr())
 	return &Codec{
-		conn:   conn,
-		reader: bufio.NewReader(conn),
+		conn:         conn,
+		reader:       bufio.NewReader(conn),
+		framing:      FramingJSONLine,
+		maxFrameSize: DefaultMaxFrameSize,
 	}
 }
 
+// SetFraming switches the framing used for all following messages in both directions
+func (c *Codec) SetFraming(version int) error {
+	if !IsSupportedFraming(version) {
+		return fmt.Errorf("unsupported framing version %d", version)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.framing = version
+	logger.Network.Debug("Switched connection %s to framing version %d", c.conn.RemoteAddr(), version)
+	return nil
+}
+
+// Framing returns the framing version currently in use
+func (c *Codec) Framing() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.framing
+}
+
+// SetMaxFrameSize sets the largest message, in bytes, the codec sends or accepts
+func (c *Codec) SetMaxFrameSize(size int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.maxFrameSize = size
+}
+
+// MaxFrameSize returns the largest message, in bytes, the codec sends or accepts
+func (c *Codec) MaxFrameSize() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.maxFrameSize
+}
+
+// SetPeerVersion sets the protocol version the peer speaks. Payload fields added in later
+// versions are left out of the messages sent to it.
+func (c *Codec) SetPeerVersion(version int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.peerVersion = version
+}
+
+// IsSupportedFraming reports whether the codec can use the given framing version
+func IsSupportedFraming(version int) bool {
+	for _, v := range SupportedFramings {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ChooseFraming picks the first of the peer's offered framing versions this codec supports,
+// falling back to JSON lines, which every peer understands
+func ChooseFraming(offered []int) int {
+	for _, v := range offered {
+		if IsSupportedFraming(v) {
+			return v
+		}
+	}
+	return FramingJSONLine
+}
+
 // Send encodes a Message and sends it over the connection
 func (c *Codec) Send(msgType MessageType, payload interface{}) error {
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		logger.Network.Error("Failed to marshal payload of type %s: %v", msgType, err)
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	c.mutex.RLock()
+	peerVersion := c.peerVersion
+	c.mutex.RUnlock()
+	if payloadData, err = downgradePayload(msgType, payloadData, peerVersion); err != nil {
+		logger.Network.Error("Failed to downgrade payload of type %s to protocol version %d: %v", msgType, peerVersion, err)
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
 	msg := Message{
 		Type:    msgType,
-		Payload: payload,
+		Payload: payloadData,
 	}
 
 	data, err := json.Marshal(msg)
@@ -38,8 +139,21 @@ func (c *Codec) Send(msgType MessageType, payload interface{}) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	// Add newline as message delimiter
-	data = append(data, '\n')
+	framing, maxFrameSize := c.Framing(), c.MaxFrameSize()
+	if len(data) > maxFrameSize {
+		logger.Network.Error("Message of type %s is %d bytes, over the %d byte frame limit", msgType, len(data), maxFrameSize)
+		return fmt.Errorf("failed to send message: %w", ErrFrameTooLarge)
+	}
+
+	// Frame the message; it goes out in a single write so concurrent senders cannot interleave
+	if framing == FramingLengthPrefixed {
+		frame := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(frame, uint32(len(data)))
+		data = append(frame, data...)
+	} else {
+		// Add newline as message delimiter
+		data = append(data, '\n')
+	}
 
 	logger.Network.Debug("Sending message of type %s to %s (data size: %d bytes)",
 		msgType, c.conn.RemoteAddr(), len(data))
@@ -53,10 +167,33 @@ func (c *Codec) Send(msgType MessageType, payload interface{}) error {
 	return nil
 }
 
+// downgradePayload removes the payload fields added after the peer's protocol version, which
+// a peer decoding strictly would reject. A peer that never announced a version gets them all.
+func downgradePayload(msgType MessageType, data []byte, peerVersion int) ([]byte, error) {
+	if peerVersion == 0 || peerVersion >= ProtocolVersion {
+		return data, nil
+	}
+
+	var fields map[string]json.RawMessage
+	for version := peerVersion + 1; version <= ProtocolVersion; version++ {
+		for _, field := range versionFields[version][msgType] {
+			if fields == nil {
+				if err := json.Unmarshal(data, &fields); err != nil {
+					return nil, err
+				}
+			}
+			delete(fields, field)
+		}
+	}
+	if fields == nil {
+		return data, nil
+	}
+	return json.Marshal(fields)
+}
+
 // Receive reads a Message from the connection and decodes it
 func (c *Codec) Receive() (*Message, error) {
-	// Read until newline
-	data, err := c.reader.ReadBytes('\n')
+	data, err := c.readFrame()
 	if err != nil {
 		if err == io.EOF {
 			logger.Network.Debug("Connection closed by peer %s", c.conn.RemoteAddr())
@@ -85,31 +222,60 @@ func (c *Codec) Receive() (*Message, error) {
 	return &msg, nil
 }
 
-// ParsePayload parses the raw payload into the specified type
+// readFrame reads the next message frame, enforcing the maximum frame size
+func (c *Codec) readFrame() ([]byte, error) {
+	framing, maxFrameSize := c.Framing(), c.MaxFrameSize()
+
+	if framing == FramingLengthPrefixed {
+		var header [4]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if uint64(size) > uint64(maxFrameSize) {
+			return nil, fmt.Errorf("%w: %d bytes announced, limit is %d", ErrFrameTooLarge, size, maxFrameSize)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	// Read until newline, without buffering more than one frame's worth of data
+	var data []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		if len(data)+len(chunk) > maxFrameSize+1 { // +1 for the newline
+			return nil, fmt.Errorf("%w: line longer than %d bytes", ErrFrameTooLarge, maxFrameSize)
+		}
+		data = append(data, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return data, err
+	}
+}
+
+// ParsePayload decodes the message's raw payload into target. Unknown fields are ignored, so
+// clients keep working when the server adds fields, and payloads implementing Validator are
+// validated. Errors about the payload itself are returned as *PayloadError. The server decodes
+// requests strictly with DecodeRequest instead.
 func ParsePayload(msg *Message, target interface{}) error {
 	if msg == nil {
 		logger.Network.Error("Cannot parse payload: message is nil")
 		return fmt.Errorf("cannot parse nil message")
 	}
 
-	// Re-encode the payload to JSON
-	data, err := json.Marshal(msg.Payload)
-	if err != nil {
-		logger.Network.Error("Failed to re-encode payload for message type %s: %v", msg.Type, err)
-		return fmt.Errorf("failed to re-encode payload: %w", err)
-	}
-
-	// Decode into the target type
-	err = json.Unmarshal(data, target)
-	if err != nil {
+	if err := decodePayload(msg.Type, msg.Payload, target, false); err != nil {
 		logger.Network.Error("Failed to decode payload for message type %s: %v", msg.Type, err)
-		// Log the payload that failed to unmarshal (limited to first 100 bytes for safety)
-		if len(data) > 100 {
-			logger.Network.Debug("Invalid payload data (first 100 bytes): %s", string(data[:100]))
+		// Log the payload that failed to decode (limited to first 100 bytes for safety)
+		if len(msg.Payload) > 100 {
+			logger.Network.Debug("Invalid payload data (first 100 bytes): %s", string(msg.Payload[:100]))
 		} else {
-			logger.Network.Debug("Invalid payload data: %s", string(data))
+			logger.Network.Debug("Invalid payload data: %s", string(msg.Payload))
 		}
-		return fmt.Errorf("failed to decode payload: %w", err)
+		return err
 	}
 
 	logger.Network.Debug("Successfully parsed payload for message type %s", msg.Type)
@@ -0,0 +1,64 @@
+package network
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSendLeavesOutFieldsNewerThanPeer(t *testing.T) {
+	payload := &AuthResultPayload{
+		Success:        true,
+		Message:        "Authentication successful",
+		Username:       "alice",
+		SessionToken:   "token",
+		TokenExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	tests := []struct {
+		name        string
+		peerVersion int
+		wantFields  []string
+		wantMissing []string
+	}{
+		{name: "version 1 peer", peerVersion: 1, wantFields: []string{"success", "message"}, wantMissing: []string{"username", "session_token", "token_expires_at"}},
+		{name: "current peer", peerVersion: ProtocolVersion, wantFields: []string{"success", "message", "username", "session_token", "token_expires_at"}},
+		{name: "peer without hello", peerVersion: 0, wantFields: []string{"success", "message", "username", "session_token", "token_expires_at"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			defer clientConn.Close()
+
+			codec := NewCodec(serverConn)
+			codec.SetPeerVersion(tt.peerVersion)
+			go codec.Send(MessageTypeAuthResult, payload)
+
+			line, err := bufio.NewReader(clientConn).ReadBytes('\n')
+			if err != nil {
+				t.Fatalf("reading message: %v", err)
+			}
+			var msg struct {
+				Payload map[string]json.RawMessage `json:"payload"`
+			}
+			if err := json.Unmarshal(line, &msg); err != nil {
+				t.Fatalf("decoding message: %v", err)
+			}
+
+			for _, field := range tt.wantFields {
+				if _, ok := msg.Payload[field]; !ok {
+					t.Errorf("field %q missing from %s", field, line)
+				}
+			}
+			for _, field := range tt.wantMissing {
+				if _, ok := msg.Payload[field]; ok {
+					t.Errorf("field %q sent to a version %d peer: %s", field, tt.peerVersion, line)
+				}
+			}
+		})
+	}
+}
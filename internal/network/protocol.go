@@ -1,6 +1,59 @@
 package network
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ProtocolVersion is the version of the protocol spoken by this build. Peers announce their
+// version in a hello message; the server accepts versions from MinProtocolVersion up and
+// leaves out the payload fields the peer's version does not know (see versionFields).
+//
+// Version history:
+//   - 1: hello handshake and version errors
+//   - 2: auth results with codes and session tokens, ratings in game over, spectating
+const (
+	ProtocolVersion    = 2
+	MinProtocolVersion = 1
+)
+
+// versionFields lists the payload fields each protocol version added to existing message types
+var versionFields = map[int]map[MessageType][]string{
+	2: {
+		MessageTypeAuthResult:  {"code", "username", "session_token", "token_expires_at"},
+		MessageTypeGameEvent:   {"critical"},
+		MessageTypeGameOver:    {"new_rating", "rating_change"},
+		MessageTypeStateUpdate: {"mana"},
+		MessageTypeTurnChange:  {"player"},
+	},
+}
+
+// Features a peer can advertise in its hello message. A side only uses a feature when the
+// other side advertised it too.
+const (
+	FeatureEnhancedMode  = "enhanced_mode"  // Real-time Enhanced mode games
+	FeatureLengthFraming = "length_framing" // Length-prefixed framing (see MessageTypeFraming)
+	FeatureSpectate      = "spectate"       // Watching other players' games
+	FeatureReadyCheck    = "ready_check"    // Accepting a found match (see MessageTypeMatchFound)
+	FeatureQueueStatus   = "queue_status"   // Queue position updates while waiting (see MessageTypeQueueStatus)
+	FeatureChallenge     = "challenge"      // Private challenges between players (see MessageTypeChallenge)
+)
+
+// Error codes with a meaning beyond the HTTP-like defaults (400, 401, 500)
+const (
+	ErrorCodeIncompatibleVersion = 426 // The peer's protocol version is not supported
+)
+
+// Codes in a failed AuthResultPayload, telling the client why login or registration failed
+const (
+	AuthCodeInvalidInput       = 400 // Malformed username, or missing username or password
+	AuthCodeInvalidCredentials = 401 // Wrong password
+	AuthCodeUnknownUser        = 404 // No account with that username
+	AuthCodeUsernameTaken      = 409 // Registration for an existing username
+	AuthCodeAlreadyLoggedIn    = 423 // The account is in use by another connection
+	AuthCodeWeakPassword       = 422 // Registration password fails the strength rules
+)
 
 // MessageType defines the types of messages that can be exchanged
 type MessageType string
@@ -9,25 +62,54 @@ type MessageType string
 const (
 	// Client to Server message types
 	MessageTypeLogin       MessageType = "login"
+	MessageTypeResume      MessageType = "resume" // Log in with a session token instead of a password
+	MessageTypeRegister    MessageType = "register"
 	MessageTypeDeployTroop MessageType = "deploy_troop"
 	MessageTypeQuit        MessageType = "quit"
-	MessageTypeJoinQueue   MessageType = "join_queue" // New message type for matchmaking
+	MessageTypeJoinQueue   MessageType = "join_queue"   // New message type for matchmaking
+	MessageTypeStats       MessageType = "stats"        // Ask for a player's account statistics
+	MessageTypeLeaderboard MessageType = "leaderboard"  // Ask for a page of the leaderboard
+	MessageTypeLeaveQueue  MessageType = "leave_queue"  // Leave the matchmaking queue
+	MessageTypeMatchAccept MessageType = "match_accept" // Answer to a ready check
+
+	// Private games between two online players
+	MessageTypeChallenge         MessageType = "challenge"          // Invite an online player to a private game
+	MessageTypeChallengeResponse MessageType = "challenge_response" // Answer to a received challenge
+
+	MessageTypePlayBot MessageType = "play_bot" // Start a game against a server-side bot
+
+	// Watching other players' games
+	MessageTypeListGames      MessageType = "list_games"      // Ask for the games in progress
+	MessageTypeSpectate       MessageType = "spectate"        // Start watching a game
+	MessageTypeStopSpectating MessageType = "stop_spectating" // Stop watching the game
+
+	// Sent in both directions
+	MessageTypeHello       MessageType = "hello"        // Version and feature exchange, must come before login
+	MessageTypeFraming     MessageType = "framing"      // Framing handshake, must come before login
+	MessageTypeQueueStatus MessageType = "queue_status" // Client: ask for it; server: answer and periodic updates
 
 	// Server to Client message types
-	MessageTypeAuthResult   MessageType = "auth_result"
-	MessageTypeGameStart    MessageType = "game_start"
-	MessageTypeStateUpdate  MessageType = "state_update"
-	MessageTypeGameEvent    MessageType = "game_event"
-	MessageTypeGameOver     MessageType = "game_over"
-	MessageTypeTurnChange   MessageType = "turn_change"
-	MessageTypeError        MessageType = "error"
-	MessageTypeTroopChoices MessageType = "troop_choices" // New message type for troop choices
+	MessageTypeAuthResult        MessageType = "auth_result"
+	MessageTypeGameStart         MessageType = "game_start"
+	MessageTypeStateUpdate       MessageType = "state_update"
+	MessageTypeGameEvent         MessageType = "game_event"
+	MessageTypeGameOver          MessageType = "game_over"
+	MessageTypeTurnChange        MessageType = "turn_change"
+	MessageTypeError             MessageType = "error"
+	MessageTypeTroopChoices      MessageType = "troop_choices"      // New message type for troop choices
+	MessageTypeStatsResult       MessageType = "stats_result"       // Answer to a stats request
+	MessageTypeLeaderboardResult MessageType = "leaderboard_result" // Answer to a leaderboard request
+	MessageTypeMatchFound        MessageType = "match_found"        // Ready check, answered with match_accept
+	MessageTypeChallengeReceived MessageType = "challenge_received" // Challenge from another player, answered with challenge_response
+	MessageTypeGameList          MessageType = "game_list"          // Answer to list_games
+	MessageTypeSpectateStart     MessageType = "spectate_start"     // Answer to spectate, followed by the game's updates
 )
 
-// Message is the base structure for all network messages
+// Message is the base structure for all network messages. The payload is kept as raw JSON
+// until it is decoded into its registered type (see DecodeRequest, Decode and ParsePayload).
 type Message struct {
-	Type    MessageType `json:"type"`
-	Payload interface{} `json:"payload"`
+	Type    MessageType     `json:"type"`
+	Payload json.RawMessage `json:"payload"`
 }
 
 // ----- Client to Server Message Payloads -----
@@ -38,33 +120,304 @@ type LoginPayload struct {
 	Password string `json:"password"`
 }
 
+// Validate checks that both credentials are present
+func (p *LoginPayload) Validate() error {
+	if p.Username == "" {
+		return requiredField("username")
+	}
+	if p.Password == "" {
+		return requiredField("password")
+	}
+	return nil
+}
+
+// RegisterPayload represents the payload for creating an account
+type RegisterPayload struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Validate checks that both credentials are present
+func (p *RegisterPayload) Validate() error {
+	if p.Username == "" {
+		return requiredField("username")
+	}
+	if p.Password == "" {
+		return requiredField("password")
+	}
+	return nil
+}
+
+// ResumePayload represents the payload for logging in with a session token
+type ResumePayload struct {
+	Token string `json:"token"`
+}
+
+// Validate checks that a token was given
+func (p *ResumePayload) Validate() error {
+	if p.Token == "" {
+		return requiredField("token")
+	}
+	return nil
+}
+
 // DeployTroopPayload represents the payload for deploying a troop
 type DeployTroopPayload struct {
 	TroopID       string `json:"troop_id"`
 	TargetTowerID string `json:"target_tower_id,omitempty"` // Optional, targeting may be implicit
 }
 
+// Validate checks that a troop was named
+func (p *DeployTroopPayload) Validate() error {
+	if p.TroopID == "" {
+		return requiredField("troop_id")
+	}
+	return nil
+}
+
+// Game modes as named on the wire
+const (
+	GameModeSimple   = "simple"   // Turn-based
+	GameModeEnhanced = "enhanced" // Real-time, needs FeatureEnhancedMode
+)
+
+// JoinQueuePayload represents the payload for joining the matchmaking queue of a game mode
+type JoinQueuePayload struct {
+	Mode        string            `json:"mode,omitempty"` // GameModeSimple if empty
+	Preferences *QueuePreferences `json:"preferences,omitempty"`
+}
+
+// QueuePreferences are optional limits on the opponents matchmaking may pick
+type QueuePreferences struct {
+	MaxRatingGap int `json:"max_rating_gap,omitempty"` // Never match further apart in rating, however long the wait
+}
+
+// Validate checks the mode and preferences
+func (p *JoinQueuePayload) Validate() error {
+	switch p.Mode {
+	case "", GameModeSimple, GameModeEnhanced:
+	default:
+		return invalidField("mode", "must be simple or enhanced")
+	}
+	if p.Preferences != nil && p.Preferences.MaxRatingGap < 0 {
+		return invalidField("preferences.max_rating_gap", "must not be negative")
+	}
+	return nil
+}
+
+// StatsRequestPayload asks for a player's statistics, the sender's own if Username is empty
+type StatsRequestPayload struct {
+	Username string `json:"username,omitempty"`
+}
+
+// Orders a leaderboard can be sorted in, best first
+const (
+	LeaderboardByRating = "rating"
+	LeaderboardByLevel  = "level" // Level, then EXP
+	LeaderboardByWins   = "wins"
+)
+
+// Leaderboard page sizes, when the request gives none and at most
+const (
+	DefaultLeaderboardPageSize = 10
+	MaxLeaderboardPageSize     = 50
+)
+
+// LeaderboardRequestPayload asks for one page of the leaderboard
+type LeaderboardRequestPayload struct {
+	SortBy   string `json:"sort_by,omitempty"`   // One of the LeaderboardBy constants, rating if empty
+	Page     int    `json:"page,omitempty"`      // 1-based, the first page if 0
+	PageSize int    `json:"page_size,omitempty"` // DefaultLeaderboardPageSize if 0
+}
+
+// Validate checks the sort order and paging
+func (p *LeaderboardRequestPayload) Validate() error {
+	switch p.SortBy {
+	case "", LeaderboardByRating, LeaderboardByLevel, LeaderboardByWins:
+	default:
+		return invalidField("sort_by", "must be rating, level or wins")
+	}
+	if p.Page < 0 {
+		return invalidField("page", "must not be negative")
+	}
+	if p.PageSize < 0 || p.PageSize > MaxLeaderboardPageSize {
+		return invalidField("page_size", fmt.Sprintf("must be between 1 and %d", MaxLeaderboardPageSize))
+	}
+	return nil
+}
+
+// Bot difficulties, from the weakest strategy to the strongest
+const (
+	BotDifficultyEasy   = "easy"
+	BotDifficultyMedium = "medium"
+	BotDifficultyHard   = "hard"
+)
+
+// PlayBotPayload starts a game against a bot right away, without matchmaking
+type PlayBotPayload struct {
+	Mode       string `json:"mode,omitempty"`       // One of the GameMode constants, simple if empty
+	Difficulty string `json:"difficulty,omitempty"` // One of the BotDifficulty constants, medium if empty
+}
+
+// Validate checks the mode and difficulty
+func (p *PlayBotPayload) Validate() error {
+	switch p.Mode {
+	case "", GameModeSimple, GameModeEnhanced:
+	default:
+		return invalidField("mode", "must be simple or enhanced")
+	}
+	switch p.Difficulty {
+	case "", BotDifficultyEasy, BotDifficultyMedium, BotDifficultyHard:
+	default:
+		return invalidField("difficulty", "must be easy, medium or hard")
+	}
+	return nil
+}
+
+// ChallengePayload invites an online player to a private game of the given mode
+type ChallengePayload struct {
+	Username string `json:"username"`
+	Mode     string `json:"mode,omitempty"` // One of the GameMode constants, simple if empty
+}
+
+// Validate checks that the challenged player and the mode are valid
+func (p *ChallengePayload) Validate() error {
+	if p.Username == "" {
+		return requiredField("username")
+	}
+	switch p.Mode {
+	case "", GameModeSimple, GameModeEnhanced:
+	default:
+		return invalidField("mode", "must be simple or enhanced")
+	}
+	return nil
+}
+
+// ChallengeResponsePayload answers a received challenge
+type ChallengeResponsePayload struct {
+	ChallengeID string `json:"challenge_id"`
+	Accept      bool   `json:"accept"`
+}
+
+// Validate checks that the challenge is named
+func (p *ChallengeResponsePayload) Validate() error {
+	if p.ChallengeID == "" {
+		return requiredField("challenge_id")
+	}
+	return nil
+}
+
+// LeaveQueuePayload represents the payload for leaving the matchmaking queue, which carries no data
+type LeaveQueuePayload struct{}
+
+// MatchAcceptPayload answers the ready check of a found match
+type MatchAcceptPayload struct {
+	MatchID string `json:"match_id"`
+	Accept  bool   `json:"accept"`
+}
+
+// Validate checks that the match is named
+func (p *MatchAcceptPayload) Validate() error {
+	if p.MatchID == "" {
+		return requiredField("match_id")
+	}
+	return nil
+}
+
+// ListGamesPayload asks for the games in progress, it carries no data
+type ListGamesPayload struct{}
+
+// SpectatePayload names the game to watch
+type SpectatePayload struct {
+	GameID string `json:"game_id"`
+}
+
+// Validate checks that the game is named
+func (p *SpectatePayload) Validate() error {
+	if p.GameID == "" {
+		return requiredField("game_id")
+	}
+	return nil
+}
+
+// StopSpectatingPayload represents the payload for no longer watching a game, which carries no data
+type StopSpectatingPayload struct{}
+
 // QuitPayload represents the payload for quitting a game
 type QuitPayload struct {
 	Reason string `json:"reason,omitempty"`
 }
 
+// HelloPayload announces a peer's protocol version and the features it supports. The client
+// sends it first; the server answers with its own or rejects an incompatible version.
+type HelloPayload struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	ClientName      string   `json:"client_name,omitempty"` // Client: name and version of the program
+	ServerName      string   `json:"server_name,omitempty"` // Server: name and version of the program
+	Features        []string `json:"features,omitempty"`
+}
+
+// Validate checks that a protocol version was announced
+func (p *HelloPayload) Validate() error {
+	if p.ProtocolVersion == 0 {
+		return requiredField("protocol_version")
+	}
+	return nil
+}
+
+// HasFeature reports whether feature is in the advertised list
+func HasFeature(features []string, feature string) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// FramingPayload negotiates the framing of a connection. The client offers the versions it
+// supports, the server answers with the one chosen; both switch once the answer is sent.
+type FramingPayload struct {
+	Versions     []int `json:"versions,omitempty"`       // Client: supported versions, most preferred first
+	Version      int   `json:"version,omitempty"`        // Server: the chosen version
+	MaxFrameSize int   `json:"max_frame_size,omitempty"` // Largest message in bytes the sender accepts
+}
+
+// QueueStatusPayload is a client's place in the matchmaking queue. Clients ask for it with an
+// empty payload; the server answers and also sends it periodically while the client waits.
+type QueueStatusPayload struct {
+	InQueue              bool   `json:"in_queue"`
+	Mode                 string `json:"mode,omitempty"`
+	Position             int    `json:"position,omitempty"`   // 1 for the longest waiting player
+	QueueSize            int    `json:"queue_size,omitempty"` // Players waiting in the same mode
+	WaitedSeconds        int    `json:"waited_seconds,omitempty"`
+	EstimatedWaitSeconds int    `json:"estimated_wait_seconds,omitempty"` // Remaining wait, -1 if there is no estimate yet
+}
+
 // ----- Server to Client Message Payloads -----
 
 // AuthResultPayload represents the payload for authentication result
 type AuthResultPayload struct {
 	Success  bool   `json:"success"`
+	Code     int    `json:"code,omitempty"` // Set on failure, one of the AuthCode constants
 	Message  string `json:"message,omitempty"`
 	PlayerID string `json:"player_id,omitempty"`
+
+	// Set on success: the logged in user and a token to log in again without the password
+	Username       string    `json:"username,omitempty"`
+	SessionToken   string    `json:"session_token,omitempty"`
+	TokenExpiresAt time.Time `json:"token_expires_at,omitempty"`
 }
 
 // GameStartPayload represents the payload when a game starts
 type GameStartPayload struct {
 	GameID           string            `json:"game_id"`
 	OpponentUsername string            `json:"opponent_username"`
-	GameMode         string            `json:"game_mode"` // "simple" or "enhanced"
+	GameMode         string            `json:"game_mode"` // GameModeSimple or GameModeEnhanced
 	YourTurn         bool              `json:"your_turn"` // Only for Simple mode
 	InitialState     *GameStatePayload `json:"initial_state"`
+	Seed             int64             `json:"seed"`                      // Random seed of the match, quote it with the game ID in bug reports
+	TurnTimeoutAt    time.Time         `json:"turn_timeout_at,omitempty"` // Deadline of the first turn (Simple mode)
 }
 
 // TowerInfo contains information about a tower for state updates
@@ -96,18 +449,22 @@ type GameStatePayload struct {
 	YourMana     int         `json:"your_mana,omitempty"`     // Only for Enhanced mode
 	OpponentMana int         `json:"opponent_mana,omitempty"` // Only for Enhanced mode
 	TimeLeft     int         `json:"time_left,omitempty"`     // Only for Enhanced mode, in seconds
+
+	Mana map[string]int `json:"mana,omitempty"` // Only for spectators of Enhanced mode games, by username
 }
 
 // GameEventPayload represents a game event notification
 type GameEventPayload struct {
-	Message string    `json:"message"`
-	Time    time.Time `json:"time"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+	Critical bool      `json:"critical,omitempty"` // Set on attack events that were critical hits
 }
 
 // TurnChangePayload represents a turn change notification
 type TurnChangePayload struct {
 	YourTurn  bool      `json:"your_turn"`
 	TimeoutAt time.Time `json:"timeout_at,omitempty"` // Optional, for turn time limits
+	Player    string    `json:"player,omitempty"`     // Only for spectators: username of the player to move
 }
 
 // GameOverPayload represents the game over notification
@@ -118,12 +475,19 @@ type GameOverPayload struct {
 	NewTotalExp int    `json:"new_total_exp"`
 	NewLevel    int    `json:"new_level"`
 	LeveledUp   bool   `json:"leveled_up"`
+
+	NewRating    int `json:"new_rating,omitempty"` // Skill rating after the match
+	RatingChange int `json:"rating_change"`
 }
 
 // ErrorPayload represents an error message
 type ErrorPayload struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+
+	// Set on ErrorCodeIncompatibleVersion: the protocol versions the server accepts
+	MinProtocolVersion int `json:"min_protocol_version,omitempty"`
+	MaxProtocolVersion int `json:"max_protocol_version,omitempty"`
 }
 
 // TroopChoiceInfo contains details for a single troop choice
@@ -137,3 +501,97 @@ type TroopChoiceInfo struct {
 type TroopChoicesPayload struct {
 	Choices []TroopChoiceInfo `json:"choices"`
 }
+
+// MatchSummary is one match in a player's recent history
+type MatchSummary struct {
+	GameID    string    `json:"game_id"`
+	GameMode  string    `json:"game_mode"`
+	Opponent  string    `json:"opponent"`
+	Result    string    `json:"result"` // "win", "loss" or "draw"
+	ExpEarned int       `json:"exp_earned"`
+	EndedAt   time.Time `json:"ended_at"`
+}
+
+// StatsPayload carries a player's account statistics
+type StatsPayload struct {
+	Username        string         `json:"username"`
+	Level           int            `json:"level"`
+	EXP             int            `json:"exp"`
+	Rating          int            `json:"rating"`
+	Wins            int            `json:"wins"`
+	Losses          int            `json:"losses"`
+	Draws           int            `json:"draws"`
+	TowersDestroyed int            `json:"towers_destroyed"`
+	TroopsDeployed  map[string]int `json:"troops_deployed,omitempty"` // By troop spec ID
+	RecentMatches   []MatchSummary `json:"recent_matches,omitempty"`  // Newest first
+}
+
+// LeaderboardEntry is one ranked player on the leaderboard
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Level    int    `json:"level"`
+	EXP      int    `json:"exp"`
+	Wins     int    `json:"wins"`
+	Losses   int    `json:"losses"`
+	Draws    int    `json:"draws"`
+}
+
+// LeaderboardPayload carries one page of the leaderboard
+type LeaderboardPayload struct {
+	SortBy       string             `json:"sort_by"`
+	Page         int                `json:"page"`
+	PageSize     int                `json:"page_size"`
+	TotalPages   int                `json:"total_pages"`
+	TotalPlayers int                `json:"total_players"`
+	Entries      []LeaderboardEntry `json:"entries"`
+}
+
+// MatchFoundPayload announces an opponent. Both players must accept by AcceptBy, or the match
+// is called off and whoever accepted goes back to the queue.
+type MatchFoundPayload struct {
+	MatchID          string    `json:"match_id"`
+	Mode             string    `json:"mode"`
+	OpponentUsername string    `json:"opponent_username"`
+	OpponentRating   int       `json:"opponent_rating"`
+	AcceptBy         time.Time `json:"accept_by"`
+}
+
+// ChallengeReceivedPayload tells a player they were challenged. Unless they answer by
+// ExpiresAt, the challenge lapses.
+type ChallengeReceivedPayload struct {
+	ChallengeID  string    `json:"challenge_id"`
+	FromUsername string    `json:"from_username"`
+	FromRating   int       `json:"from_rating"`
+	Mode         string    `json:"mode"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// GameSummary is one game in progress
+type GameSummary struct {
+	GameID     string    `json:"game_id"`
+	GameMode   string    `json:"game_mode"`
+	Player1    string    `json:"player1"`
+	Player2    string    `json:"player2"`
+	Spectators int       `json:"spectators"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// GameListPayload lists the games in progress, oldest first
+type GameListPayload struct {
+	Games []GameSummary `json:"games"`
+}
+
+// SpectateStartPayload opens a watched game with its players and the state as it stands.
+// Until the game ends or the spectator stops, the game's state updates, events, turn changes
+// and game over notice follow, seen from neither player's side.
+type SpectateStartPayload struct {
+	GameID        string            `json:"game_id"`
+	GameMode      string            `json:"game_mode"` // GameModeSimple or GameModeEnhanced
+	Player1       string            `json:"player1"`
+	Player2       string            `json:"player2"`
+	CurrentTurn   string            `json:"current_turn,omitempty"`    // Simple mode: username of the player to move
+	TurnTimeoutAt time.Time         `json:"turn_timeout_at,omitempty"` // Deadline of the current turn (Simple mode)
+	State         *GameStatePayload `json:"state"`
+}
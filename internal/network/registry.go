@@ -0,0 +1,184 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// requestTypes maps each message type a client may send to a constructor for its payload
+// struct. The server accepts no other types.
+var requestTypes = map[MessageType]func() interface{}{
+	MessageTypeLogin:             func() interface{} { return &LoginPayload{} },
+	MessageTypeResume:            func() interface{} { return &ResumePayload{} },
+	MessageTypeRegister:          func() interface{} { return &RegisterPayload{} },
+	MessageTypeDeployTroop:       func() interface{} { return &DeployTroopPayload{} },
+	MessageTypeQuit:              func() interface{} { return &QuitPayload{} },
+	MessageTypeJoinQueue:         func() interface{} { return &JoinQueuePayload{} },
+	MessageTypeStats:             func() interface{} { return &StatsRequestPayload{} },
+	MessageTypeLeaderboard:       func() interface{} { return &LeaderboardRequestPayload{} },
+	MessageTypeLeaveQueue:        func() interface{} { return &LeaveQueuePayload{} },
+	MessageTypeMatchAccept:       func() interface{} { return &MatchAcceptPayload{} },
+	MessageTypeQueueStatus:       func() interface{} { return &QueueStatusPayload{} },
+	MessageTypeChallenge:         func() interface{} { return &ChallengePayload{} },
+	MessageTypeChallengeResponse: func() interface{} { return &ChallengeResponsePayload{} },
+	MessageTypePlayBot:           func() interface{} { return &PlayBotPayload{} },
+	MessageTypeListGames:         func() interface{} { return &ListGamesPayload{} },
+	MessageTypeSpectate:          func() interface{} { return &SpectatePayload{} },
+	MessageTypeStopSpectating:    func() interface{} { return &StopSpectatingPayload{} },
+	MessageTypeHello:             func() interface{} { return &HelloPayload{} },
+	MessageTypeFraming:           func() interface{} { return &FramingPayload{} },
+	MessageTypeGameEvent:         func() interface{} { return &GameEventPayload{} }, // Chat
+}
+
+// serverMessageTypes maps each message type the server sends to a constructor for its
+// payload struct
+var serverMessageTypes = map[MessageType]func() interface{}{
+	MessageTypeHello:             func() interface{} { return &HelloPayload{} },
+	MessageTypeFraming:           func() interface{} { return &FramingPayload{} },
+	MessageTypeAuthResult:        func() interface{} { return &AuthResultPayload{} },
+	MessageTypeGameStart:         func() interface{} { return &GameStartPayload{} },
+	MessageTypeStateUpdate:       func() interface{} { return &GameStatePayload{} },
+	MessageTypeGameEvent:         func() interface{} { return &GameEventPayload{} },
+	MessageTypeGameOver:          func() interface{} { return &GameOverPayload{} },
+	MessageTypeTurnChange:        func() interface{} { return &TurnChangePayload{} },
+	MessageTypeError:             func() interface{} { return &ErrorPayload{} },
+	MessageTypeTroopChoices:      func() interface{} { return &TroopChoicesPayload{} },
+	MessageTypeStatsResult:       func() interface{} { return &StatsPayload{} },
+	MessageTypeLeaderboardResult: func() interface{} { return &LeaderboardPayload{} },
+	MessageTypeQueueStatus:       func() interface{} { return &QueueStatusPayload{} },
+	MessageTypeMatchFound:        func() interface{} { return &MatchFoundPayload{} },
+	MessageTypeChallengeReceived: func() interface{} { return &ChallengeReceivedPayload{} },
+	MessageTypeGameList:          func() interface{} { return &GameListPayload{} },
+	MessageTypeSpectateStart:     func() interface{} { return &SpectateStartPayload{} },
+}
+
+// Validator is implemented by payloads with fields that must be present or well-formed
+type Validator interface {
+	Validate() error
+}
+
+// PayloadError describes why a message payload was rejected. Field is empty when the
+// problem is not with a single field (e.g. the payload is not a JSON object).
+type PayloadError struct {
+	Type   MessageType
+	Field  string
+	Reason string
+}
+
+func (e *PayloadError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid %s payload: %s", e.Type, e.Reason)
+	}
+	return fmt.Sprintf("invalid %s payload: field %q %s", e.Type, e.Field, e.Reason)
+}
+
+// DecodeRequest decodes a message a client sent into the payload struct registered for its
+// type. Decoding is strict: unknown fields are rejected, so a client can't believe the server
+// acted on a field it ignored. Types that are not requests are rejected too.
+func DecodeRequest(msg *Message) (interface{}, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("cannot decode nil message")
+	}
+	newPayload, exists := requestTypes[msg.Type]
+	if !exists {
+		return nil, &PayloadError{Type: msg.Type, Reason: "message type is not accepted by the server"}
+	}
+	target := newPayload()
+	if err := decodePayload(msg.Type, msg.Payload, target, true); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// DecodeMessage decodes a message the server sent into the payload struct registered for its
+// type. Like ParsePayload it ignores unknown fields, which newer servers may add.
+func DecodeMessage(msg *Message) (interface{}, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("cannot decode nil message")
+	}
+	newPayload, exists := serverMessageTypes[msg.Type]
+	if !exists {
+		return nil, &PayloadError{Type: msg.Type, Reason: "unknown message type"}
+	}
+	target := newPayload()
+	if err := ParsePayload(msg, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// Decode decodes a message's payload into a new value of type T
+func Decode[T any](msg *Message) (*T, error) {
+	target := new(T)
+	if err := ParsePayload(msg, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// decodePayload decodes a single JSON value into target, rejecting trailing data and, if
+// strict, unknown fields, then validates the result
+func decodePayload(msgType MessageType, data []byte, target interface{}, strict bool) error {
+	if len(data) == 0 {
+		data = []byte("null")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(target); err != nil {
+		return payloadErrorFromJSON(msgType, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return &PayloadError{Type: msgType, Reason: "unexpected data after payload"}
+	}
+
+	if validator, ok := target.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			var payloadErr *PayloadError
+			if errors.As(err, &payloadErr) {
+				payloadErr.Type = msgType
+				return payloadErr
+			}
+			return &PayloadError{Type: msgType, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// payloadErrorFromJSON turns an encoding/json error into a PayloadError naming the field at fault
+func payloadErrorFromJSON(msgType MessageType, err error) *PayloadError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return &PayloadError{Type: msgType, Reason: fmt.Sprintf("must be a JSON object, got %s", typeErr.Value)}
+		}
+		return &PayloadError{Type: msgType, Field: typeErr.Field, Reason: fmt.Sprintf("must be a %s, got %s", typeErr.Type, typeErr.Value)}
+	}
+
+	// DisallowUnknownFields reports `json: unknown field "name"`
+	var field string
+	if _, scanErr := fmt.Sscanf(err.Error(), "json: unknown field %q", &field); scanErr == nil {
+		return &PayloadError{Type: msgType, Field: field, Reason: "is not a known field"}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &PayloadError{Type: msgType, Reason: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)}
+	}
+	return &PayloadError{Type: msgType, Reason: err.Error()}
+}
+
+// requiredField returns a PayloadError for a missing required field
+func requiredField(field string) error {
+	return &PayloadError{Field: field, Reason: "is required"}
+}
+
+// invalidField returns a PayloadError for a field with an unacceptable value
+func invalidField(field string, reason string) error {
+	return &PayloadError{Field: field, Reason: reason}
+}
@@ -0,0 +1,113 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		msgType   MessageType
+		payload   string
+		wantField string // Field the PayloadError names, "" if it names none
+		wantErr   string // Substring of the error, "" if decoding succeeds
+	}{
+		{name: "valid", msgType: MessageTypeDeployTroop, payload: `{"troop_id":"pawn"}`},
+		{name: "unknown field", msgType: MessageTypeDeployTroop, payload: `{"troop_id":"pawn","speed":3}`, wantField: "speed", wantErr: "is not a known field"},
+		{name: "wrong type", msgType: MessageTypeDeployTroop, payload: `{"troop_id":5}`, wantField: "troop_id", wantErr: "must be a string, got number"},
+		{name: "not an object", msgType: MessageTypeDeployTroop, payload: `["pawn"]`, wantErr: "must be a JSON object, got array"},
+		{name: "missing required field", msgType: MessageTypeDeployTroop, payload: `{}`, wantField: "troop_id", wantErr: "is required"},
+		{name: "trailing data", msgType: MessageTypeDeployTroop, payload: `{"troop_id":"pawn"} {}`, wantErr: "unexpected data after payload"},
+		{name: "server message type", msgType: MessageTypeStateUpdate, payload: `{}`, wantErr: "message type is not accepted by the server"},
+		{name: "unknown message type", msgType: "teleport", payload: `{}`, wantErr: "message type is not accepted by the server"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := DecodeRequest(&Message{Type: tt.msgType, Payload: json.RawMessage(tt.payload)})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("DecodeRequest: %v", err)
+				}
+				if _, ok := payload.(*DeployTroopPayload); !ok {
+					t.Fatalf("payload is %T, want *DeployTroopPayload", payload)
+				}
+				return
+			}
+
+			var payloadErr *PayloadError
+			if !errors.As(err, &payloadErr) {
+				t.Fatalf("error = %v, want a *PayloadError", err)
+			}
+			if payloadErr.Type != tt.msgType || payloadErr.Field != tt.wantField {
+				t.Errorf("error names type %q field %q, want type %q field %q", payloadErr.Type, payloadErr.Field, tt.msgType, tt.wantField)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeMessageIgnoresUnknownFields(t *testing.T) {
+	msg := &Message{Type: MessageTypeGameOver, Payload: json.RawMessage(`{"winner":"alice","added_later":true}`)}
+	payload, err := DecodeMessage(msg)
+	if err != nil {
+		t.Fatalf("DecodeMessage: %v", err)
+	}
+	gameOver, ok := payload.(*GameOverPayload)
+	if !ok {
+		t.Fatalf("payload is %T, want *GameOverPayload", payload)
+	}
+	if gameOver.Winner != "alice" {
+		t.Errorf("winner = %q, want %q", gameOver.Winner, "alice")
+	}
+
+	if _, err := DecodeMessage(&Message{Type: MessageTypeGameOver, Payload: json.RawMessage(`{"winner":7}`)}); err == nil {
+		t.Error("DecodeMessage accepted a field of the wrong type")
+	}
+}
+
+func TestReceiveRejectsOversizedFrames(t *testing.T) {
+	const maxFrameSize = 64
+	body := `{"type":"deploy_troop","payload":{"troop_id":"` + strings.Repeat("x", 2*maxFrameSize) + `"}}`
+
+	lengthPrefixed := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(lengthPrefixed, uint32(len(body)))
+	lengthPrefixed = append(lengthPrefixed, body...)
+
+	tests := []struct {
+		name    string
+		framing int
+		frame   []byte
+	}{
+		{name: "json line", framing: FramingJSONLine, frame: []byte(body + "\n")},
+		{name: "length prefixed", framing: FramingLengthPrefixed, frame: lengthPrefixed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			defer clientConn.Close()
+
+			codec := NewCodec(serverConn)
+			if err := codec.SetFraming(tt.framing); err != nil {
+				t.Fatalf("SetFraming: %v", err)
+			}
+			codec.SetMaxFrameSize(maxFrameSize)
+
+			// The reader gives up part way through the frame, so the write fails once the pipe closes
+			go clientConn.Write(tt.frame)
+
+			if _, err := codec.Receive(); !errors.Is(err, ErrFrameTooLarge) {
+				t.Fatalf("Receive error = %v, want %v", err, ErrFrameTooLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+)
+
+// PlayerStore loads and saves player data. Implementations must be safe for concurrent use.
+type PlayerStore interface {
+	// LoadPlayer returns the player's data, or nil without an error if there is no such player
+	LoadPlayer(username string) (*models.PlayerData, error)
+	// SavePlayer creates or replaces the player's data. The data's Version must match the
+	// stored one (0 for a new player), otherwise ErrVersionConflict is returned; on success
+	// Version is incremented.
+	SavePlayer(playerData *models.PlayerData) error
+	// UpdatePlayer loads the player, applies update and saves the result while holding the
+	// player's lock, returning the saved data. Missing players give ErrPlayerNotFound.
+	UpdatePlayer(username string, update func(*models.PlayerData) error) (*models.PlayerData, error)
+	// ListPlayers returns every stored player, in no particular order
+	ListPlayers() ([]*models.PlayerData, error)
+	// Close releases the store's resources
+	Close() error
+}
+
+// Errors returned by player stores
+var (
+	ErrPlayerNotFound  = errors.New("player not found")
+	ErrVersionConflict = errors.New("player data was modified concurrently")
+)
+
+// maxUpdateAttempts bounds how often UpdatePlayer retries after a version conflict, which
+// only happens when something outside this process writes the same player
+const maxUpdateAttempts = 3
+
+// playerStoreOpeners creates each kind of store, given the server's base path and the
+// store-specific path (empty for the default location)
+var playerStoreOpeners = map[string]func(basePath, path string) (PlayerStore, error){
+	"file": func(basePath, path string) (PlayerStore, error) {
+		return NewFileStore(basePath), nil
+	},
+	"memory": func(basePath, path string) (PlayerStore, error) {
+		return NewMemoryStore(), nil
+	},
+}
+
+// OpenPlayerStore opens a player store of the given kind ("file", "memory", or "sqlite"
+// when built with the sqlite tag)
+func OpenPlayerStore(kind, basePath, path string) (PlayerStore, error) {
+	open, exists := playerStoreOpeners[kind]
+	if !exists {
+		return nil, fmt.Errorf("unknown player store %q, available: %s", kind, strings.Join(PlayerStoreKinds(), ", "))
+	}
+	return open(basePath, path)
+}
+
+// PlayerStoreKinds lists the kinds of player store this build supports
+func PlayerStoreKinds() []string {
+	kinds := make([]string, 0, len(playerStoreOpeners))
+	for kind := range playerStoreOpeners {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// playerLocks hands out one mutex per username, dropping it once nobody holds or waits for it
+type playerLocks struct {
+	locks map[string]*playerLock
+	mutex sync.Mutex
+}
+
+// playerLock is a username's mutex and the number of goroutines holding or waiting for it
+type playerLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock acquires the username's lock and returns the function releasing it
+func (l *playerLocks) lock(username string) func() {
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*playerLock)
+	}
+	lock, exists := l.locks[username]
+	if !exists {
+		lock = &playerLock{}
+		l.locks[username] = lock
+	}
+	lock.refs++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, username)
+		}
+		l.mutex.Unlock()
+	}
+}
+
+// checkVersion reports a conflict unless playerData is based on the stored version
+func checkVersion(stored, playerData *models.PlayerData) error {
+	var storedVersion int64
+	if stored != nil {
+		storedVersion = stored.Version
+	}
+	if playerData.Version != storedVersion {
+		return fmt.Errorf("%w: %s has version %d, update is based on %d",
+			ErrVersionConflict, playerData.Username, storedVersion, playerData.Version)
+	}
+	return nil
+}
+
+// updatePlayer runs a read-modify-write with the store's unlocked load and save, retrying
+// when the save finds the player changed underneath it. The caller holds the player's lock.
+func updatePlayer(load func(string) (*models.PlayerData, error), save func(*models.PlayerData) error,
+	username string, update func(*models.PlayerData) error) (*models.PlayerData, error) {
+	var err error
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var playerData *models.PlayerData
+		playerData, err = load(username)
+		if err != nil {
+			return nil, err
+		}
+		if playerData == nil {
+			return nil, ErrPlayerNotFound
+		}
+		if err := update(playerData); err != nil {
+			return nil, err
+		}
+
+		err = save(playerData)
+		if err == nil {
+			return playerData, nil
+		}
+		if !errors.Is(err, ErrVersionConflict) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// FileStore keeps each player in a JSON file under data/players
+type FileStore struct {
+	basePath string
+	locks    playerLocks
+}
+
+// NewFileStore creates a store for the player files under basePath
+func NewFileStore(basePath string) *FileStore {
+	return &FileStore{basePath: basePath}
+}
+
+// LoadPlayer reads the player's JSON file
+func (s *FileStore) LoadPlayer(username string) (*models.PlayerData, error) {
+	return LoadPlayerData(s.basePath, username)
+}
+
+// SavePlayer writes the player's JSON file if nobody saved it since it was loaded
+func (s *FileStore) SavePlayer(playerData *models.PlayerData) error {
+	unlock := s.locks.lock(playerData.Username)
+	defer unlock()
+	return s.save(playerData)
+}
+
+// UpdatePlayer applies update to the player's file under the player's lock
+func (s *FileStore) UpdatePlayer(username string, update func(*models.PlayerData) error) (*models.PlayerData, error) {
+	unlock := s.locks.lock(username)
+	defer unlock()
+	return updatePlayer(s.LoadPlayer, s.save, username, update)
+}
+
+// ListPlayers reads every player file. Files not named after a canonical username are
+// skipped, as the server can't load them anyway (tcr-usercheck reports them).
+func (s *FileStore) ListPlayers() ([]*models.PlayerData, error) {
+	entries, err := os.ReadDir(PlayersDir(s.basePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read players directory: %w", err)
+	}
+
+	players := make([]*models.PlayerData, 0, len(entries))
+	for _, entry := range entries {
+		username, isJSON := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !isJSON || !models.IsCanonicalUsername(username) {
+			continue
+		}
+		playerData, err := LoadPlayerData(s.basePath, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load player %s: %w", username, err)
+		}
+		if playerData != nil {
+			players = append(players, playerData)
+		}
+	}
+	return players, nil
+}
+
+// save checks the version against the file and writes the next one
+func (s *FileStore) save(playerData *models.PlayerData) error {
+	stored, err := LoadPlayerData(s.basePath, playerData.Username)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(stored, playerData); err != nil {
+		return err
+	}
+
+	next := *playerData
+	next.Version++
+	if err := SavePlayerData(s.basePath, &next); err != nil {
+		return err
+	}
+	playerData.Version = next.Version
+	return nil
+}
+
+// Close does nothing, files are not kept open
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// MemoryStore keeps players in memory only, for tests and throwaway servers
+type MemoryStore struct {
+	players map[string]models.PlayerData
+	mutex   sync.RWMutex
+	locks   playerLocks
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{players: make(map[string]models.PlayerData)}
+}
+
+// LoadPlayer returns a copy of the stored player
+func (s *MemoryStore) LoadPlayer(username string) (*models.PlayerData, error) {
+	if !models.IsCanonicalUsername(username) {
+		return nil, fmt.Errorf("refusing non-canonical username %q: %w", username, models.ErrInvalidUsername)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	playerData, exists := s.players[username]
+	if !exists {
+		return nil, nil
+	}
+	return playerData.Clone(), nil
+}
+
+// SavePlayer stores a copy of the player if nobody saved it since it was loaded
+func (s *MemoryStore) SavePlayer(playerData *models.PlayerData) error {
+	if !models.IsCanonicalUsername(playerData.Username) {
+		return fmt.Errorf("refusing non-canonical username %q: %w", playerData.Username, models.ErrInvalidUsername)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var stored *models.PlayerData
+	if existing, exists := s.players[playerData.Username]; exists {
+		stored = &existing
+	}
+	if err := checkVersion(stored, playerData); err != nil {
+		return err
+	}
+
+	playerData.Version++
+	s.players[playerData.Username] = *playerData.Clone()
+	return nil
+}
+
+// UpdatePlayer applies update to the stored player under the player's lock
+func (s *MemoryStore) UpdatePlayer(username string, update func(*models.PlayerData) error) (*models.PlayerData, error) {
+	unlock := s.locks.lock(username)
+	defer unlock()
+	return updatePlayer(s.LoadPlayer, s.SavePlayer, username, update)
+}
+
+// ListPlayers returns copies of all stored players
+func (s *MemoryStore) ListPlayers() ([]*models.PlayerData, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	players := make([]*models.PlayerData, 0, len(s.players))
+	for _, playerData := range s.players {
+		players = append(players, playerData.Clone())
+	}
+	return players, nil
+}
+
+// Close does nothing
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,164 @@
+//go:build sqlite
+
+package persistence
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	_ "github.com/mattn/go-sqlite3" // Registers the "sqlite3" driver
+)
+
+func init() {
+	playerStoreOpeners["sqlite"] = func(basePath, path string) (PlayerStore, error) {
+		if path == "" {
+			path = filepath.Join(basePath, "data", "players.db")
+		}
+		return NewSQLiteStore(path)
+	}
+}
+
+// SQLiteStore keeps players in an embedded SQLite database. Each row holds the player's
+// data as JSON, so new PlayerData fields need no schema change.
+type SQLiteStore struct {
+	db    *sql.DB
+	locks playerLocks
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database at path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open player database: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS players (
+		username TEXT PRIMARY KEY,
+		data     TEXT NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create players table: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// LoadPlayer reads the player's row
+func (s *SQLiteStore) LoadPlayer(username string) (*models.PlayerData, error) {
+	if !models.IsCanonicalUsername(username) {
+		return nil, fmt.Errorf("refusing non-canonical username %q: %w", username, models.ErrInvalidUsername)
+	}
+	return loadPlayerRow(s.db, username)
+}
+
+// SavePlayer inserts or replaces the player's row if nobody saved it since it was loaded.
+// Transactions take the write lock up front (_txlock=immediate), so the version check and
+// the write can't interleave with another process.
+func (s *SQLiteStore) SavePlayer(playerData *models.PlayerData) error {
+	if !models.IsCanonicalUsername(playerData.Username) {
+		return fmt.Errorf("refusing non-canonical username %q: %w", playerData.Username, models.ErrInvalidUsername)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := loadPlayerRow(tx, playerData.Username)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(stored, playerData); err != nil {
+		return err
+	}
+
+	next := *playerData
+	next.Version++
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return fmt.Errorf("failed to encode player data: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO players (username, data) VALUES (?, ?)
+		ON CONFLICT(username) DO UPDATE SET data = excluded.data`, next.Username, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save player data: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save player data: %w", err)
+	}
+	playerData.Version = next.Version
+	return nil
+}
+
+// UpdatePlayer applies update to the player's row under the player's lock
+func (s *SQLiteStore) UpdatePlayer(username string, update func(*models.PlayerData) error) (*models.PlayerData, error) {
+	unlock := s.locks.lock(username)
+	defer unlock()
+	return updatePlayer(s.LoadPlayer, s.SavePlayer, username, update)
+}
+
+// ListPlayers reads every player's row
+func (s *SQLiteStore) ListPlayers() ([]*models.PlayerData, error) {
+	rows, err := s.db.Query(`SELECT data FROM players`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list players: %w", err)
+	}
+	defer rows.Close()
+
+	var players []*models.PlayerData
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read player data: %w", err)
+		}
+		var playerData models.PlayerData
+		if err := json.Unmarshal([]byte(data), &playerData); err != nil {
+			return nil, fmt.Errorf("failed to parse player data: %w", err)
+		}
+		players = append(players, &playerData)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list players: %w", err)
+	}
+	return players, nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// loadPlayerRow reads and decodes a player's row, or returns nil if there is none
+func loadPlayerRow(q queryRower, username string) (*models.PlayerData, error) {
+	var data string
+	err := q.QueryRow(`SELECT data FROM players WHERE username = ?`, username).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read player data: %w", err)
+	}
+
+	var playerData models.PlayerData
+	if err := json.Unmarshal([]byte(data), &playerData); err != nil {
+		return nil, fmt.Errorf("failed to parse player data: %w", err)
+	}
+	return &playerData, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,222 @@
+package persistence
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+)
+
+// newStoredPlayer saves a player who deployed two pawns in one won match
+func newStoredPlayer(t *testing.T, store PlayerStore) *models.PlayerData {
+	t.Helper()
+	playerData := &models.PlayerData{Username: "alice", Level: 1}
+	playerData.Stats.RecordMatch(models.MatchRecord{GameID: "game-1", Result: models.MatchResultWin}, 1, map[string]int{"pawn": 2})
+	if err := store.SavePlayer(playerData); err != nil {
+		t.Fatalf("SavePlayer: %v", err)
+	}
+	return playerData
+}
+
+// assertStoredStats checks the stored player still has the stats newStoredPlayer saved
+func assertStoredStats(t *testing.T, store PlayerStore) {
+	t.Helper()
+	stored, err := store.LoadPlayer("alice")
+	if err != nil {
+		t.Fatalf("LoadPlayer: %v", err)
+	}
+	if stored.Stats.Wins != 1 || stored.Stats.TroopsDeployed["pawn"] != 2 || len(stored.Stats.TroopsDeployed) != 1 || len(stored.Stats.RecentMatches) != 1 {
+		t.Errorf("stored stats changed through a copy: %+v", stored.Stats)
+	}
+}
+
+func TestMemoryStoreCopiesStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, store *MemoryStore, saved *models.PlayerData)
+	}{
+		{
+			name: "loaded copy",
+			change: func(t *testing.T, store *MemoryStore, saved *models.PlayerData) {
+				loaded, err := store.LoadPlayer("alice")
+				if err != nil {
+					t.Fatalf("LoadPlayer: %v", err)
+				}
+				loaded.Stats.RecordMatch(models.MatchRecord{GameID: "game-2", Result: models.MatchResultLoss}, 0, map[string]int{"pawn": 1, "knight": 1})
+			},
+		},
+		{
+			name: "listed copy",
+			change: func(t *testing.T, store *MemoryStore, saved *models.PlayerData) {
+				players, err := store.ListPlayers()
+				if err != nil {
+					t.Fatalf("ListPlayers: %v", err)
+				}
+				players[0].Stats.TroopsDeployed["knight"] = 5
+				players[0].Stats.RecentMatches[0].Result = models.MatchResultLoss
+			},
+		},
+		{
+			name: "saved original",
+			change: func(t *testing.T, store *MemoryStore, saved *models.PlayerData) {
+				saved.Stats.TroopsDeployed["pawn"] = 7
+				saved.Stats.RecentMatches[0].GameID = "changed"
+			},
+		},
+		{
+			name: "rejected save",
+			change: func(t *testing.T, store *MemoryStore, saved *models.PlayerData) {
+				stale, err := store.LoadPlayer("alice")
+				if err != nil {
+					t.Fatalf("LoadPlayer: %v", err)
+				}
+				stale.Version--
+				stale.Stats.TroopsDeployed["pawn"] = 9
+				if err := store.SavePlayer(stale); !errors.Is(err, ErrVersionConflict) {
+					t.Fatalf("SavePlayer error = %v, want %v", err, ErrVersionConflict)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			saved := newStoredPlayer(t, store)
+			tt.change(t, store, saved)
+			assertStoredStats(t, store)
+		})
+	}
+}
+
+// testStores returns a file store and a memory store to run the same test against
+func testStores(t *testing.T) map[string]PlayerStore {
+	return map[string]PlayerStore{
+		"file":   NewFileStore(t.TempDir()),
+		"memory": NewMemoryStore(),
+	}
+}
+
+func TestSavePlayerChecksVersion(t *testing.T) {
+	for kind, store := range testStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			if err := store.SavePlayer(&models.PlayerData{Username: "alice", Version: 3}); !errors.Is(err, ErrVersionConflict) {
+				t.Fatalf("saving a new player with a version: %v, want %v", err, ErrVersionConflict)
+			}
+
+			first := &models.PlayerData{Username: "alice", Level: 1}
+			if err := store.SavePlayer(first); err != nil {
+				t.Fatalf("SavePlayer: %v", err)
+			}
+			if first.Version != 1 {
+				t.Errorf("version after first save = %d, want 1", first.Version)
+			}
+
+			// Two copies loaded at the same version: only the first save wins
+			winner, _ := store.LoadPlayer("alice")
+			loser, _ := store.LoadPlayer("alice")
+			winner.EXP = 10
+			loser.EXP = 20
+			if err := store.SavePlayer(winner); err != nil {
+				t.Fatalf("SavePlayer: %v", err)
+			}
+			if err := store.SavePlayer(loser); !errors.Is(err, ErrVersionConflict) {
+				t.Fatalf("saving a stale copy: %v, want %v", err, ErrVersionConflict)
+			}
+
+			stored, err := store.LoadPlayer("alice")
+			if err != nil {
+				t.Fatalf("LoadPlayer: %v", err)
+			}
+			if stored.EXP != 10 || stored.Version != 2 {
+				t.Errorf("stored EXP %d at version %d, want 10 at version 2", stored.EXP, stored.Version)
+			}
+		})
+	}
+}
+
+func TestUpdatePlayerLosesNoUpdates(t *testing.T) {
+	for kind, store := range testStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			if err := store.SavePlayer(&models.PlayerData{Username: "alice", Level: 1}); err != nil {
+				t.Fatalf("SavePlayer: %v", err)
+			}
+
+			const updates = 20
+			var wg sync.WaitGroup
+			for i := 0; i < updates; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := store.UpdatePlayer("alice", func(p *models.PlayerData) error {
+						p.EXP++
+						return nil
+					}); err != nil {
+						t.Errorf("UpdatePlayer: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			stored, err := store.LoadPlayer("alice")
+			if err != nil {
+				t.Fatalf("LoadPlayer: %v", err)
+			}
+			if stored.EXP != updates {
+				t.Errorf("EXP = %d after %d updates", stored.EXP, updates)
+			}
+			if _, err := store.UpdatePlayer("bob", func(*models.PlayerData) error { return nil }); !errors.Is(err, ErrPlayerNotFound) {
+				t.Errorf("updating a missing player: %v, want %v", err, ErrPlayerNotFound)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "alice.json")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("file holds %q, %v; want the new data", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("file mode = %v, want 0644", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "bob.json"), []byte("new"), 0644); err == nil {
+		t.Error("writing into a missing directory succeeded")
+	}
+}
+
+func TestFileStoreSkipsTemporaryFiles(t *testing.T) {
+	basePath := t.TempDir()
+	store := NewFileStore(basePath)
+	if err := store.SavePlayer(&models.PlayerData{Username: "alice", Level: 1}); err != nil {
+		t.Fatalf("SavePlayer: %v", err)
+	}
+	// A write interrupted before its rename leaves a partial temporary file
+	if err := os.WriteFile(filepath.Join(PlayersDir(basePath), ".alice.json.123.tmp"), []byte(`{"username": "ali`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	players, err := store.ListPlayers()
+	if err != nil {
+		t.Fatalf("ListPlayers: %v", err)
+	}
+	if len(players) != 1 || players[0].Username != "alice" {
+		t.Errorf("ListPlayers = %v, want only alice", players)
+	}
+}
@@ -87,22 +87,25 @@ func (c *ConfigLoader) LoadGameConfig() (*models.GameConfig, error) {
 
 // SavePlayerData saves player data to a JSON file in the players directory
 func SavePlayerData(basePath string, playerData *models.PlayerData) error {
+	filePath, err := playerFilePath(basePath, playerData.Username)
+	if err != nil {
+		return err
+	}
+
 	// Create the directory if it doesn't exist
-	dirPath := filepath.Join(basePath, "data", "players")
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
+	if err := os.MkdirAll(PlayersDir(basePath), 0755); err != nil {
 		return fmt.Errorf("failed to create players directory: %w", err)
 	}
 
-	filePath := filepath.Join(dirPath, fmt.Sprintf("%s.json", playerData.Username))
-
 	// Marshal the player data to JSON
 	data, err := json.MarshalIndent(playerData, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to encode player data: %w", err)
 	}
 
-	// Write to file
-	err = os.WriteFile(filePath, data, 0644)
+	// Write to a temporary file and rename it over the old one, so a crash never leaves
+	// a truncated player file behind
+	err = writeFileAtomic(filePath, data, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to save player data file: %w", err)
 	}
@@ -110,9 +113,46 @@ func SavePlayerData(basePath string, playerData *models.PlayerData) error {
 	return nil
 }
 
+// writeFileAtomic replaces path with data by writing a temporary file in the same
+// directory, syncing it and renaming it into place
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // LoadPlayerData loads a player's data from their JSON file
 func LoadPlayerData(basePath string, username string) (*models.PlayerData, error) {
-	filePath := filepath.Join(basePath, "data", "players", fmt.Sprintf("%s.json", username))
+	filePath, err := playerFilePath(basePath, username)
+	if err != nil {
+		return nil, err
+	}
 
 	// Read the file
 	data, err := os.ReadFile(filePath)
@@ -133,3 +173,71 @@ func LoadPlayerData(basePath string, username string) (*models.PlayerData, error
 
 	return &playerData, nil
 }
+
+// PlayersDir returns the directory holding the player data files
+func PlayersDir(basePath string) string {
+	return filepath.Join(basePath, "data", "players")
+}
+
+// playerFilePath returns the data file of a player. Only canonical usernames are accepted,
+// which keeps the path inside the players directory whatever the client sent.
+func playerFilePath(basePath string, username string) (string, error) {
+	if !models.IsCanonicalUsername(username) {
+		return "", fmt.Errorf("refusing player file for non-canonical username %q: %w", username, models.ErrInvalidUsername)
+	}
+	return filepath.Join(PlayersDir(basePath), username+".json"), nil
+}
+
+// SaveReplay saves a match replay to a JSON file in the replays directory. An existing
+// replay of the same game ID is never overwritten.
+func SaveReplay(basePath string, replay *models.Replay) error {
+	// Create the directory if it doesn't exist
+	filePath := ReplayPath(basePath, replay.GameID)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create replays directory: %w", err)
+	}
+
+	// Marshal the replay to JSON
+	data, err := json.MarshalIndent(replay, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode replay: %w", err)
+	}
+
+	// Write to a new file only
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create replay file: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save replay file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to save replay file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadReplay loads a match replay from the given JSON file
+func LoadReplay(filePath string) (*models.Replay, error) {
+	// Read the file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay file: %w", err)
+	}
+
+	// Parse the JSON
+	var replay models.Replay
+	err = json.Unmarshal(data, &replay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse replay file: %w", err)
+	}
+
+	return &replay, nil
+}
+
+// ReplayPath returns the path of the replay file for the given game ID
+func ReplayPath(basePath string, gameID string) string {
+	return filepath.Join(basePath, "data", "replays", fmt.Sprintf("%s.json", gameID))
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// minPasswordLength is the shortest password accepted at registration
+const minPasswordLength = 8
+
+// AuthError is an authentication or registration failure with a code for the client
+type AuthError struct {
+	Code    int // One of the network.AuthCode constants
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// newAuthError creates an AuthError
+func newAuthError(code int, format string, args ...interface{}) *AuthError {
+	return &AuthError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// canonicalUsername applies the username policy (see models.CanonicalUsername) to a name
+// sent by a client, returning the case-folded form used for storage and sessions
+func canonicalUsername(username string) (string, error) {
+	canonical, err := models.CanonicalUsername(username)
+	if err != nil {
+		return "", newAuthError(network.AuthCodeInvalidInput, "%v", err)
+	}
+	return canonical, nil
+}
+
+// ValidatePassword checks that a password is at least 8 characters with a letter and a digit
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return newAuthError(network.AuthCodeWeakPassword, "password must be at least %d characters long", minPasswordLength)
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return newAuthError(network.AuthCodeWeakPassword, "password must contain both letters and digits")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+// authCode returns the code of an AuthError, or 0 for any other error
+func authCode(err error) int {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr.Code
+	}
+	return 0
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		wantErr  bool
+	}{
+		{password: "password1"},
+		{password: "12345abc"},
+		{password: "pässwörd9"},
+		{password: "pass1", wantErr: true},
+		{password: "passwords", wantErr: true},
+		{password: "123456789", wantErr: true},
+		{password: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			err := ValidatePassword(tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePassword(%q) = %v, want error %v", tt.password, err, tt.wantErr)
+			}
+			if err != nil && authCode(err) != network.AuthCodeWeakPassword {
+				t.Errorf("error code = %d, want %d", authCode(err), network.AuthCodeWeakPassword)
+			}
+		})
+	}
+}
+
+func TestRegisterThenLogin(t *testing.T) {
+	am := NewAuthManager(persistence.NewMemoryStore())
+	if _, err := am.AuthenticateUser("alice", "password1"); authCode(err) != network.AuthCodeUnknownUser {
+		t.Fatalf("login before registering: %v, want code %d", err, network.AuthCodeUnknownUser)
+	}
+	if _, err := am.RegisterUser("alice", "weak"); authCode(err) != network.AuthCodeWeakPassword {
+		t.Fatalf("registering with a weak password: %v, want code %d", err, network.AuthCodeWeakPassword)
+	}
+
+	playerData, err := am.RegisterUser("alice", "password1")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	if playerData.Level != 1 || playerData.HashedPassword == "password1" {
+		t.Errorf("new account = %+v, want level 1 with a hashed password", playerData)
+	}
+	if _, err := am.RegisterUser("alice", "password2"); authCode(err) != network.AuthCodeUsernameTaken {
+		t.Errorf("registering a taken name: %v, want code %d", err, network.AuthCodeUsernameTaken)
+	}
+
+	if _, err := am.AuthenticateUser("alice", "password1"); err != nil {
+		t.Errorf("login with the right password: %v", err)
+	}
+	if _, err := am.AuthenticateUser("alice", "password2"); authCode(err) != network.AuthCodeInvalidCredentials {
+		t.Errorf("login with the wrong password: %v, want code %d", err, network.AuthCodeInvalidCredentials)
+	}
+}
+
+func TestAutoCreateAccounts(t *testing.T) {
+	am := NewAuthManager(persistence.NewMemoryStore())
+	am.AutoCreateAccounts = true
+	if _, err := am.AuthenticateUser("bob", "password1"); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if _, err := am.AuthenticateUser("bob", "password1"); err != nil {
+		t.Errorf("login to the created account: %v", err)
+	}
+}
+
+func TestUsernamesAreCaseInsensitive(t *testing.T) {
+	am := NewAuthManager(persistence.NewMemoryStore())
+	playerData, err := am.RegisterUser("Alice", "password1")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	if playerData.Username != "alice" {
+		t.Errorf("account stored as %q, want alice", playerData.Username)
+	}
+	if _, err := am.RegisterUser("ALICE", "password1"); authCode(err) != network.AuthCodeUsernameTaken {
+		t.Errorf("registering the name in other case: %v, want code %d", err, network.AuthCodeUsernameTaken)
+	}
+	if _, err := am.AuthenticateUser("aLiCe", "password1"); err != nil {
+		t.Errorf("login with the name in other case: %v", err)
+	}
+	if _, err := am.RegisterUser("../alice", "password1"); authCode(err) != network.AuthCodeInvalidInput {
+		t.Errorf("registering a name breaking the policy: %v, want code %d", err, network.AuthCodeInvalidInput)
+	}
+}
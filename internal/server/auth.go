@@ -1,34 +1,54 @@
 package server
 
 import (
+	"crypto/rand"
 	"errors"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
 	"github.com/NP-Dat/net-centric-project/internal/persistence"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthManager handles authentication-related functionality
 type AuthManager struct {
-	basePath    string
+	store       persistence.PlayerStore
 	activeUsers map[string]string // Maps usernames to client IDs
 	usersMutex  sync.RWMutex
+
+	AutoCreateAccounts bool       // Register unknown users on login instead of rejecting them
+	registerMutex      sync.Mutex // Serializes account creation
+
+	// Session tokens, signed with a random secret until the server loads its configured one
+	TokenTTL      time.Duration
+	tokenSecret   []byte
+	revokedTokens map[string]int64 // Token ID to expiry (Unix seconds), kept until expiry
+	tokensMutex   sync.Mutex
 }
 
-// NewAuthManager creates a new authentication manager
-func NewAuthManager(basePath string) *AuthManager {
+// NewAuthManager creates a new authentication manager backed by the given player store
+func NewAuthManager(store persistence.PlayerStore) *AuthManager {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate session token secret: %v", err)
+	}
+
 	return &AuthManager{
-		basePath:    basePath,
-		activeUsers: make(map[string]string),
+		store:         store,
+		activeUsers:   make(map[string]string),
+		TokenTTL:      DefaultSessionTokenTTL,
+		tokenSecret:   secret,
+		revokedTokens: make(map[string]int64),
 	}
 }
 
 // GetPlayerData retrieves player data by username
 func (am *AuthManager) GetPlayerData(username string) (*models.Player, error) {
 	// Try to load player data from the persistence layer
-	playerData, err := persistence.LoadPlayerData(am.basePath, username)
+	playerData, err := am.store.LoadPlayer(username)
 	if err != nil {
 		log.Printf("Error loading player data for %s: %v", username, err)
 		return nil, errors.New("error loading player data")
@@ -50,51 +70,88 @@ func (am *AuthManager) GetPlayerData(username string) (*models.Player, error) {
 	return player, nil
 }
 
-// AuthenticateUser authenticates a user with the given username and password
+// AuthenticateUser authenticates a user with the given username and password. Unknown
+// users are rejected unless AutoCreateAccounts is set, in which case they are registered.
 func (am *AuthManager) AuthenticateUser(username, password string) (*models.PlayerData, error) {
 	// Validation: Check if the username or password is empty
 	if username == "" || password == "" {
-		return nil, errors.New("username and password cannot be empty")
+		return nil, newAuthError(network.AuthCodeInvalidInput, "username and password cannot be empty")
+	}
+
+	// Usernames are case-insensitive; a name breaking the policy can't have an account
+	username, err := canonicalUsername(username)
+	if err != nil {
+		return nil, err
 	}
 
 	// Try to load the player data from the persistence layer
-	playerData, err := persistence.LoadPlayerData(am.basePath, username)
+	playerData, err := am.store.LoadPlayer(username)
 	if err != nil {
 		log.Printf("Error loading player data for %s: %v", username, err)
 		return nil, errors.New("error loading player data")
 	}
 
-	// If the player doesn't exist, create a new one with the given credentials
 	if playerData == nil {
-		// Hash the password
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, errors.New("error creating user account")
+		if am.AutoCreateAccounts {
+			return am.RegisterUser(username, password)
 		}
+		return nil, newAuthError(network.AuthCodeUnknownUser, "unknown user")
+	}
 
-		// Create a new player data object
-		playerData = &models.PlayerData{
-			Username:       username,
-			HashedPassword: string(hashedPassword),
-			EXP:            0,
-			Level:          1, // Starting at level 1
-		}
+	// Player exists, verify the password
+	err = bcrypt.CompareHashAndPassword([]byte(playerData.HashedPassword), []byte(password))
+	if err != nil {
+		return nil, newAuthError(network.AuthCodeInvalidCredentials, "invalid username or password")
+	}
 
-		// Save the new player data
-		if err := persistence.SavePlayerData(am.basePath, playerData); err != nil {
-			log.Printf("Error saving new player data for %s: %v", username, err)
-			return nil, errors.New("error creating user account")
-		}
+	return playerData, nil
+}
 
-		log.Printf("Created new account for user: %s", username)
-	} else {
-		// Player exists, verify the password
-		err = bcrypt.CompareHashAndPassword([]byte(playerData.HashedPassword), []byte(password))
-		if err != nil {
-			return nil, errors.New("invalid username or password")
-		}
+// RegisterUser creates a new account after checking the username rules and password strength
+func (am *AuthManager) RegisterUser(username, password string) (*models.PlayerData, error) {
+	username, err := canonicalUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	// Hold the lock from the existence check to the save so a name can't be taken twice
+	am.registerMutex.Lock()
+	defer am.registerMutex.Unlock()
+
+	existing, err := am.store.LoadPlayer(username)
+	if err != nil {
+		log.Printf("Error loading player data for %s: %v", username, err)
+		return nil, errors.New("error creating user account")
+	}
+	if existing != nil {
+		return nil, newAuthError(network.AuthCodeUsernameTaken, "username is already taken")
+	}
+
+	// Hash the password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("error creating user account")
+	}
+
+	// Create a new player data object
+	playerData := &models.PlayerData{
+		Username:       username,
+		HashedPassword: string(hashedPassword),
+		EXP:            0,
+		Level:          1, // Starting at level 1
+		Rating:         models.DefaultRating,
+	}
+
+	// Save the new player data
+	if err := am.store.SavePlayer(playerData); err != nil {
+		log.Printf("Error saving new player data for %s: %v", username, err)
+		return nil, errors.New("error creating user account")
 	}
 
+	log.Printf("Created new account for user: %s", username)
 	return playerData, nil
 }
 
@@ -106,7 +163,7 @@ func (am *AuthManager) RegisterActiveUser(username string, clientID string) erro
 	// Check if the user is already logged in
 	if existingClientID, exists := am.activeUsers[username]; exists {
 		if existingClientID != clientID {
-			return errors.New("user already logged in from another client")
+			return newAuthError(network.AuthCodeAlreadyLoggedIn, "user already logged in from another client")
 		}
 		// If the same client ID, they're already registered
 		return nil
@@ -125,6 +182,18 @@ func (am *AuthManager) UnregisterActiveUser(username string) {
 	log.Printf("User %s unregistered from active users", username)
 }
 
+// UnregisterActiveClient removes a user from the active users list, but only if they
+// are still registered with the given client ID (a newer login is left untouched)
+func (am *AuthManager) UnregisterActiveClient(username string, clientID string) {
+	am.usersMutex.Lock()
+	defer am.usersMutex.Unlock()
+	if am.activeUsers[username] != clientID {
+		return
+	}
+	delete(am.activeUsers, username)
+	log.Printf("User %s (client %s) unregistered from active users", username, clientID)
+}
+
 // GetActiveUserCount returns the number of currently active users
 func (am *AuthManager) GetActiveUserCount() int {
 	am.usersMutex.RLock()
@@ -132,6 +201,14 @@ func (am *AuthManager) GetActiveUserCount() int {
 	return len(am.activeUsers)
 }
 
+// ActiveClientID returns the ID of the client a user is logged in on
+func (am *AuthManager) ActiveClientID(username string) (string, bool) {
+	am.usersMutex.RLock()
+	defer am.usersMutex.RUnlock()
+	clientID, exists := am.activeUsers[username]
+	return clientID, exists
+}
+
 // IsUserActive checks if a user is currently active
 func (am *AuthManager) IsUserActive(username string) bool {
 	am.usersMutex.RLock()
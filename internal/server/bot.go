@@ -0,0 +1,250 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/game"
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// How quickly bots act: the pause before a Simple mode turn, and how often a bot considers
+// deploying in Enhanced mode (about one mana regenerates in that time)
+const (
+	botThinkTime        = 1 * time.Second
+	botEnhancedInterval = 2 * time.Second
+)
+
+// DefaultBotFallbackWait is how long a player waits in matchmaking before being given a bot
+// opponent, unless overridden before Start
+const DefaultBotFallbackWait = 60 * time.Second
+
+// BotOption is a troop a bot may deploy, with the damage it would deal to the tower it targets
+type BotOption struct {
+	TroopID  string
+	ManaCost int
+	Damage   int // Attack against the target tower's DEF, 0 if it can't hurt it
+}
+
+// BotStrategy picks which of the troops a bot may deploy to play. options is never empty.
+type BotStrategy func(options []BotOption, rng *rand.Rand) BotOption
+
+// botStrategies are the strategies bots play by, by the difficulty that selects them
+var botStrategies = map[string]BotStrategy{
+	network.BotDifficultyEasy:   CheapestFirstStrategy,
+	network.BotDifficultyMedium: RandomStrategy,
+	network.BotDifficultyHard:   GreedyStrategy,
+}
+
+// RandomStrategy deploys any of the troops
+func RandomStrategy(options []BotOption, rng *rand.Rand) BotOption {
+	return options[rng.Intn(len(options))]
+}
+
+// GreedyStrategy deploys the troop dealing the most damage to the tower it targets,
+// the cheaper one on a tie
+func GreedyStrategy(options []BotOption, rng *rand.Rand) BotOption {
+	best := options[0]
+	for _, option := range options[1:] {
+		if option.Damage > best.Damage || (option.Damage == best.Damage && option.ManaCost < best.ManaCost) {
+			best = option
+		}
+	}
+	return best
+}
+
+// CheapestFirstStrategy deploys the troop costing the least mana
+func CheapestFirstStrategy(options []BotOption, rng *rand.Rand) BotOption {
+	best := options[0]
+	for _, option := range options[1:] {
+		if option.ManaCost < best.ManaCost {
+			best = option
+		}
+	}
+	return best
+}
+
+// Bot is a server-side player. It is seated in a game session like a client and plays
+// through the same SessionManager calls, choosing troops with its strategy.
+type Bot struct {
+	name       string
+	difficulty string
+	level      int
+	strategy   BotStrategy
+	sm         *SessionManager
+	rng        *rand.Rand // The bot's own source: the game's seeded one is for game logic only
+
+	mutex    sync.Mutex
+	gameID   string
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBot creates a bot of the given difficulty playing at the given level
+func NewBot(sm *SessionManager, difficulty string, level int) (*Bot, error) {
+	strategy, exists := botStrategies[difficulty]
+	if !exists {
+		return nil, fmt.Errorf("unknown bot difficulty %q", difficulty)
+	}
+	if level < 1 {
+		level = 1
+	}
+	return &Bot{
+		// Not a valid username, so a bot can never share a name with a player
+		name:       fmt.Sprintf("Bot (%s)", difficulty),
+		difficulty: difficulty,
+		level:      level,
+		strategy:   strategy,
+		sm:         sm,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		stop:       make(chan struct{}),
+	}, nil
+}
+
+// Name returns the bot's display name
+func (b *Bot) Name() string {
+	return b.name
+}
+
+// CurrentGameID returns the ID of the game the bot is playing, "" if none
+func (b *Bot) CurrentGameID() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.gameID
+}
+
+// SetGameID seats the bot in a game. Clearing it stops the bot.
+func (b *Bot) SetGameID(gameID string) {
+	b.mutex.Lock()
+	b.gameID = gameID
+	b.mutex.Unlock()
+	if gameID == "" {
+		b.stopOnce.Do(func() { close(b.stop) })
+	}
+}
+
+// Send receives a game message. The bot acts on its own goroutine so the session is never
+// held up by it.
+func (b *Bot) Send(msgType network.MessageType, payload interface{}) error {
+	switch msgType {
+	case network.MessageTypeTroopChoices:
+		if choices, ok := payload.(*network.TroopChoicesPayload); ok && len(choices.Choices) > 0 {
+			go b.playTurn(choices.Choices)
+		}
+	case network.MessageTypeGameStart:
+		if start, ok := payload.(*network.GameStartPayload); ok && start.GameMode == network.GameModeEnhanced {
+			go b.playRealTime()
+		}
+	case network.MessageTypeGameOver:
+		b.stopOnce.Do(func() { close(b.stop) })
+	}
+	return nil
+}
+
+// playerData returns the game data the bot is seated with; it has no account
+func (b *Bot) playerData() *models.Player {
+	return &models.Player{ID: b.name, Username: b.name, Level: b.level}
+}
+
+// playTurn picks one of the offered troops and deploys it after a short pause
+func (b *Bot) playTurn(choices []network.TroopChoiceInfo) {
+	// Never think so long that the turn times out
+	delay := botThinkTime
+	if timeout := b.sm.server.TurnTimeout; timeout > 0 && timeout/2 < delay {
+		delay = timeout / 2
+	}
+	select {
+	case <-b.stop:
+		return
+	case <-time.After(delay):
+	}
+
+	session, exists := b.sm.GetSession(b.CurrentGameID())
+	if !exists {
+		return
+	}
+	troopIDs := make([]string, 0, len(choices))
+	for _, choice := range choices {
+		troopIDs = append(troopIDs, choice.ID)
+	}
+	b.deploy(session, troopIDs, false)
+}
+
+// playRealTime deploys a troop it can afford every botEnhancedInterval until the game ends
+func (b *Bot) playRealTime() {
+	ticker := time.NewTicker(botEnhancedInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+
+		session, exists := b.sm.GetSession(b.CurrentGameID())
+		if !exists {
+			return
+		}
+		troopIDs := make([]string, 0, len(session.Game.TroopSpecs))
+		for troopID := range session.Game.TroopSpecs {
+			troopIDs = append(troopIDs, troopID)
+		}
+		sort.Strings(troopIDs) // Map order would make equal options pick differently each time
+		b.deploy(session, troopIDs, true)
+	}
+}
+
+// deploy lets the strategy choose among troopIDs and deploys the choice. With checkMana,
+// troops the bot can't afford yet are left out.
+func (b *Bot) deploy(session *GameSession, troopIDs []string, checkMana bool) {
+	playerIndex := session.playerIndexOf(b)
+	if playerIndex < 0 {
+		return
+	}
+
+	session.Game.Mutex.Lock()
+	options := botOptions(session.Game, playerIndex, troopIDs, checkMana)
+	session.Game.Mutex.Unlock()
+	if len(options) == 0 {
+		return
+	}
+
+	choice := b.strategy(options, b.rng)
+	if err := b.sm.HandleDeployTroop(b, choice.TroopID); err != nil {
+		log.Printf("[Session %s] %s could not deploy %s: %v", session.ID, b.name, choice.TroopID, err)
+	}
+}
+
+// botOptions describes the troops the player at playerIndex may deploy. The caller holds
+// the game mutex.
+func botOptions(g *game.Game, playerIndex int, troopIDs []string, checkMana bool) []BotOption {
+	player := g.Players[playerIndex]
+	target := g.NextTarget(playerIndex)
+	boost := game.LevelMultiplier(player.Level)
+
+	options := make([]BotOption, 0, len(troopIDs))
+	for _, troopID := range troopIDs {
+		spec, exists := g.TroopSpecs[troopID]
+		if !exists || (checkMana && spec.ManaCost > player.CurrentMana) {
+			continue
+		}
+		damage := 0
+		if target != nil {
+			damage = max(int(float64(spec.BaseATK)*boost)-target.DEF, 0)
+		}
+		options = append(options, BotOption{TroopID: troopID, ManaCost: spec.ManaCost, Damage: damage})
+	}
+	return options
+}
+
+// isBot reports whether a seated player is a bot
+func isBot(player Player) bool {
+	_, ok := player.(*Bot)
+	return ok
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+func TestBotStrategyPerDifficulty(t *testing.T) {
+	options := []BotOption{
+		{TroopID: "knight", ManaCost: 3, Damage: 20},
+		{TroopID: "giant", ManaCost: 5, Damage: 30},
+		{TroopID: "archer", ManaCost: 2, Damage: 30},
+		{TroopID: "goblin", ManaCost: 1, Damage: 0},
+	}
+
+	tests := []struct {
+		difficulty string
+		want       string
+	}{
+		{difficulty: network.BotDifficultyEasy, want: "goblin"}, // Cheapest
+		{difficulty: network.BotDifficultyHard, want: "archer"}, // Most damage, cheaper of the two
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.difficulty, func(t *testing.T) {
+			bot, err := NewBot(nil, tt.difficulty, 1)
+			if err != nil {
+				t.Fatalf("NewBot: %v", err)
+			}
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 10; i++ {
+				if got := bot.strategy(options, rng).TroopID; got != tt.want {
+					t.Fatalf("%s bot deployed %s, want %s", tt.difficulty, got, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run(network.BotDifficultyMedium, func(t *testing.T) {
+		bot, err := NewBot(nil, network.BotDifficultyMedium, 1)
+		if err != nil {
+			t.Fatalf("NewBot: %v", err)
+		}
+		rng := rand.New(rand.NewSource(1))
+		deployed := make(map[string]int)
+		for i := 0; i < 200; i++ {
+			deployed[bot.strategy(options, rng).TroopID]++
+		}
+		if len(deployed) != len(options) {
+			t.Errorf("medium bot deployed %v, want every troop now and then", deployed)
+		}
+	})
+}
+
+func TestNewBot(t *testing.T) {
+	bot, err := NewBot(nil, network.BotDifficultyHard, 0)
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	if bot.Name() != "Bot (hard)" || bot.level != 1 {
+		t.Errorf("bot is %q at level %d, want Bot (hard) at level 1", bot.Name(), bot.level)
+	}
+	if _, err := NewBot(nil, "impossible", 1); err == nil {
+		t.Error("bot of an unknown difficulty created")
+	}
+}
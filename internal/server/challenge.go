@@ -0,0 +1,192 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/game"
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// DefaultChallengeTimeout is how long a challenge waits for an answer, unless overridden before Start
+const DefaultChallengeTimeout = 60 * time.Second
+
+// ChallengeManager handles private games, where a player challenges another online player
+// directly instead of going through matchmaking
+type ChallengeManager struct {
+	server     *Server
+	challenges map[string]*challenge // Challenges waiting for an answer, by ID
+	mutex      sync.Mutex
+	counter    int // Counter for generating challenge IDs
+
+	Timeout time.Duration // How long a challenge waits for an answer
+}
+
+// challenge is an invitation from one player to another that has not been answered yet
+type challenge struct {
+	id        string
+	from      *Client
+	to        *Client
+	mode      game.GameMode
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// NewChallengeManager creates a new challenge manager
+func NewChallengeManager(server *Server) *ChallengeManager {
+	return &ChallengeManager{
+		server:     server,
+		challenges: make(map[string]*challenge),
+		Timeout:    server.ChallengeTimeout,
+	}
+}
+
+// Challenge sends a challenge from client to the online player username. Problems are
+// reported to the client; the returned error is only for failed sends.
+func (cm *ChallengeManager) Challenge(client *Client, username string, mode game.GameMode) error {
+	username, err := models.CanonicalUsername(username)
+	if err != nil {
+		return sendError(client, 400, err.Error())
+	}
+	if username == client.Username {
+		return sendError(client, 400, "You cannot challenge yourself")
+	}
+	if client.CurrentGameID() != "" {
+		return sendError(client, 409, "You are already in a game")
+	}
+
+	target := cm.server.activeClient(username)
+	if target == nil {
+		return sendError(client, 404, username+" is not online")
+	}
+	if target.CurrentGameID() != "" {
+		return sendError(client, 409, username+" is in a game right now")
+	}
+	if !target.HasFeature(network.FeatureChallenge) {
+		return sendError(client, 400, username+"'s client does not support challenges")
+	}
+	if mode == game.GameModeEnhanced && !target.HasFeature(network.FeatureEnhancedMode) {
+		return sendError(client, 400, username+"'s client does not support enhanced mode")
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	// One open challenge per player keeps answers unambiguous
+	for _, ch := range cm.challenges {
+		if ch.from.ID == client.ID {
+			return sendError(client, 409, fmt.Sprintf("You already challenged %s. Wait for their answer first.", ch.to.Username))
+		}
+	}
+
+	cm.counter++
+	ch := &challenge{
+		id:        fmt.Sprintf("challenge-%d", cm.counter),
+		from:      client,
+		to:        target,
+		mode:      mode,
+		expiresAt: time.Now().Add(cm.Timeout),
+	}
+	ch.timer = time.AfterFunc(cm.Timeout, func() { cm.expire(ch.id) })
+	cm.challenges[ch.id] = ch
+	log.Printf("Challenge %s: %s challenged %s to a %s game", ch.id, client.Username, target.Username, mode)
+
+	rating := models.DefaultRating
+	if playerData, err := cm.server.PlayerStore.LoadPlayer(client.Username); err != nil {
+		log.Printf("Error loading rating of %s for challenge %s: %v", client.Username, ch.id, err)
+	} else if playerData != nil {
+		rating = playerData.CurrentRating()
+	}
+	if err := target.Codec.Send(network.MessageTypeChallengeReceived, &network.ChallengeReceivedPayload{
+		ChallengeID:  ch.id,
+		FromUsername: client.Username,
+		FromRating:   rating,
+		Mode:         modeName(mode),
+		ExpiresAt:    ch.expiresAt,
+	}); err != nil {
+		log.Printf("Error sending challenge %s to client %s: %v", ch.id, target.ID, err)
+	}
+
+	return sendEvent(client, fmt.Sprintf("You challenged %s to a %s game. Waiting for their answer...", target.Username, modeName(mode)))
+}
+
+// Respond accepts or declines a challenge sent to client. Accepting starts the game at once,
+// taking both players out of the matchmaking queue.
+func (cm *ChallengeManager) Respond(client *Client, challengeID string, accept bool) error {
+	cm.mutex.Lock()
+	ch, exists := cm.challenges[challengeID]
+	if !exists || ch.to.ID != client.ID {
+		cm.mutex.Unlock()
+		return sendError(client, 404, "No such challenge is waiting for your answer")
+	}
+	cm.removeLocked(ch)
+	cm.mutex.Unlock()
+
+	if !accept {
+		log.Printf("Challenge %s declined by %s", ch.id, client.Username)
+		sendEvent(ch.from, fmt.Sprintf("%s declined your challenge.", client.Username))
+		return sendEvent(client, fmt.Sprintf("You declined the challenge from %s.", ch.from.Username))
+	}
+
+	if client.CurrentGameID() != "" {
+		sendEvent(ch.from, fmt.Sprintf("%s could not accept your challenge.", client.Username))
+		return sendError(client, 409, "You are already in a game")
+	}
+	if ch.from.CurrentGameID() != "" || cm.server.activeClient(ch.from.Username) != ch.from {
+		sendEvent(ch.from, fmt.Sprintf("%s accepted your challenge, but you are no longer available.", client.Username))
+		return sendError(client, 409, ch.from.Username+" is no longer available")
+	}
+
+	// A private game replaces any public matchmaking the players were waiting for. Only the
+	// two of them leave it, so anyone they were matched with stays queued. StartPrivateGame
+	// checks again that both are free, as they may have started another game since.
+	log.Printf("Challenge %s accepted", ch.id)
+	if err := cm.server.matchmaker.StartPrivateGame(ch.from, client, ch.mode); err != nil {
+		log.Printf("Challenge %s could not start: %v", ch.id, err)
+		sendEvent(ch.from, fmt.Sprintf("%s accepted your challenge, but the game could not start: %v.", client.Username, err))
+		return sendError(client, 409, fmt.Sprintf("The game could not start: %v", err))
+	}
+	return nil
+}
+
+// RemoveClient withdraws every challenge the client sent or received, telling the other side
+func (cm *ChallengeManager) RemoveClient(client *Client) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	for _, ch := range cm.challenges {
+		switch client.ID {
+		case ch.from.ID:
+			cm.removeLocked(ch)
+			sendEvent(ch.to, fmt.Sprintf("The challenge from %s was withdrawn.", ch.from.Username))
+		case ch.to.ID:
+			cm.removeLocked(ch)
+			sendEvent(ch.from, fmt.Sprintf("%s went offline. Your challenge was cancelled.", ch.to.Username))
+		}
+	}
+}
+
+// expire drops an unanswered challenge once its time is up
+func (cm *ChallengeManager) expire(challengeID string) {
+	cm.mutex.Lock()
+	ch, exists := cm.challenges[challengeID]
+	if !exists {
+		cm.mutex.Unlock()
+		return
+	}
+	cm.removeLocked(ch)
+	cm.mutex.Unlock()
+
+	log.Printf("Challenge %s from %s to %s expired", ch.id, ch.from.Username, ch.to.Username)
+	sendEvent(ch.from, fmt.Sprintf("%s did not answer your challenge in time.", ch.to.Username))
+	sendEvent(ch.to, fmt.Sprintf("The challenge from %s expired.", ch.from.Username))
+}
+
+// removeLocked forgets a challenge and stops its expiry timer. The caller holds mutex.
+func (cm *ChallengeManager) removeLocked(ch *challenge) {
+	ch.timer.Stop()
+	delete(cm.challenges, ch.id)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// sendChallenge sends a challenge from one client to another and returns it as received
+func sendChallenge(t *testing.T, from, to *testClient, mode string) *network.ChallengeReceivedPayload {
+	t.Helper()
+	from.send(network.MessageTypeChallenge, &network.ChallengePayload{Username: to.username, Mode: mode})
+	from.expectEvent("You challenged " + to.username)
+	received := expect[network.ChallengeReceivedPayload](to, network.MessageTypeChallengeReceived)
+	if received.FromUsername != from.username {
+		t.Fatalf("%s received a challenge from %s, want %s", to.username, received.FromUsername, from.username)
+	}
+	return received
+}
+
+func TestChallengeAccepted(t *testing.T) {
+	s := newTestServer(t, nil)
+	addAccount(t, s, "alice", 1300)
+	addAccount(t, s, "bob", models.DefaultRating)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+
+	received := sendChallenge(t, alice, bob, network.GameModeEnhanced)
+	if received.FromRating != 1300 || received.Mode != network.GameModeEnhanced {
+		t.Errorf("challenge received = %+v, want an enhanced game against alice rated 1300", received)
+	}
+
+	bob.send(network.MessageTypeChallengeResponse, &network.ChallengeResponsePayload{ChallengeID: received.ChallengeID, Accept: true})
+	aliceStart := expect[network.GameStartPayload](alice, network.MessageTypeGameStart)
+	bobStart := expect[network.GameStartPayload](bob, network.MessageTypeGameStart)
+	if aliceStart.GameID != bobStart.GameID || aliceStart.OpponentUsername != "bob" || aliceStart.GameMode != network.GameModeEnhanced {
+		t.Errorf("game starts %+v and %+v, want one enhanced game between alice and bob", aliceStart, bobStart)
+	}
+
+	// An answered challenge is gone
+	bob.send(network.MessageTypeChallengeResponse, &network.ChallengeResponsePayload{ChallengeID: received.ChallengeID, Accept: true})
+	bob.expectError(404)
+}
+
+func TestChallengeDeclined(t *testing.T) {
+	s := newTestServer(t, nil)
+	addAccount(t, s, "alice", models.DefaultRating)
+	addAccount(t, s, "bob", models.DefaultRating)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+
+	received := sendChallenge(t, alice, bob, "")
+	alice.send(network.MessageTypeChallenge, &network.ChallengePayload{Username: "bob"})
+	alice.expectError(409)
+
+	bob.send(network.MessageTypeChallengeResponse, &network.ChallengeResponsePayload{ChallengeID: received.ChallengeID})
+	bob.expectEvent("You declined the challenge from alice")
+	alice.expectEvent("bob declined your challenge")
+
+	// Alice may challenge again once answered
+	sendChallenge(t, alice, bob, "")
+}
+
+func TestChallengeExpires(t *testing.T) {
+	s := newTestServer(t, func(s *Server) { s.ChallengeTimeout = 100 * time.Millisecond })
+	addAccount(t, s, "alice", models.DefaultRating)
+	addAccount(t, s, "bob", models.DefaultRating)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+
+	received := sendChallenge(t, alice, bob, "")
+	alice.expectEvent("bob did not answer your challenge in time")
+	bob.expectEvent("The challenge from alice expired")
+
+	bob.send(network.MessageTypeChallengeResponse, &network.ChallengeResponsePayload{ChallengeID: received.ChallengeID, Accept: true})
+	bob.expectError(404)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+// DefaultLeaderboardMaxAge is how long a computed leaderboard is served before it is rebuilt
+// from the player store, unless a finished game invalidates it sooner
+const DefaultLeaderboardMaxAge = time.Minute
+
+// Leaderboard ranks all players in the player store. The rankings are computed on demand
+// and cached until they are invalidated or grow older than MaxAge.
+type Leaderboard struct {
+	store  persistence.PlayerStore
+	MaxAge time.Duration
+
+	mutex    sync.Mutex
+	rankings map[string][]*models.PlayerData // Sorted players per sort order
+	builtAt  time.Time
+}
+
+// NewLeaderboard creates a leaderboard over the players in store
+func NewLeaderboard(store persistence.PlayerStore) *Leaderboard {
+	return &Leaderboard{
+		store:  store,
+		MaxAge: DefaultLeaderboardMaxAge,
+	}
+}
+
+// Invalidate makes the next request rebuild the rankings, e.g. after ratings changed
+func (lb *Leaderboard) Invalidate() {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	lb.rankings = nil
+}
+
+// Page returns one page of the players ranked by sortBy (a network.LeaderboardBy constant).
+// page is 1-based; pages past the end come back empty.
+func (lb *Leaderboard) Page(sortBy string, page, pageSize int) (*network.LeaderboardPayload, error) {
+	if sortBy == "" {
+		sortBy = network.LeaderboardByRating
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = network.DefaultLeaderboardPageSize
+	}
+
+	ranking, err := lb.ranking(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &network.LeaderboardPayload{
+		SortBy:       sortBy,
+		Page:         page,
+		PageSize:     pageSize,
+		TotalPages:   (len(ranking) + pageSize - 1) / pageSize,
+		TotalPlayers: len(ranking),
+		Entries:      make([]network.LeaderboardEntry, 0, pageSize),
+	}
+	for i := (page - 1) * pageSize; i < len(ranking) && i < page*pageSize; i++ {
+		playerData := ranking[i]
+		payload.Entries = append(payload.Entries, network.LeaderboardEntry{
+			Rank:     i + 1,
+			Username: playerData.Username,
+			Rating:   playerData.CurrentRating(),
+			Level:    playerData.Level,
+			EXP:      playerData.EXP,
+			Wins:     playerData.Stats.Wins,
+			Losses:   playerData.Stats.Losses,
+			Draws:    playerData.Stats.Draws,
+		})
+	}
+	return payload, nil
+}
+
+// ranking returns the players sorted by sortBy, rebuilding the rankings if they are stale
+func (lb *Leaderboard) ranking(sortBy string) ([]*models.PlayerData, error) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if lb.rankings == nil || time.Since(lb.builtAt) > lb.MaxAge {
+		players, err := lb.store.ListPlayers()
+		if err != nil {
+			return nil, err
+		}
+		lb.rankings = make(map[string][]*models.PlayerData)
+		lb.builtAt = time.Now()
+		lb.rankings[network.LeaderboardByRating] = rankPlayers(players, func(a, b *models.PlayerData) int {
+			return a.CurrentRating() - b.CurrentRating()
+		})
+		lb.rankings[network.LeaderboardByLevel] = rankPlayers(players, func(a, b *models.PlayerData) int {
+			if a.Level != b.Level {
+				return a.Level - b.Level
+			}
+			return a.EXP - b.EXP
+		})
+		lb.rankings[network.LeaderboardByWins] = rankPlayers(players, func(a, b *models.PlayerData) int {
+			if a.Stats.Wins != b.Stats.Wins {
+				return a.Stats.Wins - b.Stats.Wins
+			}
+			return a.CurrentRating() - b.CurrentRating()
+		})
+	}
+	return lb.rankings[sortBy], nil
+}
+
+// rankPlayers returns a copy of players sorted best first by compare (positive when a ranks
+// above b), with ties in username order
+func rankPlayers(players []*models.PlayerData, compare func(a, b *models.PlayerData) int) []*models.PlayerData {
+	ranked := append([]*models.PlayerData(nil), players...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if c := compare(ranked[i], ranked[j]); c != 0 {
+			return c > 0
+		}
+		return ranked[i].Username < ranked[j].Username
+	})
+	return ranked
+}
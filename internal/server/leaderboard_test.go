@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+// newTestLeaderboard returns a leaderboard over five players with ties in every sort order
+func newTestLeaderboard(t *testing.T) (*Leaderboard, persistence.PlayerStore) {
+	t.Helper()
+	store := persistence.NewMemoryStore()
+	for _, playerData := range []*models.PlayerData{
+		{Username: "alice", Rating: 1300, Level: 2, EXP: 50, Stats: models.PlayerStats{Wins: 3}},
+		{Username: "bob", Rating: 1250, Level: 3, EXP: 10, Stats: models.PlayerStats{Wins: 3}},
+		{Username: "carol", Level: 2, EXP: 80, Stats: models.PlayerStats{Wins: 5}}, // Saved before ratings existed
+		{Username: "dave", Rating: 1300, Level: 1},
+		{Username: "erin", Rating: 1100, Level: 3, EXP: 10, Stats: models.PlayerStats{Wins: 1}},
+	} {
+		if err := store.SavePlayer(playerData); err != nil {
+			t.Fatalf("SavePlayer: %v", err)
+		}
+	}
+	return NewLeaderboard(store), store
+}
+
+// entryNames returns the usernames on a leaderboard page, with their ranks
+func entryNames(page *network.LeaderboardPayload) string {
+	names := ""
+	for _, entry := range page.Entries {
+		names += fmt.Sprintf("%d:%s ", entry.Rank, entry.Username)
+	}
+	return names
+}
+
+func TestLeaderboardSortOrders(t *testing.T) {
+	tests := []struct {
+		sortBy string
+		want   string
+	}{
+		{sortBy: "", want: "1:alice 2:dave 3:bob 4:carol 5:erin "},
+		{sortBy: network.LeaderboardByRating, want: "1:alice 2:dave 3:bob 4:carol 5:erin "},
+		{sortBy: network.LeaderboardByLevel, want: "1:bob 2:erin 3:carol 4:alice 5:dave "},
+		{sortBy: network.LeaderboardByWins, want: "1:carol 2:alice 3:bob 4:erin 5:dave "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			lb, _ := newTestLeaderboard(t)
+			page, err := lb.Page(tt.sortBy, 1, 10)
+			if err != nil {
+				t.Fatalf("Page: %v", err)
+			}
+			if got := entryNames(page); got != tt.want {
+				t.Errorf("ranking = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardPaging(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     int
+		pageSize int
+		want     string
+	}{
+		{name: "first page", page: 1, pageSize: 2, want: "1:alice 2:dave "},
+		{name: "page 0 is the first page", page: 0, pageSize: 2, want: "1:alice 2:dave "},
+		{name: "middle page", page: 2, pageSize: 2, want: "3:bob 4:carol "},
+		{name: "last page", page: 3, pageSize: 2, want: "5:erin "},
+		{name: "past the end", page: 4, pageSize: 2, want: ""},
+		{name: "default page size", page: 1, pageSize: 0, want: "1:alice 2:dave 3:bob 4:carol 5:erin "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb, _ := newTestLeaderboard(t)
+			page, err := lb.Page(network.LeaderboardByRating, tt.page, tt.pageSize)
+			if err != nil {
+				t.Fatalf("Page: %v", err)
+			}
+			if got := entryNames(page); got != tt.want {
+				t.Errorf("page = %s, want %s", got, tt.want)
+			}
+			if page.TotalPlayers != 5 || page.TotalPages != (5+page.PageSize-1)/page.PageSize {
+				t.Errorf("%d players on %d pages of %d", page.TotalPlayers, page.TotalPages, page.PageSize)
+			}
+		})
+	}
+}
+
+func TestLeaderboardRebuildsWhenInvalidated(t *testing.T) {
+	lb, store := newTestLeaderboard(t)
+	if _, err := lb.Page(network.LeaderboardByRating, 1, 1); err != nil {
+		t.Fatalf("Page: %v", err)
+	}
+	if err := store.SavePlayer(&models.PlayerData{Username: "frank", Rating: 2000, Level: 1}); err != nil {
+		t.Fatalf("SavePlayer: %v", err)
+	}
+
+	page, _ := lb.Page(network.LeaderboardByRating, 1, 1)
+	if got := entryNames(page); got != "1:alice " {
+		t.Errorf("cached ranking = %s, want the ranking from before frank joined", got)
+	}
+
+	lb.Invalidate()
+	page, _ = lb.Page(network.LeaderboardByRating, 1, 1)
+	if got := entryNames(page); got != "1:frank " || page.TotalPlayers != 6 {
+		t.Errorf("rebuilt ranking = %s of %d players, want frank first of 6", got, page.TotalPlayers)
+	}
+}
@@ -1,28 +1,114 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/NP-Dat/net-centric-project/internal/game"
+	"github.com/NP-Dat/net-centric-project/internal/models"
 	"github.com/NP-Dat/net-centric-project/internal/network"
 )
 
+// Default rating window settings, used unless overridden before Start
+const (
+	DefaultRatingWindow       = 100 // Largest rating gap accepted as soon as a player joins
+	DefaultRatingWindowGrowth = 10  // Rating points the window widens per second of waiting
+)
+
+// Default queue settings, used unless overridden before Start
+const (
+	DefaultReadyCheckTimeout   = 15 * time.Second
+	DefaultQueueStatusInterval = 10 * time.Second
+)
+
+// waitSamples is how many recent matches per mode the estimated wait is averaged over
+const waitSamples = 20
+
+// ErrUnknownMatch is returned when answering a ready check that doesn't exist (any more)
+var ErrUnknownMatch = errors.New("no such match is waiting for your answer")
+
+// ErrPlayerBusy is returned when starting a game for a player who is already in one
+var ErrPlayerBusy = errors.New("already in a game")
+
 // MatchmakingManager handles matchmaking functionality
 type MatchmakingManager struct {
-	server      *Server
-	waitingPool []*Client // Pool of clients waiting for a match
-	poolMutex   sync.Mutex
-	gameCounter int // Counter for generating game IDs
+	server       *Server
+	waitingPools map[game.GameMode][]*queueEntry // Clients waiting for a match per mode, in the order they joined
+	poolMutex    sync.Mutex
+	gameCounter  int    // Counter for generating game IDs
+	gameIDPrefix string // Unique per server run, so game IDs and their replay files don't repeat after a restart
+
+	pendingMatches map[string]*pendingMatch          // Matches waiting for both players to accept
+	matchCounter   int                               // Counter for generating match IDs
+	recentWaits    map[game.GameMode][]time.Duration // How long the latest matched players waited
+
+	RatingWindow        int           // Largest rating gap accepted as soon as a player joins
+	RatingWindowGrowth  int           // Rating points the window widens per second of waiting
+	ReadyCheckTimeout   time.Duration // Time both players have to accept a match, 0 starts games at once
+	QueueStatusInterval time.Duration // How often waiting players are sent their queue status
+	BotFallbackWait     time.Duration // Wait after which a player is given a bot opponent, 0 never
+}
+
+// queueEntry is a client waiting for a match, with the rating it is matched by
+type queueEntry struct {
+	client       *Client
+	mode         game.GameMode
+	rating       int
+	joinedAt     time.Time
+	maxRatingGap int       // From the client's preferences, 0 if unlimited
+	lastStatusAt time.Time // When the client was last sent its queue status
+}
+
+// pendingMatch is a pair of players who were matched and must both accept before their
+// game is created
+type pendingMatch struct {
+	id       string
+	mode     game.GameMode
+	entries  [2]*queueEntry
+	accepted [2]bool
+	declined [2]bool // Declined, left or let the ready check expire; unanswered players are neither
+	deadline time.Time
+}
+
+// indexOf returns the position of the client in the match, or -1
+func (pm *pendingMatch) indexOf(clientID string) int {
+	for i, entry := range pm.entries {
+		if entry.client.ID == clientID {
+			return i
+		}
+	}
+	return -1
+}
+
+// ratingWindow returns the largest rating gap the entry accepts after waiting until now
+func (mm *MatchmakingManager) ratingWindow(entry *queueEntry, now time.Time) int {
+	waited := int(now.Sub(entry.joinedAt) / time.Second)
+	window := mm.RatingWindow + waited*mm.RatingWindowGrowth
+	if entry.maxRatingGap > 0 && window > entry.maxRatingGap {
+		window = entry.maxRatingGap
+	}
+	return window
 }
 
 // NewMatchmakingManager creates a new matchmaking manager
 func NewMatchmakingManager(server *Server) *MatchmakingManager {
 	mm := &MatchmakingManager{
-		server:      server,
-		waitingPool: make([]*Client, 0),
+		server:              server,
+		waitingPools:        make(map[game.GameMode][]*queueEntry),
+		gameIDPrefix:        "game-" + strconv.FormatInt(time.Now().Unix(), 36),
+		pendingMatches:      make(map[string]*pendingMatch),
+		recentWaits:         make(map[game.GameMode][]time.Duration),
+		RatingWindow:        server.RatingWindow,
+		RatingWindowGrowth:  server.RatingWindowGrowth,
+		ReadyCheckTimeout:   server.ReadyCheckTimeout,
+		QueueStatusInterval: server.QueueStatusInterval,
+		BotFallbackWait:     server.BotFallbackWait,
 	}
 
 	// Start the matchmaking process in a separate goroutine
@@ -31,44 +117,157 @@ func NewMatchmakingManager(server *Server) *MatchmakingManager {
 	return mm
 }
 
-// AddToWaitingPool adds a client to the waiting pool for matchmaking
-func (mm *MatchmakingManager) AddToWaitingPool(client *Client) {
+// AddToWaitingPool adds a client to the waiting pool of a game mode. A client waiting in
+// another mode's pool is moved.
+func (mm *MatchmakingManager) AddToWaitingPool(client *Client, mode game.GameMode, preferences *network.QueuePreferences) {
 	mm.poolMutex.Lock()
 	defer mm.poolMutex.Unlock()
 
+	// Games only start under poolMutex, so this holds until the client is queued
+	if client.CurrentGameID() != "" {
+		sendError(client, 409, "You are already in a game")
+		return
+	}
+
+	// A matched player has to answer the ready check first
+	if mm.pendingMatchOf(client.ID) != nil {
+		sendEvent(client, "You have a match waiting for your answer. Accept or decline it first.")
+		return
+	}
+
 	// Check if client is already in the pool
-	for _, c := range mm.waitingPool {
-		if c.ID == client.ID {
+	for _, entry := range mm.waitingPools[mode] {
+		if entry.client.ID == client.ID {
 			return // Client already in pool
 		}
 	}
+	mm.removeLocked(client.ID)
 
-	// Add client to the pool
-	mm.waitingPool = append(mm.waitingPool, client)
+	// Add client to the pool with their current rating
+	rating := models.DefaultRating
+	playerData, err := mm.server.PlayerStore.LoadPlayer(client.Username)
+	if err != nil {
+		log.Printf("Error loading rating of %s, matching them at %d: %v", client.Username, rating, err)
+	} else if playerData != nil {
+		rating = playerData.CurrentRating()
+	}
+	entry := &queueEntry{client: client, mode: mode, rating: rating, joinedAt: time.Now()}
+	if preferences != nil {
+		entry.maxRatingGap = preferences.MaxRatingGap
+	}
+	mm.waitingPools[mode] = append(mm.waitingPools[mode], entry)
 
 	// Inform the client they've been added to the matchmaking queue
-	event := &network.GameEventPayload{
-		Message: "You have been added to the matchmaking queue. Waiting for opponent...",
-		Time:    time.Now(),
+	sendEvent(client, fmt.Sprintf("You have been added to the %s matchmaking queue. Waiting for opponent...", modeName(mode)))
+	mm.sendStatusLocked(entry, time.Now())
+}
+
+// RemoveFromWaitingPool removes a client from whichever waiting pool it is in. A client
+// in a ready check declines the match, sending their opponent back to the queue. It reports
+// whether the client was queued at all.
+func (mm *MatchmakingManager) RemoveFromWaitingPool(clientID string) bool {
+	mm.poolMutex.Lock()
+	defer mm.poolMutex.Unlock()
+
+	if pm := mm.pendingMatchOf(clientID); pm != nil {
+		pm.declined[pm.indexOf(clientID)] = true
+		mm.cancelMatchLocked(pm, "")
+		return true
 	}
+	return mm.removeLocked(clientID)
+}
 
-	if err := client.Codec.Send(network.MessageTypeGameEvent, event); err != nil {
-		log.Printf("Error sending matchmaking event to client %s: %v", client.ID, err)
+// removeLocked removes a client from the waiting pools. The caller holds poolMutex.
+func (mm *MatchmakingManager) removeLocked(clientID string) bool {
+	for mode, pool := range mm.waitingPools {
+		for i, entry := range pool {
+			if entry.client.ID == clientID {
+				mm.waitingPools[mode] = append(pool[:i], pool[i+1:]...)
+				return true
+			}
+		}
 	}
+	return false
 }
 
-// RemoveFromWaitingPool removes a client from the waiting pool
-func (mm *MatchmakingManager) RemoveFromWaitingPool(clientID string) {
+// QueueStatus returns the client's place in the matchmaking queue
+func (mm *MatchmakingManager) QueueStatus(clientID string) *network.QueueStatusPayload {
 	mm.poolMutex.Lock()
 	defer mm.poolMutex.Unlock()
 
-	// Find and remove the client from the pool
-	for i, client := range mm.waitingPool {
-		if client.ID == clientID {
-			mm.waitingPool = append(mm.waitingPool[:i], mm.waitingPool[i+1:]...)
+	now := time.Now()
+	for _, pool := range mm.waitingPools {
+		for _, entry := range pool {
+			if entry.client.ID == clientID {
+				return mm.statusLocked(entry, now)
+			}
+		}
+	}
+	return &network.QueueStatusPayload{InQueue: false}
+}
+
+// statusLocked builds the queue status of a waiting client. The caller holds poolMutex.
+func (mm *MatchmakingManager) statusLocked(entry *queueEntry, now time.Time) *network.QueueStatusPayload {
+	pool := mm.waitingPools[entry.mode]
+	position := 0
+	for i, waiting := range pool {
+		if waiting == entry {
+			position = i + 1
 			break
 		}
 	}
+
+	waited := now.Sub(entry.joinedAt)
+	estimate := -1
+	if average, ok := mm.averageWait(entry.mode); ok {
+		estimate = 0
+		if average > waited {
+			estimate = int((average - waited).Round(time.Second) / time.Second)
+		}
+	}
+
+	return &network.QueueStatusPayload{
+		InQueue:              true,
+		Mode:                 modeName(entry.mode),
+		Position:             position,
+		QueueSize:            len(pool),
+		WaitedSeconds:        int(waited / time.Second),
+		EstimatedWaitSeconds: estimate,
+	}
+}
+
+// sendStatusLocked sends a waiting client its queue status, if its client supports it. The
+// caller holds poolMutex.
+func (mm *MatchmakingManager) sendStatusLocked(entry *queueEntry, now time.Time) {
+	entry.lastStatusAt = now
+	if !entry.client.HasFeature(network.FeatureQueueStatus) {
+		return
+	}
+	if err := entry.client.Codec.Send(network.MessageTypeQueueStatus, mm.statusLocked(entry, now)); err != nil {
+		log.Printf("Error sending queue status to client %s: %v", entry.client.ID, err)
+	}
+}
+
+// averageWait returns the average wait of the latest players matched in a mode
+func (mm *MatchmakingManager) averageWait(mode game.GameMode) (time.Duration, bool) {
+	waits := mm.recentWaits[mode]
+	if len(waits) == 0 {
+		return 0, false
+	}
+	var total time.Duration
+	for _, wait := range waits {
+		total += wait
+	}
+	return total / time.Duration(len(waits)), true
+}
+
+// recordWait adds a matched player's wait to the samples of their mode
+func (mm *MatchmakingManager) recordWait(mode game.GameMode, wait time.Duration) {
+	waits := append(mm.recentWaits[mode], wait)
+	if len(waits) > waitSamples {
+		waits = waits[len(waits)-waitSamples:]
+	}
+	mm.recentWaits[mode] = waits
 }
 
 // matchmakingLoop continuously checks for possible matches
@@ -83,36 +282,328 @@ func (mm *MatchmakingManager) matchmakingLoop() {
 	}
 }
 
-// tryMatchmaking attempts to create matches between waiting clients
+// tryMatchmaking attempts to create matches between waiting clients of each mode, expires
+// unanswered ready checks and keeps waiting clients informed
 func (mm *MatchmakingManager) tryMatchmaking() error {
 	mm.poolMutex.Lock()
 	defer mm.poolMutex.Unlock()
 
-	// For Sprint 1, simply match the first two waiting clients
-	if len(mm.waitingPool) >= 2 {
-		player1 := mm.waitingPool[0]
-		player2 := mm.waitingPool[1]
+	now := time.Now()
+	for _, pm := range mm.pendingMatches {
+		if now.After(pm.deadline) {
+			// Not answering in time counts as declining
+			for i := range pm.entries {
+				pm.declined[i] = !pm.accepted[i]
+			}
+			mm.cancelMatchLocked(pm, "You did not accept the match in time and were removed from the matchmaking queue.")
+		}
+	}
 
-		// Remove the matched players from the pool
-		mm.waitingPool = mm.waitingPool[2:]
+	for mode := range mm.waitingPools {
+		mm.matchPool(mode)
+		if mm.BotFallbackWait > 0 {
+			mm.fallBackToBots(mode, now)
+		}
+	}
+
+	if mm.QueueStatusInterval > 0 {
+		for _, pool := range mm.waitingPools {
+			for _, entry := range pool {
+				if now.Sub(entry.lastStatusAt) >= mm.QueueStatusInterval {
+					mm.sendStatusLocked(entry, now)
+				}
+			}
+		}
+	}
+	return nil
+}
 
-		// Create a new game for these players
-		mm.gameCounter++
-		gameID := fmt.Sprintf("game-%d", mm.gameCounter)
+// matchPool pairs the clients waiting in one mode's pool. The caller holds poolMutex.
+func (mm *MatchmakingManager) matchPool(mode game.GameMode) {
+	pool := mm.waitingPools[mode]
 
-		// Start game using session manager
-		mm.startGame(player1, player2, gameID)
+	// Longest waiting players pick first. Each is paired with the closest rated player
+	// within their rating window, which widens the longer they wait.
+	now := time.Now()
+	matched := make(map[*queueEntry]bool)
+	for i, entry := range pool {
+		if matched[entry] {
+			continue
+		}
+
+		window := mm.ratingWindow(entry, now)
+		var opponent *queueEntry
+		for _, candidate := range pool[i+1:] {
+			if matched[candidate] {
+				continue
+			}
+			gap := ratingGap(entry, candidate)
+			if candidate.maxRatingGap > 0 && gap > candidate.maxRatingGap {
+				continue
+			}
+			if gap <= window && (opponent == nil || gap < ratingGap(entry, opponent)) {
+				opponent = candidate
+			}
+		}
+		if opponent == nil {
+			continue
+		}
+		matched[entry] = true
+		matched[opponent] = true
+
+		log.Printf("Matched %s (%d) with %s (%d) in %s mode after %v, window %d", entry.client.Username, entry.rating,
+			opponent.client.Username, opponent.rating, mode, now.Sub(entry.joinedAt).Round(time.Second), window)
+		mm.recordWait(mode, now.Sub(entry.joinedAt))
+		mm.recordWait(mode, now.Sub(opponent.joinedAt))
+		mm.proposeMatch(entry, opponent, now)
 	}
 
+	// Remove the matched players from the pool
+	if len(matched) > 0 {
+		remaining := make([]*queueEntry, 0, len(pool)-len(matched))
+		for _, entry := range pool {
+			if !matched[entry] {
+				remaining = append(remaining, entry)
+			}
+		}
+		mm.waitingPools[mode] = remaining
+	}
+}
+
+// fallBackToBots gives players who waited BotFallbackWait in vain a bot opponent of similar
+// strength. The caller holds poolMutex.
+func (mm *MatchmakingManager) fallBackToBots(mode game.GameMode, now time.Time) {
+	pool := mm.waitingPools[mode]
+	remaining := pool[:0]
+	for _, entry := range pool {
+		if now.Sub(entry.joinedAt) < mm.BotFallbackWait {
+			remaining = append(remaining, entry)
+			continue
+		}
+		log.Printf("No opponent for %s in %s mode after %v, starting a bot game", entry.client.Username, mode, now.Sub(entry.joinedAt).Round(time.Second))
+		sendEvent(entry.client, "No opponent was found in time. You will play against a bot instead.")
+		mm.startBotGameLocked(entry.client, mode, network.BotDifficultyMedium)
+	}
+	mm.waitingPools[mode] = remaining
+}
+
+// StartBotGame starts a game between the client and a bot of the given difficulty right
+// away, taking the client out of the matchmaking queue
+func (mm *MatchmakingManager) StartBotGame(client *Client, mode game.GameMode, difficulty string) {
+	mm.poolMutex.Lock()
+	defer mm.poolMutex.Unlock()
+
+	// Games only start under poolMutex, so this holds until the bot game starts
+	if client.CurrentGameID() != "" {
+		sendError(client, 409, "You are already in a game")
+		return
+	}
+
+	// A matched player has to answer the ready check first
+	if mm.pendingMatchOf(client.ID) != nil {
+		sendEvent(client, "You have a match waiting for your answer. Accept or decline it first.")
+		return
+	}
+	mm.removeLocked(client.ID)
+	mm.startBotGameLocked(client, mode, difficulty)
+}
+
+// StartPrivateGame starts a game between two players who agreed to play each other outside
+// matchmaking. Both leave the queue first; the opponents of any ready check they were in go
+// back to the queue. It fails with ErrPlayerBusy if either player is already in a game.
+func (mm *MatchmakingManager) StartPrivateGame(player1, player2 *Client, mode game.GameMode) error {
+	mm.poolMutex.Lock()
+	defer mm.poolMutex.Unlock()
+
+	// Games only start under poolMutex, so neither player can be seated elsewhere meanwhile
+	for _, player := range []*Client{player1, player2} {
+		if player.CurrentGameID() != "" {
+			return fmt.Errorf("%s is %w", player.Username, ErrPlayerBusy)
+		}
+	}
+
+	for _, player := range []*Client{player1, player2} {
+		if pm := mm.pendingMatchOf(player.ID); pm != nil {
+			pm.declined[pm.indexOf(player.ID)] = true
+			mm.cancelMatchLocked(pm, "")
+		}
+		mm.removeLocked(player.ID)
+	}
+
+	gameID := mm.nextGameIDLocked()
+	log.Printf("Starting private game %s between %s and %s", gameID, player1.Username, player2.Username)
+	mm.startGame(player1, player2, gameID, mode)
 	return nil
 }
 
+// startBotGameLocked seats the client against a bot playing at the client's level. The
+// caller holds poolMutex.
+func (mm *MatchmakingManager) startBotGameLocked(client *Client, mode game.GameMode, difficulty string) {
+	level := 1
+	playerData, err := mm.server.PlayerStore.LoadPlayer(client.Username)
+	if err != nil {
+		log.Printf("Error loading level of %s, their bot plays at level 1: %v", client.Username, err)
+	} else if playerData != nil {
+		level = playerData.Level
+	}
+
+	bot, err := NewBot(mm.server.sessionManager, difficulty, level)
+	if err != nil {
+		log.Printf("Error creating bot for %s: %v", client.Username, err)
+		sendEvent(client, fmt.Sprintf("Failed to start game: %v", err))
+		return
+	}
+
+	gameID := mm.nextGameIDLocked()
+	mm.startGame(client, bot, gameID, mode)
+}
+
+// proposeMatch sends both players a ready check. Players whose client doesn't support ready
+// checks accept automatically; if both do, or ready checks are off, the game starts at once.
+// The caller holds poolMutex.
+func (mm *MatchmakingManager) proposeMatch(a, b *queueEntry, now time.Time) {
+	mm.matchCounter++
+	pm := &pendingMatch{
+		id:       fmt.Sprintf("match-%d", mm.matchCounter),
+		mode:     a.mode,
+		entries:  [2]*queueEntry{a, b},
+		deadline: now.Add(mm.ReadyCheckTimeout),
+	}
+
+	for i, entry := range pm.entries {
+		if mm.ReadyCheckTimeout <= 0 || !entry.client.HasFeature(network.FeatureReadyCheck) {
+			pm.accepted[i] = true
+			continue
+		}
+		opponent := pm.entries[1-i]
+		err := entry.client.Codec.Send(network.MessageTypeMatchFound, &network.MatchFoundPayload{
+			MatchID:          pm.id,
+			Mode:             modeName(pm.mode),
+			OpponentUsername: opponent.client.Username,
+			OpponentRating:   opponent.rating,
+			AcceptBy:         pm.deadline,
+		})
+		if err != nil {
+			log.Printf("Error sending match found to client %s: %v", entry.client.ID, err)
+		}
+	}
+
+	mm.pendingMatches[pm.id] = pm
+	mm.startIfAcceptedLocked(pm)
+}
+
+// AcceptMatch records a player's answer to a ready check. Declining calls the match off.
+func (mm *MatchmakingManager) AcceptMatch(client *Client, matchID string, accept bool) error {
+	mm.poolMutex.Lock()
+	defer mm.poolMutex.Unlock()
+
+	pm, exists := mm.pendingMatches[matchID]
+	if !exists || pm.indexOf(client.ID) < 0 {
+		return ErrUnknownMatch
+	}
+	idx := pm.indexOf(client.ID)
+
+	if !accept {
+		pm.accepted[idx] = false
+		pm.declined[idx] = true
+		mm.cancelMatchLocked(pm, "You declined the match and left the matchmaking queue.")
+		return nil
+	}
+
+	pm.accepted[idx] = true
+	if !pm.accepted[1-idx] {
+		sendEvent(client, "Match accepted. Waiting for your opponent to accept...")
+	}
+	mm.startIfAcceptedLocked(pm)
+	return nil
+}
+
+// startIfAcceptedLocked creates the game once both players accepted. The caller holds poolMutex.
+func (mm *MatchmakingManager) startIfAcceptedLocked(pm *pendingMatch) {
+	if !pm.accepted[0] || !pm.accepted[1] {
+		return
+	}
+	delete(mm.pendingMatches, pm.id)
+
+	// Create a new game for these players
+	gameID := mm.nextGameIDLocked()
+
+	// Start game using session manager
+	mm.startGame(pm.entries[0].client, pm.entries[1].client, gameID, pm.mode)
+}
+
+// cancelMatchLocked calls off a match whose ready check failed. Players who declined are
+// removed and told dropMessage, unless it is empty; the others go back to the queue in their
+// old place, whether they accepted yet or not. The caller holds poolMutex.
+func (mm *MatchmakingManager) cancelMatchLocked(pm *pendingMatch, dropMessage string) {
+	delete(mm.pendingMatches, pm.id)
+	log.Printf("Match %s between %s and %s called off", pm.id, pm.entries[0].client.Username, pm.entries[1].client.Username)
+
+	for i, entry := range pm.entries {
+		if !pm.declined[i] {
+			mm.requeueLocked(entry)
+			sendEvent(entry.client, fmt.Sprintf("Your opponent did not accept the match. You are back in the %s queue.", modeName(entry.mode)))
+			continue
+		}
+		if dropMessage != "" {
+			sendEvent(entry.client, dropMessage)
+		}
+	}
+}
+
+// requeueLocked puts an entry back into its pool, ordered by when it first joined. The
+// caller holds poolMutex.
+func (mm *MatchmakingManager) requeueLocked(entry *queueEntry) {
+	pool := append(mm.waitingPools[entry.mode], entry)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].joinedAt.Before(pool[j].joinedAt)
+	})
+	mm.waitingPools[entry.mode] = pool
+}
+
+// nextGameIDLocked returns a new game ID. The caller holds poolMutex.
+func (mm *MatchmakingManager) nextGameIDLocked() string {
+	mm.gameCounter++
+	return fmt.Sprintf("%s-%d", mm.gameIDPrefix, mm.gameCounter)
+}
+
+// pendingMatchOf returns the match whose ready check the client is in, if any. The caller
+// holds poolMutex.
+func (mm *MatchmakingManager) pendingMatchOf(clientID string) *pendingMatch {
+	for _, pm := range mm.pendingMatches {
+		if pm.indexOf(clientID) >= 0 {
+			return pm
+		}
+	}
+	return nil
+}
+
+// modeName returns a game mode as named on the wire
+func modeName(mode game.GameMode) string {
+	return strings.ToLower(string(mode))
+}
+
+// ratingGap returns the absolute rating difference between two waiting players
+func ratingGap(a, b *queueEntry) int {
+	if a.rating > b.rating {
+		return a.rating - b.rating
+	}
+	return b.rating - a.rating
+}
+
 // startGame initiates a new game between two players
-func (mm *MatchmakingManager) startGame(player1, player2 *Client, gameID string) {
-	log.Printf("Starting game %s between %s and %s", gameID, player1.Username, player2.Username)
+func (mm *MatchmakingManager) startGame(player1, player2 Player, gameID string, mode game.GameMode) {
+	log.Printf("Starting game %s between %s and %s", gameID, player1.Name(), player2.Name())
+
+	// Players stop watching other games once they play one
+	for _, player := range []Player{player1, player2} {
+		if client, ok := player.(*Client); ok {
+			mm.server.sessionManager.StopSpectating(client)
+		}
+	}
 
 	// Use the session manager to create and start the game
-	session, err := mm.server.sessionManager.CreateSession(player1, player2, gameID, game.GameModeSimple)
+	session, err := mm.server.sessionManager.CreateSession(player1, player2, gameID, mode)
 	if err != nil {
 		log.Printf("Error creating game session: %v", err)
 
@@ -122,8 +613,8 @@ func (mm *MatchmakingManager) startGame(player1, player2 *Client, gameID string)
 			Time:    time.Now(),
 		}
 
-		player1.Codec.Send(network.MessageTypeGameEvent, errorMsg)
-		player2.Codec.Send(network.MessageTypeGameEvent, errorMsg)
+		player1.Send(network.MessageTypeGameEvent, errorMsg)
+		player2.Send(network.MessageTypeGameEvent, errorMsg)
 		return
 	}
 
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+func TestRatingWindowWidensWhileWaiting(t *testing.T) {
+	mm := &MatchmakingManager{RatingWindow: 100, RatingWindowGrowth: 10}
+	joinedAt := time.Now()
+
+	tests := []struct {
+		name         string
+		waited       time.Duration
+		maxRatingGap int
+		want         int
+	}{
+		{name: "just joined", waited: 0, want: 100},
+		{name: "part of a second", waited: 900 * time.Millisecond, want: 100},
+		{name: "five seconds", waited: 5 * time.Second, want: 150},
+		{name: "a minute", waited: time.Minute, want: 700},
+		{name: "capped by preference", waited: time.Minute, maxRatingGap: 200, want: 200},
+		{name: "preference above window", waited: 0, maxRatingGap: 200, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &queueEntry{joinedAt: joinedAt, maxRatingGap: tt.maxRatingGap}
+			if got := mm.ratingWindow(entry, joinedAt.Add(tt.waited)); got != tt.want {
+				t.Errorf("ratingWindow after %v = %d, want %d", tt.waited, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchmakingPairsClosestRatingInWindow(t *testing.T) {
+	s := newTestServer(t, func(s *Server) {
+		s.RatingWindow = 100
+		s.RatingWindowGrowth = 0
+	})
+	addAccount(t, s, "alice", 1200)
+	addAccount(t, s, "carol", 1500)
+	addAccount(t, s, "dave", 1290)
+	addAccount(t, s, "bob", 1250)
+
+	// Alice waits longest and picks first: carol is outside her window and bob is
+	// closer than dave
+	var clients []*testClient
+	for _, username := range []string{"alice", "carol", "dave", "bob"} {
+		c := connect(t, s, username)
+		c.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+		c.expectEvent("added to the simple matchmaking queue")
+		clients = append(clients, c)
+	}
+	alice, carol, dave, bob := clients[0], clients[1], clients[2], clients[3]
+
+	if start := expect[network.GameStartPayload](alice, network.MessageTypeGameStart); start.OpponentUsername != "bob" {
+		t.Errorf("alice was matched with %s, want bob", start.OpponentUsername)
+	}
+	expect[network.GameStartPayload](bob, network.MessageTypeGameStart)
+
+	// The unmatched players move up, still waiting. (They were sent their status on joining
+	// too, at other positions.)
+	for position, c := range []*testClient{carol, dave} {
+		c.send(network.MessageTypeQueueStatus, &network.QueueStatusPayload{})
+		expectMatch(c, network.MessageTypeQueueStatus, func(status *network.QueueStatusPayload) bool {
+			return status.InQueue && status.QueueSize == 2 && status.Position == position+1
+		})
+	}
+}
+
+// expectMatchFound waits for the ready check of a match against opponent
+func expectMatchFound(c *testClient, opponent string) *network.MatchFoundPayload {
+	c.t.Helper()
+	found := expect[network.MatchFoundPayload](c, network.MessageTypeMatchFound)
+	if found.OpponentUsername != opponent {
+		c.t.Fatalf("%s was matched with %s, want %s", c.username, found.OpponentUsername, opponent)
+	}
+	return found
+}
+
+// expectInQueue asks for the client's queue status and checks whether it is waiting
+func expectInQueue(c *testClient, inQueue bool) {
+	c.t.Helper()
+	c.send(network.MessageTypeQueueStatus, &network.QueueStatusPayload{})
+	if status := expect[network.QueueStatusPayload](c, network.MessageTypeQueueStatus); status.InQueue != inQueue {
+		c.t.Errorf("%s in queue = %v, want %v", c.username, status.InQueue, inQueue)
+	}
+}
+
+func TestReadyCheckStartsGameOnceBothAccept(t *testing.T) {
+	s := newTestServer(t, func(s *Server) { s.ReadyCheckTimeout = time.Minute })
+	addAccount(t, s, "alice", 1200)
+	addAccount(t, s, "bob", 1250)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+	alice.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	bob.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+
+	found := expectMatchFound(alice, "bob")
+	if found.OpponentRating != 1250 || found.Mode != "simple" {
+		t.Errorf("match found = %+v, want a simple game against bob rated 1250", found)
+	}
+	expectMatchFound(bob, "alice")
+
+	alice.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: true})
+	alice.expectEvent("Waiting for your opponent to accept")
+	bob.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: true})
+	for _, c := range []*testClient{alice, bob} {
+		expect[network.GameStartPayload](c, network.MessageTypeGameStart)
+	}
+}
+
+func TestReadyCheckDeclineRequeuesOpponent(t *testing.T) {
+	s := newTestServer(t, func(s *Server) { s.ReadyCheckTimeout = time.Minute })
+	addAccount(t, s, "alice", 1200)
+	addAccount(t, s, "bob", 1250)
+	addAccount(t, s, "carol", 1300)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+	alice.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	bob.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	found := expectMatchFound(alice, "bob")
+	expectMatchFound(bob, "alice")
+
+	bob.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: true})
+	alice.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: false})
+	alice.expectEvent("You declined the match and left the matchmaking queue")
+	bob.expectEvent("You are back in the simple queue")
+	expectInQueue(alice, false)
+	expectInQueue(bob, true)
+
+	// The called off match can't be answered any more
+	alice.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: true})
+	alice.expectError(404)
+
+	// Bob is matched again from the queue
+	carol := connect(t, s, "carol")
+	carol.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	expectMatchFound(bob, "carol")
+	expectMatchFound(carol, "bob")
+}
+
+func TestReadyCheckTimeout(t *testing.T) {
+	s := newTestServer(t, func(s *Server) { s.ReadyCheckTimeout = 300 * time.Millisecond })
+	addAccount(t, s, "alice", 1200)
+	addAccount(t, s, "bob", 1250)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+	alice.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	bob.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	found := expectMatchFound(alice, "bob")
+	expectMatchFound(bob, "alice")
+
+	// Only alice answers before the deadline
+	alice.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: true})
+	bob.expectEvent("You did not accept the match in time")
+	alice.expectEvent("You are back in the simple queue")
+	expectInQueue(alice, true)
+	expectInQueue(bob, false)
+
+	bob.send(network.MessageTypeMatchAccept, &network.MatchAcceptPayload{MatchID: found.MatchID, Accept: true})
+	bob.expectError(404)
+}
@@ -1,11 +1,16 @@
 package server
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/NP-Dat/net-centric-project/internal/game"
 	"github.com/NP-Dat/net-centric-project/internal/models"
 	"github.com/NP-Dat/net-centric-project/internal/network"
 	"github.com/NP-Dat/net-centric-project/internal/persistence"
@@ -25,28 +30,116 @@ type Server struct {
 	authManager    *AuthManager        // Add auth manager for user authentication
 	matchmaker     *MatchmakingManager // Add matchmaking manager
 	sessionManager *SessionManager     // Add session manager for game management
+	leaderboard    *Leaderboard        // Player rankings, created on Start from PlayerStore
+	challenges     *ChallengeManager   // Private games between online players
+
+	TurnTimeout           time.Duration // Per-turn deadline in Simple mode, 0 disables the turn timer
+	MaxTurnTimeouts       int           // Consecutive timeouts before a player forfeits, 0 never forfeits
+	DisconnectGracePeriod time.Duration // How long a dropped player has to come back before forfeiting
+	MaxFrameSize          int           // Largest message in bytes accepted from a client
+
+	RatingWindow       int // Largest rating gap matchmaking accepts as soon as a player joins
+	RatingWindowGrowth int // Rating points the matchmaking window widens per second of waiting
+
+	ReadyCheckTimeout   time.Duration // Time matched players have to accept, 0 starts games without asking
+	QueueStatusInterval time.Duration // How often waiting players are sent their queue position, 0 only on request
+	ChallengeTimeout    time.Duration // How long a challenge to another player waits for an answer
+	BotFallbackWait     time.Duration // Matchmaking wait after which a player gets a bot opponent, 0 never
+
+	SessionTokenTTL    time.Duration // How long a session token issued at login stays valid
+	SessionSecretFile  string        // Keeps the session token secret across restarts, "" keeps it in memory only
+	AutoCreateAccounts bool          // Create an account for unknown usernames on login
+
+	TLSCertFile string // Certificate for TLS connections, plain TCP is used when empty
+	TLSKeyFile  string // Private key matching TLSCertFile
+
+	PlayerStore persistence.PlayerStore // Where accounts are kept, JSON files under basePath by default
 }
 
+// Default session timing settings, used unless overridden before Start
+const (
+	DefaultTurnTimeout           = 30 * time.Second
+	DefaultMaxTurnTimeouts       = 3
+	DefaultDisconnectGracePeriod = 30 * time.Second
+)
+
 // Client represents a connected client
 type Client struct {
 	ID       string
 	Username string
 	Conn     net.Conn
 	Codec    *network.Codec
-	GameID   string
 	Server   *Server
+
+	gameMutex sync.Mutex // Guards gameID, which the opponent's connection clears when the game ends
+	gameID    string
+
+	SessionToken    string   // Token the client logged in with or was issued, revoked on quit
+	ProtocolVersion int      // Announced in the client's hello, 0 if it never sent one
+	Features        []string // Features the client advertised in its hello
+}
+
+// ServerFeatures are the protocol features this server advertises in its hello
+var ServerFeatures = []string{network.FeatureEnhancedMode, network.FeatureLengthFraming, network.FeatureReadyCheck, network.FeatureSpectate,
+	network.FeatureQueueStatus, network.FeatureChallenge}
+
+// serverName identifies this server in its hello
+const serverName = "tcr-server"
+
+// HasFeature reports whether the client advertised the feature in its hello
+func (c *Client) HasFeature(feature string) bool {
+	return network.HasFeature(c.Features, feature)
+}
+
+// Name returns the client's username, as seated in a game session
+func (c *Client) Name() string {
+	return c.Username
+}
+
+// Send sends a message to the client
+func (c *Client) Send(msgType network.MessageType, payload interface{}) error {
+	return c.Codec.Send(msgType, payload)
+}
+
+// CurrentGameID returns the ID of the game the client is playing, "" if none
+func (c *Client) CurrentGameID() string {
+	c.gameMutex.Lock()
+	defer c.gameMutex.Unlock()
+	return c.gameID
+}
+
+// SetGameID records the game the client is playing, "" once it ends
+func (c *Client) SetGameID(gameID string) {
+	c.gameMutex.Lock()
+	defer c.gameMutex.Unlock()
+	c.gameID = gameID
 }
 
 // NewServer creates a new TCR server
 func NewServer(host string, port int, basePath string) *Server {
 	configLoader := persistence.NewConfigLoader(basePath)
+	playerStore := persistence.NewFileStore(basePath)
 	server := &Server{
 		Host:         host,
 		Port:         port,
 		clients:      make(map[string]*Client),
 		basePath:     basePath,
 		configLoader: configLoader,
-		authManager:  NewAuthManager(basePath), // Initialize auth manager
+		authManager:  NewAuthManager(playerStore), // Initialize auth manager
+
+		TurnTimeout:           DefaultTurnTimeout,
+		MaxTurnTimeouts:       DefaultMaxTurnTimeouts,
+		DisconnectGracePeriod: DefaultDisconnectGracePeriod,
+		MaxFrameSize:          network.DefaultMaxFrameSize,
+		SessionTokenTTL:       DefaultSessionTokenTTL,
+		SessionSecretFile:     filepath.Join(basePath, "data", "session_secret"),
+		RatingWindow:          DefaultRatingWindow,
+		RatingWindowGrowth:    DefaultRatingWindowGrowth,
+		ReadyCheckTimeout:     DefaultReadyCheckTimeout,
+		QueueStatusInterval:   DefaultQueueStatusInterval,
+		ChallengeTimeout:      DefaultChallengeTimeout,
+		BotFallbackWait:       DefaultBotFallbackWait,
+		PlayerStore:           playerStore,
 	}
 
 	// Initialize the session manager
@@ -67,15 +160,44 @@ func (s *Server) Start() error {
 
 	// Initialize the matchmaking manager
 	s.matchmaker = NewMatchmakingManager(s)
-
-	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
-	s.listener, err = net.Listen("tcp", addr)
+	s.challenges = NewChallengeManager(s)
+	s.authManager.TokenTTL = s.SessionTokenTTL
+	secret, generated, err := LoadSessionSecret(s.SessionSecretFile)
+	if err != nil {
+		logger.Server.Error("Failed to load session token secret: %v", err)
+		return err
+	}
+	if generated && s.SessionSecretFile != "" {
+		logger.Server.Warn("No session token secret configured, generated one at %s. Set %s to share one between servers.", s.SessionSecretFile, SessionSecretEnv)
+	} else if generated {
+		logger.Server.Warn("No session token secret configured, using a random one. Session tokens will not survive a restart.")
+	}
+	s.authManager.tokenSecret = secret
+	s.authManager.AutoCreateAccounts = s.AutoCreateAccounts
+	s.authManager.store = s.PlayerStore
+	s.leaderboard = NewLeaderboard(s.PlayerStore)
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	if s.TLSCertFile != "" {
+		tlsConfig, err := network.ServerTLSConfig(s.TLSCertFile, s.TLSKeyFile)
+		if err != nil {
+			logger.Server.Error("Failed to set up TLS: %v", err)
+			return err
+		}
+		s.listener, err = tls.Listen("tcp", addr, tlsConfig)
+	} else {
+		s.listener, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		logger.Server.Error("Failed to start server on %s: %v", addr, err)
 		return fmt.Errorf("failed to start server on %s: %w", addr, err)
 	}
 
-	logger.Server.Info("Server started on %s", addr)
+	if s.TLSCertFile != "" {
+		logger.Server.Info("Server started on %s (TLS)", addr)
+	} else {
+		logger.Server.Info("Server started on %s", addr)
+	}
 
 	// Accept connections in a goroutine
 	go s.acceptConnections()
@@ -102,6 +224,13 @@ func (s *Server) Stop() error {
 		logger.Server.Info("All client connections closed")
 	}
 
+	if s.PlayerStore != nil {
+		if err := s.PlayerStore.Close(); err != nil {
+			logger.Server.Error("Failed to close player store: %v", err)
+			return fmt.Errorf("failed to close player store: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -129,6 +258,7 @@ func (s *Server) acceptConnections() {
 			Codec:  network.NewCodec(conn),
 			Server: s,
 		}
+		client.Codec.SetMaxFrameSize(s.MaxFrameSize)
 
 		// Add to clients map
 		s.clientsMux.Lock()
@@ -155,6 +285,19 @@ func (s *Server) handleClient(client *Client) {
 		delete(s.clients, client.ID)
 		s.clientsMux.Unlock()
 
+		// Give an in-game player a grace period to come back before they forfeit. This
+		// happens before the login is released so a quick re-login finds the game resumable.
+		if client.CurrentGameID() != "" {
+			s.sessionManager.HandlePlayerDisconnect(client)
+		}
+		// Release the login and queue slot however the connection ended
+		if client.Username != "" {
+			s.authManager.UnregisterActiveClient(client.Username, client.ID)
+			s.matchmaker.RemoveFromWaitingPool(client.ID)
+			s.challenges.RemoveClient(client)
+			s.sessionManager.StopSpectating(client)
+		}
+
 		// Close the connection
 		client.Conn.Close()
 		logger.Server.Info("Client %s disconnected", client.ID)
@@ -164,7 +307,7 @@ func (s *Server) handleClient(client *Client) {
 
 	// Send a welcome message
 	welcomePayload := &network.GameEventPayload{
-		Message: "Welcome to Text Clash Royale! Please login with your username and password by 'login' command. Use 'help' for more commands.",
+		Message: "Welcome to Text Clash Royale! Please login with your username and password by 'login' command, or create an account with 'register'. Use 'help' for more commands.",
 		Time:    time.Now(),
 	}
 
@@ -189,11 +332,7 @@ func (s *Server) handleClient(client *Client) {
 			logger.Server.Error("Error processing message from client %s: %v", client.ID, err)
 
 			// Send error message to client
-			errorPayload := &network.ErrorPayload{
-				Code:    500, // Internal server error
-				Message: "Error processing your request",
-			}
-			if sendErr := client.Codec.Send(network.MessageTypeError, errorPayload); sendErr != nil {
+			if sendErr := sendError(client, 500, "Error processing your request"); sendErr != nil {
 				logger.Server.Error("Failed to send error message to client %s: %v", client.ID, sendErr)
 			}
 
@@ -207,13 +346,17 @@ func (s *Server) handleClient(client *Client) {
 
 // processMessage processes a message from a client
 func (s *Server) processMessage(client *Client, msg *network.Message) error {
+	// Every request is decoded strictly into the payload registered for its type, and
+	// messages of any other type are rejected
+	payload, err := network.DecodeRequest(msg)
+	if err != nil {
+		logger.Server.Warn("Invalid %s message from client %s: %v", msg.Type, client.ID, err)
+		return s.sendPayloadError(client, err)
+	}
+
 	switch msg.Type {
 	case network.MessageTypeLogin:
-		var loginPayload network.LoginPayload
-		if err := network.ParsePayload(msg, &loginPayload); err != nil {
-			logger.Server.Error("Invalid login payload from client %s: %v", client.ID, err)
-			return err
-		}
+		loginPayload := payload.(*network.LoginPayload)
 
 		logger.Server.Info("Login attempt from client %s with username: %s", client.ID, loginPayload.Username)
 
@@ -222,91 +365,289 @@ func (s *Server) processMessage(client *Client, msg *network.Message) error {
 		if err != nil {
 			// Authentication failed
 			logger.Server.Warn("Authentication failed for username '%s': %v", loginPayload.Username, err)
-			authResultPayload := &network.AuthResultPayload{
-				Success: false,
-				Message: err.Error(),
-			}
-			return client.Codec.Send(network.MessageTypeAuthResult, authResultPayload)
+			return s.sendAuthFailure(client, err)
 		}
 
 		// Authentication successful
 		logger.Server.Info("Authentication successful for user: %s", playerData.Username)
-		client.Username = playerData.Username
-
-		// Register the user as active
-		if err := s.authManager.RegisterActiveUser(playerData.Username, client.ID); err != nil {
-			logger.Server.Error("Failed to register active user %s: %v", playerData.Username, err)
-			authResultPayload := &network.AuthResultPayload{
-				Success: false,
-				Message: err.Error(),
+		return s.completeLogin(client, playerData.Username, "")
+
+	case network.MessageTypeRegister:
+		registerPayload := payload.(*network.RegisterPayload)
+
+		logger.Server.Info("Registration attempt from client %s with username: %s", client.ID, registerPayload.Username)
+
+		playerData, err := s.authManager.RegisterUser(registerPayload.Username, registerPayload.Password)
+		if err != nil {
+			logger.Server.Warn("Registration failed for username '%s': %v", registerPayload.Username, err)
+			return s.sendAuthFailure(client, err)
+		}
+
+		// A new account is logged straight in
+		return s.completeLogin(client, playerData.Username, "")
+
+	case network.MessageTypeResume:
+		resumePayload := payload.(*network.ResumePayload)
+
+		claims, err := s.authManager.ValidateSessionToken(resumePayload.Token)
+		if err == nil {
+			// The account may have gone away since the token was issued
+			_, err = s.authManager.GetPlayerData(claims.Username)
+		}
+		if err != nil {
+			logger.Server.Warn("Token login from client %s rejected: %v", client.ID, err)
+			return s.sendAuthFailure(client, err)
+		}
+
+		logger.Server.Info("Token login successful for user: %s", claims.Username)
+		return s.completeLogin(client, claims.Username, resumePayload.Token)
+
+	case network.MessageTypeHello:
+		// The hello opens the conversation; it may not be repeated or come after login
+		if client.ProtocolVersion != 0 || client.Username != "" {
+			return sendError(client, 400, "Hello must be the first message and may only be sent once")
+		}
+
+		helloPayload := payload.(*network.HelloPayload)
+
+		if helloPayload.ProtocolVersion < network.MinProtocolVersion || helloPayload.ProtocolVersion > network.ProtocolVersion {
+			logger.Server.Warn("Client %s (%s) speaks unsupported protocol version %d", client.ID, helloPayload.ClientName, helloPayload.ProtocolVersion)
+			if err := client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
+				Code:               network.ErrorCodeIncompatibleVersion,
+				Message:            fmt.Sprintf("Protocol version %d is not supported, this server accepts versions %d to %d", helloPayload.ProtocolVersion, network.MinProtocolVersion, network.ProtocolVersion),
+				MinProtocolVersion: network.MinProtocolVersion,
+				MaxProtocolVersion: network.ProtocolVersion,
+			}); err != nil {
+				logger.Server.Error("Failed to send version rejection to client %s: %v", client.ID, err)
 			}
-			return client.Codec.Send(network.MessageTypeAuthResult, authResultPayload)
+			return fmt.Errorf("critical error") // Nothing more can be understood, disconnect
 		}
 
-		// Send successful authentication result
-		authResultPayload := &network.AuthResultPayload{
-			Success:  true,
-			Message:  "Authentication successful",
-			PlayerID: client.ID,
+		client.ProtocolVersion = helloPayload.ProtocolVersion
+		client.Features = helloPayload.Features
+		client.Codec.SetPeerVersion(client.ProtocolVersion)
+		logger.Server.Info("Client %s is %s, protocol version %d, features %v", client.ID, helloPayload.ClientName, client.ProtocolVersion, client.Features)
+
+		return client.Codec.Send(network.MessageTypeHello, &network.HelloPayload{
+			ProtocolVersion: network.ProtocolVersion,
+			ServerName:      serverName,
+			Features:        ServerFeatures,
+		})
+
+	case network.MessageTypeFraming:
+		// Switching framing mid-game would race with session messages, so only allow it before login
+		if client.Username != "" {
+			return sendError(client, 400, "Framing must be negotiated before login")
 		}
 
-		if err := client.Codec.Send(network.MessageTypeAuthResult, authResultPayload); err != nil {
-			logger.Server.Error("Failed to send authentication result to client %s: %v", client.ID, err)
-			return err
+		framingPayload := payload.(*network.FramingPayload)
+
+		// Use the client's preferred framing we support, and the smaller of the two frame limits.
+		// A client that said hello must also have advertised length-prefixed framing.
+		version := network.ChooseFraming(framingPayload.Versions)
+		if client.ProtocolVersion != 0 && !client.HasFeature(network.FeatureLengthFraming) {
+			version = network.FramingJSONLine
+		}
+		if framingPayload.MaxFrameSize > 0 && framingPayload.MaxFrameSize < client.Codec.MaxFrameSize() {
+			client.Codec.SetMaxFrameSize(framingPayload.MaxFrameSize)
 		}
+		logger.Server.Info("Client %s negotiated framing version %d (max frame size %d bytes)", client.ID, version, client.Codec.MaxFrameSize())
 
-		// Send an info message about joining matchmaking
-		infoPayload := &network.GameEventPayload{
-			Message: "You can join the matchmaking queue by typing 'join'",
-			Time:    time.Now(),
+		// The answer still goes out in the old framing; everything after it uses the new one
+		if err := client.Codec.Send(network.MessageTypeFraming, &network.FramingPayload{
+			Version:      version,
+			MaxFrameSize: client.Codec.MaxFrameSize(),
+		}); err != nil {
+			return err
 		}
-		return client.Codec.Send(network.MessageTypeGameEvent, infoPayload)
+		return client.Codec.SetFraming(version)
 
 	case network.MessageTypeJoinQueue:
 		// Check if the client is authenticated
 		if client.Username == "" {
 			logger.Server.Warn("Unauthenticated client %s attempted to join matchmaking queue", client.ID)
-			return client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
-				Code:    401,
-				Message: "You must be logged in to join matchmaking",
-			})
+			return sendError(client, 401, "You must be logged in to join matchmaking")
+		}
+		if client.CurrentGameID() != "" {
+			return sendError(client, 409, "You are already in a game")
 		}
 
-		logger.Server.Info("Client %s (%s) joining matchmaking queue", client.ID, client.Username)
+		joinPayload := payload.(*network.JoinQueuePayload)
+
+		mode := game.GameModeSimple
+		if joinPayload.Mode == network.GameModeEnhanced {
+			// Only clients that announced they can play real-time games are put in that queue
+			if !client.HasFeature(network.FeatureEnhancedMode) {
+				return sendError(client, 400, "Your client does not support enhanced mode")
+			}
+			mode = game.GameModeEnhanced
+		}
+
+		logger.Server.Info("Client %s (%s) joining %s matchmaking queue", client.ID, client.Username, mode)
 
 		// Add the client to the matchmaking queue
-		s.matchmaker.AddToWaitingPool(client)
+		s.matchmaker.AddToWaitingPool(client, mode, joinPayload.Preferences)
+		return nil
+
+	case network.MessageTypeLeaveQueue:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to leave matchmaking")
+		}
+
+		if !s.matchmaker.RemoveFromWaitingPool(client.ID) {
+			return sendError(client, 400, "You are not in the matchmaking queue")
+		}
+		logger.Server.Info("Client %s (%s) left the matchmaking queue", client.ID, client.Username)
+		return sendEvent(client, "You left the matchmaking queue.")
+
+	case network.MessageTypeQueueStatus:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to view your queue status")
+		}
+		if !client.HasFeature(network.FeatureQueueStatus) {
+			return sendError(client, 400, "Your client does not support queue status")
+		}
+
+		return client.Codec.Send(network.MessageTypeQueueStatus, s.matchmaker.QueueStatus(client.ID))
+
+	case network.MessageTypeMatchAccept:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to accept a match")
+		}
+		if !client.HasFeature(network.FeatureReadyCheck) {
+			return sendError(client, 400, "Your client does not support ready checks")
+		}
+
+		acceptPayload := payload.(*network.MatchAcceptPayload)
+
+		if err := s.matchmaker.AcceptMatch(client, acceptPayload.MatchID, acceptPayload.Accept); err != nil {
+			return sendError(client, 404, err.Error())
+		}
+		return nil
+
+	case network.MessageTypePlayBot:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to play against a bot")
+		}
+		if client.CurrentGameID() != "" {
+			return sendError(client, 409, "You are already in a game")
+		}
+
+		botPayload := payload.(*network.PlayBotPayload)
+
+		mode := game.GameModeSimple
+		if botPayload.Mode == network.GameModeEnhanced {
+			if !client.HasFeature(network.FeatureEnhancedMode) {
+				return sendError(client, 400, "Your client does not support enhanced mode")
+			}
+			mode = game.GameModeEnhanced
+		}
+		difficulty := botPayload.Difficulty
+		if difficulty == "" {
+			difficulty = network.BotDifficultyMedium
+		}
+
+		logger.Server.Info("Client %s (%s) starting a %s game against a %s bot", client.ID, client.Username, mode, difficulty)
+		s.matchmaker.StartBotGame(client, mode, difficulty)
 		return nil
 
+	case network.MessageTypeChallenge:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to challenge a player")
+		}
+		if !client.HasFeature(network.FeatureChallenge) {
+			return sendError(client, 400, "Your client does not support challenges")
+		}
+
+		challengePayload := payload.(*network.ChallengePayload)
+
+		mode := game.GameModeSimple
+		if challengePayload.Mode == network.GameModeEnhanced {
+			if !client.HasFeature(network.FeatureEnhancedMode) {
+				return sendError(client, 400, "Your client does not support enhanced mode")
+			}
+			mode = game.GameModeEnhanced
+		}
+		return s.challenges.Challenge(client, challengePayload.Username, mode)
+
+	case network.MessageTypeChallengeResponse:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to answer a challenge")
+		}
+		if !client.HasFeature(network.FeatureChallenge) {
+			return sendError(client, 400, "Your client does not support challenges")
+		}
+
+		responsePayload := payload.(*network.ChallengeResponsePayload)
+		return s.challenges.Respond(client, responsePayload.ChallengeID, responsePayload.Accept)
+
+	case network.MessageTypeListGames:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to list games")
+		}
+		if !client.HasFeature(network.FeatureSpectate) {
+			return sendError(client, 400, "Your client does not support watching games")
+		}
+		return client.Codec.Send(network.MessageTypeGameList, &network.GameListPayload{Games: s.sessionManager.ListGames()})
+
+	case network.MessageTypeSpectate:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to watch a game")
+		}
+		if !client.HasFeature(network.FeatureSpectate) {
+			return sendError(client, 400, "Your client does not support watching games")
+		}
+
+		spectatePayload := payload.(*network.SpectatePayload)
+		logger.Server.Info("Client %s (%s) wants to watch game %s", client.ID, client.Username, spectatePayload.GameID)
+		return s.sessionManager.Spectate(client, spectatePayload.GameID)
+
+	case network.MessageTypeStopSpectating:
+		if !s.sessionManager.StopSpectating(client) {
+			return sendError(client, 400, "You are not watching a game")
+		}
+		return sendEvent(client, "You stopped watching the game.")
+
+	case network.MessageTypeStats:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to view stats")
+		}
+
+		statsRequest := payload.(*network.StatsRequestPayload)
+		return s.sendStats(client, statsRequest.Username)
+
+	case network.MessageTypeLeaderboard:
+		if client.Username == "" {
+			return sendError(client, 401, "You must be logged in to view the leaderboard")
+		}
+
+		leaderboardRequest := payload.(*network.LeaderboardRequestPayload)
+
+		page, err := s.leaderboard.Page(leaderboardRequest.SortBy, leaderboardRequest.Page, leaderboardRequest.PageSize)
+		if err != nil {
+			logger.Server.Error("Failed to compute leaderboard: %v", err)
+			return sendError(client, 500, "Could not load the leaderboard")
+		}
+		return client.Codec.Send(network.MessageTypeLeaderboardResult, page)
+
 	case network.MessageTypeDeployTroop:
 		// Check if the client is in a game
-		if client.GameID == "" {
+		if client.CurrentGameID() == "" {
+			if s.sessionManager.spectatedSession(client) != nil {
+				return sendError(client, 403, "Spectators cannot send game actions")
+			}
 			logger.Server.Warn("Client %s attempted to deploy a troop while not in a game", client.ID)
-			return client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
-				Code:    400,
-				Message: "You are not in a game",
-			})
+			return sendError(client, 400, "You are not in a game")
 		}
 
 		// Get the game session
-		_, exists := s.sessionManager.GetSession(client.GameID)
+		_, exists := s.sessionManager.GetSession(client.CurrentGameID())
 		if !exists {
-			logger.Server.Error("Client %s referred to non-existent game session: %s", client.ID, client.GameID)
-			return client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
-				Code:    400,
-				Message: "Game session not found",
-			})
+			logger.Server.Error("Client %s referred to non-existent game session: %s", client.ID, client.CurrentGameID())
+			return sendError(client, 400, "Game session not found")
 		}
 
-		// Parse the deploy troop payload
-		var deployPayload network.DeployTroopPayload
-		if err := network.ParsePayload(msg, &deployPayload); err != nil {
-			logger.Server.Error("Invalid deploy troop payload from client %s: %v", client.ID, err)
-			return client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
-				Code:    400,
-				Message: "Invalid deploy troop payload: " + err.Error(),
-			})
-		}
+		deployPayload := payload.(*network.DeployTroopPayload)
 
 		logger.Server.Info("Client %s (%s) deploying troop: %s", client.ID, client.Username, deployPayload.TroopID)
 
@@ -317,6 +658,10 @@ func (s *Server) processMessage(client *Client, msg *network.Message) error {
 		// If the user has authenticated, unregister them
 		if client.Username != "" {
 			logger.Server.Info("Client %s (%s) is quitting", client.ID, client.Username)
+			// Quitting is logging out, so the session token may not be used again
+			if client.SessionToken != "" {
+				s.authManager.RevokeSessionToken(client.SessionToken)
+			}
 			s.authManager.UnregisterActiveUser(client.Username)
 			// Also remove them from the matchmaking queue
 			s.matchmaker.RemoveFromWaitingPool(client.ID)
@@ -329,27 +674,20 @@ func (s *Server) processMessage(client *Client, msg *network.Message) error {
 		// Handle messages from authenticated users
 		if client.Username == "" {
 			logger.Server.Warn("Unauthenticated client %s sent message of type %s", client.ID, msg.Type)
-			return client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
-				Code:    401,
-				Message: "You must be logged in first",
-			})
+			return sendError(client, 401, "You must be logged in first")
 		}
 
 		// For Sprint 1, if it's a GameEvent type, we'll treat it as a chat message
 		if msg.Type == network.MessageTypeGameEvent {
-			var payload network.GameEventPayload
-			if err := network.ParsePayload(msg, &payload); err != nil {
-				logger.Server.Error("Invalid game event payload from client %s: %v", client.ID, err)
-				return err
-			}
+			chatPayload := payload.(*network.GameEventPayload)
 
 			// Format the message with the username
 			messagePayload := &network.GameEventPayload{
-				Message: fmt.Sprintf("[%s]: %s", client.Username, payload.Message),
+				Message: fmt.Sprintf("[%s]: %s", client.Username, chatPayload.Message),
 				Time:    time.Now(),
 			}
 
-			logger.Server.Debug("Broadcasting chat message from %s: %s", client.Username, payload.Message)
+			logger.Server.Debug("Broadcasting chat message from %s: %s", client.Username, chatPayload.Message)
 
 			// Broadcast to all clients (simple chat implementation)
 			s.clientsMux.Lock()
@@ -367,15 +705,117 @@ func (s *Server) processMessage(client *Client, msg *network.Message) error {
 
 		// For other message types, just acknowledge receipt
 		logger.Server.Debug("Received unhandled message type %s from client %s", msg.Type, client.ID)
-		gameEventPayload := &network.GameEventPayload{
-			Message: fmt.Sprintf("Received message of type %s", msg.Type),
-			Time:    time.Now(),
+		return sendEvent(client, fmt.Sprintf("Received message of type %s", msg.Type))
+	}
+}
+
+// completeLogin finishes a successful password or token login: the user is marked active,
+// given a session token (a token login keeps its token) and, if they dropped out of a game
+// within the grace period, put back into it
+func (s *Server) completeLogin(client *Client, username string, sessionToken string) error {
+	// Register the user as active
+	if err := s.authManager.RegisterActiveUser(username, client.ID); err != nil {
+		logger.Server.Error("Failed to register active user %s: %v", username, err)
+		return s.sendAuthFailure(client, err)
+	}
+	client.Username = username
+
+	authResultPayload := &network.AuthResultPayload{
+		Success:  true,
+		Message:  "Authentication successful",
+		PlayerID: client.ID,
+		Username: username,
+	}
+	if sessionToken == "" {
+		token, expiresAt, err := s.authManager.IssueSessionToken(username)
+		if err != nil {
+			// Not fatal, the user just has to log in with their password next time
+			logger.Server.Error("Failed to issue session token for %s: %v", username, err)
+		} else {
+			sessionToken = token
+			authResultPayload.TokenExpiresAt = expiresAt
 		}
-		return client.Codec.Send(network.MessageTypeGameEvent, gameEventPayload)
+	} else if claims, err := s.authManager.ValidateSessionToken(sessionToken); err == nil {
+		authResultPayload.TokenExpiresAt = time.Unix(claims.ExpiresAt, 0)
+	}
+	client.SessionToken = sessionToken
+	authResultPayload.SessionToken = sessionToken
+
+	// Send successful authentication result
+	if err := client.Codec.Send(network.MessageTypeAuthResult, authResultPayload); err != nil {
+		logger.Server.Error("Failed to send authentication result to client %s: %v", client.ID, err)
+		return err
 	}
+
+	// A player returning within their disconnect grace period goes straight back into their game
+	if s.sessionManager.ResumeSession(client) {
+		return nil
+	}
+
+	// Send an info message about joining matchmaking
+	return sendEvent(client, "You can join the matchmaking queue by typing 'join'")
+}
+
+// sendAuthFailure sends a failed authentication result, with the code of an AuthError.
+// Token problems count as bad credentials; anything else is a server-side failure.
+func (s *Server) sendAuthFailure(client *Client, err error) error {
+	code := 500
+	var authErr *AuthError
+	switch {
+	case errors.As(err, &authErr):
+		code = authErr.Code
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrExpiredToken), errors.Is(err, ErrRevokedToken):
+		code = network.AuthCodeInvalidCredentials
+	}
+	return client.Codec.Send(network.MessageTypeAuthResult, &network.AuthResultPayload{
+		Success: false,
+		Code:    code,
+		Message: err.Error(),
+	})
+}
+
+// sendPayloadError tells the client why its message payload was rejected. Malformed payloads
+// get a 400 naming the problem; anything else is returned to be handled as a server error.
+func (s *Server) sendPayloadError(client *Client, err error) error {
+	var payloadErr *network.PayloadError
+	if !errors.As(err, &payloadErr) {
+		return err
+	}
+	return sendError(client, 400, payloadErr.Error())
+}
+
+// activeClient returns the connected client the user is logged in on, or nil if they are offline
+func (s *Server) activeClient(username string) *Client {
+	clientID, online := s.authManager.ActiveClientID(username)
+	if !online {
+		return nil
+	}
+	s.clientsMux.Lock()
+	defer s.clientsMux.Unlock()
+	return s.clients[clientID]
 }
 
 // generateID generates a unique ID for a client
 func generateID() string {
 	return fmt.Sprintf("client-%d", time.Now().UnixNano())
 }
+
+// sendEvent sends a notice to a client as a game event
+func sendEvent(client *Client, message string) error {
+	err := client.Codec.Send(network.MessageTypeGameEvent, &network.GameEventPayload{
+		Message: message,
+		Time:    time.Now(),
+	})
+	if err != nil {
+		logger.Server.Error("Error sending event to client %s: %v", client.ID, err)
+	}
+	return err
+}
+
+// sendError sends an error reply to a client
+func sendError(client *Client, code int, message string) error {
+	return client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword is the password of every account made by addAccount
+const testPassword = "password1"
+
+// testWait is how long a test waits for a message before failing
+const testWait = 5 * time.Second
+
+// newTestServer starts a server on a free local port, with the repo's game config and
+// accounts kept in memory. configure may change its settings before it starts.
+func newTestServer(t *testing.T, configure func(s *Server)) *Server {
+	t.Helper()
+	basePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(basePath, "config"), 0755); err != nil {
+		t.Fatalf("creating config directory: %v", err)
+	}
+	for _, name := range []string{"towers.json", "troops.json"} {
+		data, err := os.ReadFile(filepath.Join("..", "..", "config", name))
+		if err != nil {
+			t.Fatalf("reading config: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(basePath, "config", name), data, 0644); err != nil {
+			t.Fatalf("writing config: %v", err)
+		}
+	}
+
+	s := NewServer("127.0.0.1", 0, basePath)
+	s.PlayerStore = persistence.NewMemoryStore()
+	s.SessionSecretFile = ""
+	s.ReadyCheckTimeout = 0
+	s.QueueStatusInterval = 0
+	s.BotFallbackWait = 0
+	if configure != nil {
+		configure(s)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+// addAccount stores an account for username with testPassword and the given rating
+func addAccount(t *testing.T, s *Server, username string, rating int) {
+	t.Helper()
+	// The lowest cost keeps logins fast; checking the password works the same way
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	if err := s.PlayerStore.SavePlayer(&models.PlayerData{
+		Username:       username,
+		HashedPassword: string(hashedPassword),
+		Level:          1,
+		Rating:         rating,
+	}); err != nil {
+		t.Fatalf("SavePlayer: %v", err)
+	}
+}
+
+// testClient is a connection to the test server that queues every message it receives
+type testClient struct {
+	t        *testing.T
+	username string
+	codec    *network.Codec
+	conn     net.Conn
+	messages chan *network.Message
+}
+
+// dial connects to the server and says hello with every feature the server offers
+func dial(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c := &testClient{t: t, codec: network.NewCodec(conn), conn: conn, messages: make(chan *network.Message, 1000)}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		defer close(c.messages)
+		for {
+			msg, err := c.codec.Receive()
+			if err != nil {
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+
+	c.send(network.MessageTypeHello, &network.HelloPayload{ProtocolVersion: network.ProtocolVersion, Features: ServerFeatures})
+	expect[network.HelloPayload](c, network.MessageTypeHello)
+	return c
+}
+
+// connect logs in to the account of username over a new connection
+func connect(t *testing.T, s *Server, username string) *testClient {
+	t.Helper()
+	c := dial(t, s)
+	c.username = username
+	c.send(network.MessageTypeLogin, &network.LoginPayload{Username: username, Password: testPassword})
+	if result := expect[network.AuthResultPayload](c, network.MessageTypeAuthResult); !result.Success {
+		t.Fatalf("login as %s failed: %s", username, result.Message)
+	}
+	return c
+}
+
+// send sends a message to the server
+func (c *testClient) send(msgType network.MessageType, payload interface{}) {
+	c.t.Helper()
+	if err := c.codec.Send(msgType, payload); err != nil {
+		c.t.Fatalf("sending %s: %v", msgType, err)
+	}
+}
+
+// expect waits for the next message of msgType, skipping messages of other types
+func expect[T any](c *testClient, msgType network.MessageType) *T {
+	c.t.Helper()
+	return expectMatch(c, msgType, func(*T) bool { return true })
+}
+
+// expectMatch waits for the next message of msgType that match accepts, skipping others
+func expectMatch[T any](c *testClient, msgType network.MessageType, match func(payload *T) bool) *T {
+	c.t.Helper()
+	timeout := time.After(testWait)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("%s: connection closed while waiting for %s", c.username, msgType)
+			}
+			if msg.Type != msgType {
+				continue
+			}
+			payload, err := network.Decode[T](msg)
+			if err != nil {
+				c.t.Fatalf("%s: decoding %s: %v", c.username, msgType, err)
+			}
+			if match(payload) {
+				return payload
+			}
+		case <-timeout:
+			c.t.Fatalf("%s: no %s message within %v", c.username, msgType, testWait)
+		}
+	}
+}
+
+// expectEvent waits for a game event whose message contains text
+func (c *testClient) expectEvent(text string) *network.GameEventPayload {
+	c.t.Helper()
+	return expectMatch(c, network.MessageTypeGameEvent, func(event *network.GameEventPayload) bool {
+		return strings.Contains(event.Message, text)
+	})
+}
+
+// expectError waits for an error reply with the given code
+func (c *testClient) expectError(code int) *network.ErrorPayload {
+	c.t.Helper()
+	return expectMatch(c, network.MessageTypeError, func(payload *network.ErrorPayload) bool {
+		return payload.Code == code
+	})
+}
+
+// startMatch queues both clients for a Simple mode game and returns their game starts
+func startMatch(t *testing.T, first, second *testClient) (*network.GameStartPayload, *network.GameStartPayload) {
+	t.Helper()
+	first.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	second.send(network.MessageTypeJoinQueue, &network.JoinQueuePayload{})
+	return expect[network.GameStartPayload](first, network.MessageTypeGameStart), expect[network.GameStartPayload](second, network.MessageTypeGameStart)
+}
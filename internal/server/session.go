@@ -13,6 +13,12 @@ import (
 	"github.com/NP-Dat/net-centric-project/internal/persistence"
 )
 
+// EXP awarded at the end of an Enhanced mode match, on top of destroyed tower EXP
+const (
+	enhancedWinExp  = 30
+	enhancedDrawExp = 10
+)
+
 // SessionManager handles game sessions for the TCR server
 type SessionManager struct {
 	server        *Server
@@ -21,15 +27,48 @@ type SessionManager struct {
 	configLoader  *persistence.ConfigLoader
 }
 
+// Player is a participant seated in a game session: a connected client or a server-side bot
+type Player interface {
+	Name() string                                                // Username, unique among the players of a session
+	Send(msgType network.MessageType, payload interface{}) error // Delivers a game message to the player
+	CurrentGameID() string                                       // ID of the game the player is in, "" if none
+	SetGameID(gameID string)                                     // Seats the player in a game, or clears it with ""
+}
+
 // GameSession represents a single game session between two players
 type GameSession struct {
 	ID           string
 	Game         *game.Game
-	Player1      *Client
-	Player2      *Client
 	GameMode     game.GameMode
 	Active       bool
 	LastActivity time.Time
+
+	enhancedHandler *game.EnhancedModeHandler // Real-time handler, only set for Enhanced mode
+	stopChan        chan struct{}             // Closed by EndSession to stop background loops
+	replay          *models.Replay            // Append-only command log, saved when the session ends
+	replayMutex     sync.Mutex
+	gameOverOnce    sync.Once // Ensures game over is handled once (turn, timeout or tick loop)
+
+	// Troops each player successfully deployed, by troop spec ID, for their account stats
+	statsMutex     sync.Mutex
+	troopsDeployed [2]map[string]int
+
+	// Simple mode turn timer
+	turnMutex           sync.Mutex
+	turnTimer           *time.Timer
+	turnDeadline        time.Time // When the current turn times out, zero if turns are untimed
+	consecutiveTimeouts [2]int    // Per player, reset when the player acts
+
+	// Disconnect handling, per player. A player who reconnects is reseated with their new
+	// client, so the seats are only read through players and playerClient.
+	connMutex    sync.Mutex
+	seats        [2]Player
+	disconnected [2]bool
+	graceTimers  [2]*time.Timer
+
+	// Clients watching the game, by client ID
+	spectatorMutex sync.Mutex
+	spectators     map[string]*Client
 }
 
 // NewSessionManager creates a new session manager
@@ -42,7 +81,7 @@ func NewSessionManager(server *Server, configLoader *persistence.ConfigLoader) *
 }
 
 // CreateSession creates a new game session between two players
-func (sm *SessionManager) CreateSession(player1, player2 *Client, gameID string, gameMode game.GameMode) (*GameSession, error) {
+func (sm *SessionManager) CreateSession(player1, player2 Player, gameID string, gameMode game.GameMode) (*GameSession, error) {
 	sm.sessionsMutex.Lock()
 	defer sm.sessionsMutex.Unlock()
 
@@ -52,12 +91,12 @@ func (sm *SessionManager) CreateSession(player1, player2 *Client, gameID string,
 	}
 
 	// Load player data
-	player1Data, err := sm.server.authManager.GetPlayerData(player1.Username)
+	player1Data, err := sm.seatPlayerData(player1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load player1 data: %w", err)
 	}
 
-	player2Data, err := sm.server.authManager.GetPlayerData(player2.Username)
+	player2Data, err := sm.seatPlayerData(player2)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load player2 data: %w", err)
 	}
@@ -69,29 +108,35 @@ func (sm *SessionManager) CreateSession(player1, player2 *Client, gameID string,
 	}
 
 	// Convert config maps to maps of pointers as expected by game functions
-	towerSpecsPtr := make(map[string]*models.TowerSpec)
-	for id, spec := range gameConfig.Towers {
-		specCopy := spec // Create a copy to avoid taking address of loop variable
-		towerSpecsPtr[id] = &specCopy
-	}
-	troopSpecsPtr := make(map[string]*models.TroopSpec)
-	for id, spec := range gameConfig.Troops {
-		specCopy := spec // Create a copy
-		troopSpecsPtr[id] = &specCopy
-	}
+	towerSpecsPtr, troopSpecsPtr := game.SpecPointers(gameConfig)
 
-	// Create new game instance, passing the maps of pointers
-	gameInstance := game.NewGame(gameID, player1Data, player2Data, gameMode, towerSpecsPtr, troopSpecsPtr)
+	// Create new game instance, passing the maps of pointers. The seed is recorded on the
+	// game and sent to the players so the match can be reproduced later.
+	seed := time.Now().UnixNano()
+	gameInstance := game.NewGame(gameID, player1Data, player2Data, gameMode, towerSpecsPtr, troopSpecsPtr, seed)
+	log.Printf("Game %s created with seed %d", gameID, seed)
 
 	// Create session struct
 	session := &GameSession{
 		ID:           gameID,
 		Game:         gameInstance,
-		Player1:      player1,
-		Player2:      player2,
+		seats:        [2]Player{player1, player2},
 		GameMode:     gameMode,
 		Active:       true,
 		LastActivity: time.Now(),
+		stopChan:     make(chan struct{}),
+		spectators:   make(map[string]*Client),
+		replay: &models.Replay{
+			GameID:   gameID,
+			GameMode: string(gameMode),
+			Seed:     seed,
+			Players: [2]models.ReplayPlayer{
+				{Username: player1Data.Username, Level: player1Data.Level},
+				{Username: player2Data.Username, Level: player2Data.Level},
+			},
+			Config:   *gameConfig,
+			Commands: make([]models.ReplayCommand, 0),
+		},
 	}
 
 	// Initialize game state using the appropriate mode handler
@@ -103,12 +148,13 @@ func (sm *SessionManager) CreateSession(player1, player2 *Client, gameID string,
 			return nil, fmt.Errorf("failed to start simple game mode: %w", err)
 		}
 		gameInstance.GameState = game.GameStateRunningSimple // Set state after successful start
-	// case game.GameModeEnhanced: // Add this when Enhanced mode is implemented
-	// 	enhancedHandler := game.NewEnhancedModeHandler(gameInstance)
-	// 	if err := enhancedHandler.StartGame(player1Data, player2Data, gameConfig.Towers, gameConfig.Troops); err != nil {
-	// 		return nil, fmt.Errorf("failed to start enhanced game mode: %w", err)
-	// 	}
-	// 	gameInstance.GameState = game.GameStateRunningEnhanced
+	case game.GameModeEnhanced:
+		enhancedHandler := game.NewEnhancedModeHandler(gameInstance)
+		// StartGame sets the RunningEnhanced state, starting mana and the 3-minute end time
+		if err := enhancedHandler.StartGame(player1Data, player2Data, towerSpecsPtr, troopSpecsPtr); err != nil {
+			return nil, fmt.Errorf("failed to start enhanced game mode: %w", err)
+		}
+		session.enhancedHandler = enhancedHandler
 	default:
 		return nil, fmt.Errorf("unsupported game mode: %s", gameMode)
 	}
@@ -121,14 +167,9 @@ func (sm *SessionManager) CreateSession(player1, player2 *Client, gameID string,
 		gameInstance.StartTime = time.Now()
 	}
 
-	// For enhanced mode, set the end time (should be handled by EnhancedModeHandler.StartGame)
-	// if gameMode == game.GameModeEnhanced {
-	// 	 gameInstance.EndTime = gameInstance.StartTime.Add(3 * time.Minute)
-	// }
-
 	// Associate clients with this game session
-	player1.GameID = gameID
-	player2.GameID = gameID
+	player1.SetGameID(gameID)
+	player2.SetGameID(gameID)
 
 	// Start game session processing (e.g., sending initial state)
 	go sm.runGameSession(session)
@@ -136,6 +177,15 @@ func (sm *SessionManager) CreateSession(player1, player2 *Client, gameID string,
 	return session, nil
 }
 
+// seatPlayerData loads the game data of a player about to be seated. Bots have no account
+// and bring their own.
+func (sm *SessionManager) seatPlayerData(player Player) (*models.Player, error) {
+	if bot, ok := player.(*Bot); ok {
+		return bot.playerData(), nil
+	}
+	return sm.server.authManager.GetPlayerData(player.Name())
+}
+
 // GetSession gets a game session by ID
 func (sm *SessionManager) GetSession(gameID string) (*GameSession, bool) {
 	sm.sessionsMutex.RLock()
@@ -147,35 +197,50 @@ func (sm *SessionManager) GetSession(gameID string) (*GameSession, bool) {
 
 // EndSession ends a game session
 func (sm *SessionManager) EndSession(gameID string) {
+	// Remove session from active sessions; only the caller that removes it cleans it up
 	sm.sessionsMutex.Lock()
-	defer sm.sessionsMutex.Unlock()
-
 	session, exists := sm.sessions[gameID]
 	if !exists {
+		sm.sessionsMutex.Unlock()
 		return
 	}
-
-	// Set session as inactive
 	session.Active = false
+	delete(sm.sessions, gameID)
+	sm.sessionsMutex.Unlock()
 
 	// Set game state as finished if not already
+	session.Game.Mutex.Lock()
 	if session.Game.GameState != game.GameStateFinished {
 		session.Game.GameState = game.GameStateFinished
 		if session.Game.EndTime.IsZero() {
 			session.Game.EndTime = time.Now()
 		}
 	}
+	session.Game.Mutex.Unlock()
 
-	// Remove session from active sessions
-	delete(sm.sessions, gameID)
-
-	// Clear game ID from clients
-	if session.Player1 != nil {
-		session.Player1.GameID = ""
+	// Stop any background loops
+	close(session.stopChan)
+	session.turnMutex.Lock()
+	if session.turnTimer != nil {
+		session.turnTimer.Stop()
 	}
-	if session.Player2 != nil {
-		session.Player2.GameID = ""
+	session.turnMutex.Unlock()
+	session.connMutex.Lock()
+	for _, timer := range session.graceTimers {
+		if timer != nil {
+			timer.Stop()
+		}
 	}
+	session.connMutex.Unlock()
+	session.dropSpectators()
+
+	// Persist the replay of this match
+	sm.saveReplay(session)
+
+	// Clear game ID from clients
+	player1, player2 := session.players()
+	player1.SetGameID("")
+	player2.SetGameID("")
 
 	// Notify players that the game has ended (already done in handleGameOver)
 	log.Printf("Game session %s ended and cleaned up", gameID)
@@ -183,55 +248,49 @@ func (sm *SessionManager) EndSession(gameID string) {
 
 // runGameSession handles the main game loop for a session
 func (sm *SessionManager) runGameSession(session *GameSession) {
-	// For simple mode, just send initial game state to both players
-	if session.GameMode == game.GameModeSimple {
-		// Send initial game state to players
-		sm.sendInitialGameState(session)
-	} else if session.GameMode == game.GameModeEnhanced {
-		// Enhanced mode not implemented in Sprint 1
-		log.Printf("Enhanced mode not implemented in Sprint 1")
+	// Send initial game state to both players
+	sm.sendInitialGameState(session)
+
+	// Simple mode is driven by player turns (see HandleDeployTroop)
+	if session.GameMode != game.GameModeEnhanced {
+		return
 	}
 
-	// For Sprint 1, we just initialize the game and don't manage the game loop yet
+	// Enhanced mode: relay each tick's events and state until the game ends
+	for events := range session.enhancedHandler.Run(session.stopChan) {
+		sm.broadcastGameEvents(session, events)
+		sm.sendUpdatedGameState(session)
+	}
+
+	if session.enhancedHandler.IsFinished() {
+		sm.handleGameOver(session)
+	}
 }
 
 // sendInitialGameState sends the initial game state to both players
 func (sm *SessionManager) sendInitialGameState(session *GameSession) {
-	// Convert game state to network representation
-	gameState := convertGameStateToPayload(session.Game, session.Player1.Username)
+	// Start the clock for the first turn so its deadline goes out with the game start
+	sm.startTurnTimer(session)
+	turnTimeoutAt := session.currentTurnDeadline()
 
-	// Prepare game start messages for players
-	p1Payload := &network.GameStartPayload{
-		GameID:           session.ID,
-		OpponentUsername: session.Player2.Username,
-		GameMode:         "simple",
-		YourTurn:         true, // Player 1 goes first in simple mode
-		InitialState:     gameState,
-	}
-
-	// For player 2, adjust the game state view
-	p2GameState := convertGameStateToPayload(session.Game, session.Player2.Username)
-	p2Payload := &network.GameStartPayload{
-		GameID:           session.ID,
-		OpponentUsername: session.Player1.Username,
-		GameMode:         "simple",
-		YourTurn:         false, // Player 2 goes second in simple mode
-		InitialState:     p2GameState,
-	}
+	// Prepare game start messages for players, each from their own perspective
+	p1Payload := sm.gameStartPayload(session, 0, turnTimeoutAt)
+	p2Payload := sm.gameStartPayload(session, 1, turnTimeoutAt)
 
 	// Send game start messages
-	err := session.Player1.Codec.Send(network.MessageTypeGameStart, p1Payload)
+	player1, player2 := session.players()
+	err := player1.Send(network.MessageTypeGameStart, p1Payload)
 	if err != nil {
-		log.Printf("Error sending game start to player %s: %v", session.Player1.Username, err)
+		log.Printf("Error sending game start to player %s: %v", player1.Name(), err)
 	}
 
-	err = session.Player2.Codec.Send(network.MessageTypeGameStart, p2Payload)
+	err = player2.Send(network.MessageTypeGameStart, p2Payload)
 	if err != nil {
-		log.Printf("Error sending game start to player %s: %v", session.Player2.Username, err)
+		log.Printf("Error sending game start to player %s: %v", player2.Name(), err)
 	}
 
 	// Log game start
-	log.Printf("Game %s started between %s and %s", session.ID, session.Player1.Username, session.Player2.Username)
+	log.Printf("Game %s started between %s and %s", session.ID, player1.Name(), player2.Name())
 
 	// Send initial troop choices to Player 1 (who starts)
 	if session.GameMode == game.GameModeSimple && session.Game.CurrentTurnPlayerIndex == 0 {
@@ -239,6 +298,32 @@ func (sm *SessionManager) sendInitialGameState(session *GameSession) {
 	}
 }
 
+// gameStartPayload builds the game start message for the player at playerIndex
+func (sm *SessionManager) gameStartPayload(session *GameSession, playerIndex int, turnTimeoutAt time.Time) *network.GameStartPayload {
+	gameMode := network.GameModeSimple
+	if session.GameMode == game.GameModeEnhanced {
+		gameMode = network.GameModeEnhanced
+	}
+
+	player := session.playerClient(playerIndex)
+	opponent := session.playerClient((playerIndex + 1) % 2)
+
+	session.Game.Mutex.Lock()
+	gameState := convertGameStateToPayload(session.Game, player.Name())
+	yourTurn := session.GameMode == game.GameModeSimple && session.Game.CurrentTurnPlayerIndex == playerIndex
+	session.Game.Mutex.Unlock()
+
+	return &network.GameStartPayload{
+		GameID:           session.ID,
+		OpponentUsername: opponent.Name(),
+		GameMode:         gameMode,
+		YourTurn:         yourTurn,
+		InitialState:     gameState,
+		Seed:             session.Game.Seed,
+		TurnTimeoutAt:    turnTimeoutAt,
+	}
+}
+
 // sendTroopChoicesToCurrentPlayer generates and sends troop choices to the player whose turn it currently is.
 func (sm *SessionManager) sendTroopChoicesToCurrentPlayer(session *GameSession) {
 	if session.Game == nil || session.Game.GameState != game.GameStateRunningSimple {
@@ -257,7 +342,10 @@ func (sm *SessionManager) sendTroopChoicesToCurrentPlayer(session *GameSession)
 	// This might need a more robust way to get the correct handler if multiple modes are active
 	simpleHandler := game.NewSimpleModeHandler(session.Game) // Create a new handler instance for this operation
 
+	// Choices are read by the turn timer, so store them under the game lock
+	session.Game.Mutex.Lock()
 	troopChoicesPayload, err := simpleHandler.GenerateAndStoreTroopChoices(currentPlayerInGame)
+	session.Game.Mutex.Unlock()
 	if err != nil {
 		log.Printf("[Session %s] Error generating troop choices for player %s: %v", session.ID, currentPlayerInGame.Username, err)
 		// Optionally, send an error message to the client if appropriate
@@ -271,41 +359,42 @@ func (sm *SessionManager) sendTroopChoicesToCurrentPlayer(session *GameSession)
 	}
 
 	// Determine which client (Player1 or Player2 of the session) is the current player
-	var targetClient *Client
-	if session.Player1.Username == currentPlayerInGame.Username { // Compare by a unique identifier like Username or ID
-		targetClient = session.Player1
-	} else if session.Player2.Username == currentPlayerInGame.Username {
-		targetClient = session.Player2
+	var targetClient Player
+	if player1, player2 := session.players(); player1.Name() == currentPlayerInGame.Username { // Compare by a unique identifier like Username or ID
+		targetClient = player1
+	} else if player2.Name() == currentPlayerInGame.Username {
+		targetClient = player2
 	} else {
 		log.Printf("[Session %s] Critical error: Could not match PlayerInGame %s to a session client.", session.ID, currentPlayerInGame.Username)
 		return
 	}
 
-	if targetClient == nil || targetClient.Codec == nil {
+	if targetClient == nil {
 		log.Printf("[Session %s] Cannot send troop choices to %s: client or codec is nil.", session.ID, currentPlayerInGame.Username)
 		return
 	}
 
-	log.Printf("[Session %s] Sending troop choices to %s: %+v", session.ID, targetClient.Username, troopChoicesPayload.Choices)
-	err = targetClient.Codec.Send(network.MessageTypeTroopChoices, troopChoicesPayload)
+	log.Printf("[Session %s] Sending troop choices to %s: %+v", session.ID, targetClient.Name(), troopChoicesPayload.Choices)
+	err = targetClient.Send(network.MessageTypeTroopChoices, troopChoicesPayload)
 	if err != nil {
-		log.Printf("[Session %s] Error sending troop choices to player %s: %v", session.ID, targetClient.Username, err)
+		log.Printf("[Session %s] Error sending troop choices to player %s: %v", session.ID, targetClient.Name(), err)
 	}
 }
 
-// convertGameStateToPayload converts a game.Game state to a network.GameStatePayload
-func convertGameStateToPayload(game *game.Game, viewerUsername string) *network.GameStatePayload {
+// convertGameStateToPayload converts a game.Game state to a network.GameStatePayload seen by
+// viewerUsername, or by a spectator when it is empty
+func convertGameStateToPayload(gameInstance *game.Game, viewerUsername string) *network.GameStatePayload {
 	payload := &network.GameStatePayload{
-		Towers: make([]network.TowerInfo, 0, len(game.BoardState.Towers)),
-		Troops: make([]network.TroopInfo, 0, len(game.BoardState.ActiveTroops)),
+		Towers: make([]network.TowerInfo, 0, len(gameInstance.BoardState.Towers)),
+		Troops: make([]network.TroopInfo, 0, len(gameInstance.BoardState.ActiveTroops)),
 	}
 
 	// Add towers to payload
-	for _, tower := range game.BoardState.Towers {
+	for _, tower := range gameInstance.BoardState.Towers {
 		// Find owning player's username
 		var ownerUsername string
 		var ownerID string // Store owner ID for position check
-		for _, player := range game.Players {
+		for _, player := range gameInstance.Players {
 			if player.ID == tower.OwnerPlayerID {
 				ownerUsername = player.Username
 				ownerID = player.ID
@@ -339,10 +428,10 @@ func convertGameStateToPayload(game *game.Game, viewerUsername string) *network.
 	}
 
 	// Add troops to payload
-	for _, troop := range game.BoardState.ActiveTroops {
+	for _, troop := range gameInstance.BoardState.ActiveTroops {
 		// Find owning player's username
 		var ownerUsername string
-		for _, player := range game.Players {
+		for _, player := range gameInstance.Players {
 			if player.ID == troop.OwnerPlayerID {
 				ownerUsername = player.Username
 				break
@@ -361,50 +450,83 @@ func convertGameStateToPayload(game *game.Game, viewerUsername string) *network.
 		payload.Troops = append(payload.Troops, troopInfo)
 	}
 
+	// Enhanced mode also reports mana (from the viewer's perspective) and the time left.
+	// Spectators, who have no viewer username, get each player's mana by name.
+	if gameInstance.GameMode == game.GameModeEnhanced {
+		if viewerUsername == "" {
+			payload.Mana = make(map[string]int, len(gameInstance.Players))
+		}
+		for _, player := range gameInstance.Players {
+			switch {
+			case viewerUsername == "":
+				payload.Mana[player.Username] = player.CurrentMana
+			case player.Username == viewerUsername:
+				payload.YourMana = player.CurrentMana
+			default:
+				payload.OpponentMana = player.CurrentMana
+			}
+		}
+		if timeLeft := time.Until(gameInstance.EndTime); timeLeft > 0 {
+			payload.TimeLeft = int(timeLeft.Seconds())
+		}
+	}
+
 	return payload
 }
 
-// HandleDeployTroop processes a troop deployment request from a client
-func (sm *SessionManager) HandleDeployTroop(client *Client, troopID string) error {
+// HandleDeployTroop processes a troop deployment request from a client or bot
+func (sm *SessionManager) HandleDeployTroop(client Player, troopID string) error {
 	// Get the session for this client
-	session, exists := sm.GetSession(client.GameID)
+	session, exists := sm.GetSession(client.CurrentGameID())
 	if !exists {
-		return fmt.Errorf("game session not found or not active for client %s", client.Username)
+		return fmt.Errorf("game session not found or not active for client %s", client.Name())
 	}
 
+	// Enhanced mode has no turns; deployments are limited by mana instead
+	if session.GameMode == game.GameModeEnhanced {
+		return sm.handleEnhancedDeploy(session, client, troopID)
+	}
+
+	// The turn timer may play or end the turn at any moment, so the turn is read under the
+	// game lock. ProcessTurn checks it again when it applies the deployment.
+	session.Game.Mutex.Lock()
+	running := session.Game.GameState == game.GameStateRunningSimple
+	currentTurn := session.Game.CurrentTurnPlayerIndex
+	session.Game.Mutex.Unlock()
+
 	// Ensure the game is in the correct state (RunningSimple)
-	if session.Game.GameState != game.GameStateRunningSimple {
+	if !running {
 		return fmt.Errorf("game %s is not in RunningSimple state", session.ID)
 	}
 
 	// Determine player index for the game logic
-	playerIndex := -1
-	var otherPlayerClient *Client // Re-declare otherPlayerClient
-	if session.Player1.Username == client.Username {
-		playerIndex = 0
-		otherPlayerClient = session.Player2 // Assign otherPlayerClient
-	} else if session.Player2.Username == client.Username {
-		playerIndex = 1
-		otherPlayerClient = session.Player1 // Assign otherPlayerClient
-	} else {
-		// This case was already handled by the playerIndex check, but defensive
-		return fmt.Errorf("client %s not part of session %s for event broadcasting", client.Username, session.ID)
-	}
-
+	playerIndex := session.playerIndexOf(client)
 	if playerIndex == -1 {
-		return fmt.Errorf("client %s not found in game session %s", client.Username, session.ID)
+		return fmt.Errorf("client %s not found in game session %s", client.Name(), session.ID)
 	}
 
 	// Check if it's the client's turn
-	if session.Game.CurrentTurnPlayerIndex != playerIndex {
-		errMsg := fmt.Sprintf("Not your turn. It is player %d's turn.", session.Game.CurrentTurnPlayerIndex)
-		sendErr := client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{Message: errMsg})
+	if currentTurn != playerIndex {
+		errMsg := fmt.Sprintf("Not your turn. It is player %d's turn.", currentTurn)
+		sendErr := client.Send(network.MessageTypeError, &network.ErrorPayload{Message: errMsg})
 		if sendErr != nil {
-			log.Printf("Error sending 'not your turn' error to %s: %v", client.Username, sendErr)
+			log.Printf("Error sending 'not your turn' error to %s: %v", client.Name(), sendErr)
 		}
 		return fmt.Errorf(errMsg) // Also return error to stop further processing
 	}
 
+	// A deliberate action resets the player's consecutive timeout count
+	session.turnMutex.Lock()
+	session.consecutiveTimeouts[playerIndex] = 0
+	session.turnMutex.Unlock()
+
+	return sm.playSimpleTurn(session, playerIndex, troopID)
+}
+
+// playSimpleTurn plays a Simple mode turn for the player at playerIndex and advances the game
+func (sm *SessionManager) playSimpleTurn(session *GameSession, playerIndex int, troopID string) error {
+	client := session.playerClient(playerIndex)
+
 	// Get the appropriate game handler
 	// For now, we assume SimpleModeHandler. This might need to be stored in GameSession or retrieved based on Game.Mode
 	simpleHandler := game.NewSimpleModeHandler(session.Game)
@@ -415,64 +537,371 @@ func (sm *SessionManager) HandleDeployTroop(client *Client, troopID string) erro
 	// Process the turn logic
 	events, err := simpleHandler.ProcessTurn(playerIndex, "deploy_troop", actionData)
 	if err != nil {
-		log.Printf("Error processing turn for player %s in game %s: %v", client.Username, session.ID, err)
+		log.Printf("Error processing turn for player %s in game %s: %v", client.Name(), session.ID, err)
 		// Send error to client
-		sendErr := client.Codec.Send(network.MessageTypeError, &network.ErrorPayload{Message: err.Error()})
+		sendErr := client.Send(network.MessageTypeError, &network.ErrorPayload{Message: err.Error()})
 		if sendErr != nil {
-			log.Printf("Error sending process turn error to %s: %v", client.Username, sendErr)
+			log.Printf("Error sending process turn error to %s: %v", client.Name(), sendErr)
 		}
 		return err // Return the error from ProcessTurn
 	}
 
-	// Send game events that occurred during the turn (e.g., troop deployed, attacks, damage)
-	for _, event := range events {
-		// Send to current player (client)
-		if err := client.Codec.Send(network.MessageTypeGameEvent, &event); err != nil {
-			log.Printf("Error sending game event to %s: %v", client.Username, err)
-		}
-		// Send to the other player
-		if otherPlayerClient != nil && otherPlayerClient.Codec != nil {
-			if err := otherPlayerClient.Codec.Send(network.MessageTypeGameEvent, &event); err != nil {
-				log.Printf("Error sending game event to %s: %v", otherPlayerClient.Username, err)
-			}
-		} else {
-			log.Printf("Warning: otherPlayerClient or its codec is nil for game %s", session.ID)
+	sm.finishSimpleTurn(session, playerIndex, string(network.MessageTypeDeployTroop), troopID, events)
+	return nil
+}
+
+// autoPlayTurn plays a timed out Simple mode turn by deploying a troop the game picks
+func (sm *SessionManager) autoPlayTurn(session *GameSession, playerIndex int) error {
+	troopID, events, err := game.NewSimpleModeHandler(session.Game).AutoDeploy(playerIndex)
+	if err != nil {
+		// The pick advanced the game's seeded source, so the replay must make it too
+		if troopID != "" {
+			session.recordCommand(playerIndex, models.ReplayCommandAutoDeploy, troopID, "")
 		}
+		return err
 	}
 
+	sm.finishSimpleTurn(session, playerIndex, models.ReplayCommandAutoDeploy, troopID, events)
+	return nil
+}
+
+// finishSimpleTurn records a Simple mode turn the game accepted, sends its events and starts
+// the next turn, or ends the game if the turn won it
+func (sm *SessionManager) finishSimpleTurn(session *GameSession, playerIndex int, cmdType string, troopID string, events []network.GameEventPayload) {
+	// Only turns the game accepted are replayed. Nothing else can be played until the next
+	// player is sent their choices below, so the log stays in turn order.
+	session.recordCommand(playerIndex, cmdType, troopID, "")
+	session.countDeploy(playerIndex, troopID)
+
+	// Send game events that occurred during the turn (e.g., troop deployed, attacks, damage)
+	sm.broadcastGameEvents(session, events)
+
 	// After processing the turn, check for game over
-	if session.Game.GameState == game.GameStateFinished {
+	session.Game.Mutex.Lock()
+	finished := session.Game.GameState == game.GameStateFinished
+	session.Game.Mutex.Unlock()
+	if finished {
 		sm.handleGameOver(session)
-		return nil // Game is over, no further turn actions
+		return // Game is over, no further turn actions
 	}
 
-	// If game is not over, update game state for all players
+	// If game is not over, update game state for all players and start the next turn
 	sm.sendUpdatedGameState(session)
+	sm.startNextTurn(session)
+}
 
-	// Notify players about the turn change
-	sm.notifyTurnChange(session) // This will inform whose turn it is now
+// skipTurn passes a timed out turn that could not be played automatically to the opponent,
+// so the game does not stall waiting for it
+func (sm *SessionManager) skipTurn(session *GameSession, playerIndex int) {
+	events, err := game.NewSimpleModeHandler(session.Game).SkipTurn(playerIndex)
+	if err != nil {
+		log.Printf("[Session %s] Error skipping turn of player %d: %v", session.ID, playerIndex, err)
+		return
+	}
+	session.recordCommand(playerIndex, models.ReplayCommandSkipTurn, "", "")
 
-	// Send troop choices to the NEW current player
+	sm.broadcastGameEvents(session, events)
+	sm.sendUpdatedGameState(session)
+	sm.startNextTurn(session)
+}
+
+// startNextTurn starts the clock for the turn that just began, notifies players about the
+// turn change and sends troop choices to the new current player
+func (sm *SessionManager) startNextTurn(session *GameSession) {
+	sm.startTurnTimer(session)
+	sm.notifyTurnChange(session) // This will inform whose turn it is now
 	sm.sendTroopChoicesToCurrentPlayer(session)
+}
+
+// startTurnTimer sets the deadline for the current Simple mode turn and arms a timer
+// that plays the turn automatically if the player does not act in time
+func (sm *SessionManager) startTurnTimer(session *GameSession) {
+	timeout := sm.server.TurnTimeout
+	if session.GameMode != game.GameModeSimple || timeout <= 0 {
+		return
+	}
+
+	session.Game.Mutex.Lock()
+	turnNumber := session.Game.TurnNumber
+	session.Game.Mutex.Unlock()
+
+	session.turnMutex.Lock()
+	defer session.turnMutex.Unlock()
+
+	if session.turnTimer != nil {
+		session.turnTimer.Stop()
+	}
+	session.turnDeadline = time.Now().Add(timeout)
+	session.turnTimer = time.AfterFunc(timeout, func() {
+		sm.handleTurnTimeout(session, turnNumber)
+	})
+}
+
+// handleTurnTimeout runs when a turn deadline passes. The current player's troop is deployed
+// automatically, or the turn is skipped if that is not possible; after MaxTurnTimeouts
+// consecutive timeouts the player forfeits the match.
+func (sm *SessionManager) handleTurnTimeout(session *GameSession, turnNumber int) {
+	select {
+	case <-session.stopChan:
+		return // Session already ended
+	default:
+	}
+
+	session.Game.Mutex.Lock()
+	stillRunning := session.Game.GameState == game.GameStateRunningSimple
+	sameTurn := session.Game.TurnNumber == turnNumber
+	playerIndex := session.Game.CurrentTurnPlayerIndex
+	player := session.Game.Players[playerIndex]
+	session.Game.Mutex.Unlock()
+
+	if !stillRunning || !sameTurn {
+		return // The player acted just before the deadline
+	}
+
+	session.turnMutex.Lock()
+	session.consecutiveTimeouts[playerIndex]++
+	timeouts := session.consecutiveTimeouts[playerIndex]
+	session.turnMutex.Unlock()
+
+	maxTimeouts := sm.server.MaxTurnTimeouts
+	if maxTimeouts > 0 && timeouts >= maxTimeouts {
+		log.Printf("[Session %s] %s forfeits after %d consecutive turn timeouts", session.ID, player.Username, timeouts)
+		reason := fmt.Sprintf("%s forfeited after %d turn timeouts", player.Username, timeouts)
+		session.recordCommand(playerIndex, models.ReplayCommandForfeit, "", reason)
+		session.Game.Forfeit(playerIndex, reason)
+		sm.handleGameOver(session)
+		return
+	}
+
+	log.Printf("[Session %s] Turn of %s timed out (%d/%d), auto-deploying", session.ID, player.Username, timeouts, maxTimeouts)
+	sm.broadcastGameEvents(session, []network.GameEventPayload{{
+		Message: fmt.Sprintf("%s's turn timed out (%d/%d), a random offered troop is deployed if possible", player.Username, timeouts, maxTimeouts),
+		Time:    time.Now(),
+	}})
+
+	if err := sm.autoPlayTurn(session, playerIndex); err != nil {
+		log.Printf("[Session %s] Error auto-playing turn for %s, skipping it: %v", session.ID, player.Username, err)
+		sm.skipTurn(session, playerIndex)
+	}
+}
+
+// HandlePlayerDisconnect is called when an in-game player's connection drops. The opponent
+// is notified and the player forfeits unless they return within the grace period.
+func (sm *SessionManager) HandlePlayerDisconnect(client *Client) {
+	session, exists := sm.GetSession(client.CurrentGameID())
+	if !exists {
+		return
+	}
+
+	playerIndex := session.playerIndexOf(client)
+	if playerIndex == -1 {
+		return
+	}
+	grace := sm.server.DisconnectGracePeriod
+
+	session.connMutex.Lock()
+	session.disconnected[playerIndex] = true
+	if session.graceTimers[playerIndex] != nil {
+		session.graceTimers[playerIndex].Stop()
+	}
+	session.graceTimers[playerIndex] = time.AfterFunc(grace, func() {
+		sm.handleDisconnectTimeout(session, playerIndex)
+	})
+	session.connMutex.Unlock()
+
+	log.Printf("[Session %s] %s disconnected, forfeiting in %v unless they return", session.ID, client.Username, grace)
+
+	opponent := session.playerClient((playerIndex + 1) % 2)
+	event := &network.GameEventPayload{
+		Message: fmt.Sprintf("%s disconnected. They have %d seconds to return before forfeiting the match.", client.Username, int(grace.Seconds())),
+		Time:    time.Now(),
+	}
+	if err := opponent.Send(network.MessageTypeGameEvent, event); err != nil {
+		log.Printf("[Session %s] Error notifying %s of opponent disconnect: %v", session.ID, opponent.Name(), err)
+	}
+	session.sendToSpectators(network.MessageTypeGameEvent, event)
+}
+
+// handleDisconnectTimeout awards the win to the opponent if the player is still disconnected
+func (sm *SessionManager) handleDisconnectTimeout(session *GameSession, playerIndex int) {
+	select {
+	case <-session.stopChan:
+		return // Session already ended
+	default:
+	}
+
+	session.connMutex.Lock()
+	stillGone := session.disconnected[playerIndex]
+	session.connMutex.Unlock()
+	if !stillGone {
+		return
+	}
+
+	player := session.playerClient(playerIndex)
+	log.Printf("[Session %s] %s did not return in time and forfeits", session.ID, player.Name())
+
+	reason := "Opponent disconnected"
+	session.recordCommand(playerIndex, models.ReplayCommandForfeit, "", reason)
+	session.Game.Forfeit(playerIndex, reason)
+	sm.handleGameOver(session)
+}
+
+// ResumeSession puts a player who logged back in during their grace period back into their
+// game: the session is rebound to the new client, which is sent the current state, its
+// outstanding troop choices and turn status. It reports whether a game was resumed.
+func (sm *SessionManager) ResumeSession(client *Client) bool {
+	session, playerIndex := sm.findSessionByUsername(client.Username)
+	if session == nil {
+		return false
+	}
+
+	session.connMutex.Lock()
+	if !session.disconnected[playerIndex] {
+		session.connMutex.Unlock()
+		return false
+	}
+	session.disconnected[playerIndex] = false
+	if session.graceTimers[playerIndex] != nil {
+		session.graceTimers[playerIndex].Stop()
+		session.graceTimers[playerIndex] = nil
+	}
+	client.SetGameID(session.ID)
+	session.seats[playerIndex] = client
+	session.connMutex.Unlock()
+
+	log.Printf("[Session %s] %s reconnected as client %s", session.ID, client.Username, client.ID)
+
+	// Bring the player up to date with the game as it stands now
+	startPayload := sm.gameStartPayload(session, playerIndex, session.currentTurnDeadline())
+	if err := client.Codec.Send(network.MessageTypeGameStart, startPayload); err != nil {
+		log.Printf("[Session %s] Error resending game start to %s: %v", session.ID, client.Username, err)
+	}
+
+	// Re-offer the troop choices of a turn that was interrupted, rather than drawing new
+	// ones: only game logic may advance the game's seeded source
+	session.Game.Mutex.Lock()
+	var offered []network.TroopChoiceInfo
+	if session.Game.GameState == game.GameStateRunningSimple && session.Game.CurrentTurnPlayerIndex == playerIndex {
+		offered = append(offered, session.Game.Players[playerIndex].OfferedTroopChoices...)
+	}
+	session.Game.Mutex.Unlock()
+	if len(offered) > 0 {
+		if err := client.Codec.Send(network.MessageTypeTroopChoices, &network.TroopChoicesPayload{Choices: offered}); err != nil {
+			log.Printf("[Session %s] Error resending troop choices to %s: %v", session.ID, client.Username, err)
+		}
+	}
+
+	opponent := session.playerClient((playerIndex + 1) % 2)
+	event := &network.GameEventPayload{
+		Message: fmt.Sprintf("%s reconnected. The match continues.", client.Username),
+		Time:    time.Now(),
+	}
+	if err := opponent.Send(network.MessageTypeGameEvent, event); err != nil {
+		log.Printf("[Session %s] Error notifying %s of opponent reconnect: %v", session.ID, opponent.Name(), err)
+	}
+	session.sendToSpectators(network.MessageTypeGameEvent, event)
+
+	return true
+}
+
+// findSessionByUsername returns the active session the user is playing in and their player index
+func (sm *SessionManager) findSessionByUsername(username string) (*GameSession, int) {
+	sm.sessionsMutex.RLock()
+	defer sm.sessionsMutex.RUnlock()
 
+	for _, session := range sm.sessions {
+		if playerIndex := session.playerIndexByName(username); playerIndex != -1 {
+			return session, playerIndex
+		}
+	}
+	return nil, -1
+}
+
+// players returns the players seated in this session
+func (session *GameSession) players() (Player, Player) {
+	session.connMutex.Lock()
+	defer session.connMutex.Unlock()
+	return session.seats[0], session.seats[1]
+}
+
+// playerIndexOf returns the player index of the player in this session, or -1
+func (session *GameSession) playerIndexOf(player Player) int {
+	return session.playerIndexByName(player.Name())
+}
+
+// playerIndexByName returns the player index of the user in this session, or -1
+func (session *GameSession) playerIndexByName(username string) int {
+	player1, player2 := session.players()
+	if player1.Name() == username {
+		return 0
+	}
+	if player2.Name() == username {
+		return 1
+	}
+	return -1
+}
+
+// playerClient returns the player seated at the given player index
+func (session *GameSession) playerClient(playerIndex int) Player {
+	session.connMutex.Lock()
+	defer session.connMutex.Unlock()
+	return session.seats[playerIndex]
+}
+
+// handleEnhancedDeploy deploys a troop in a real-time Enhanced mode game
+func (sm *SessionManager) handleEnhancedDeploy(session *GameSession, client Player, troopID string) error {
+	playerIndex := session.playerIndexOf(client)
+	if playerIndex == -1 {
+		return fmt.Errorf("client %s not found in game session %s", client.Name(), session.ID)
+	}
+
+	events, err := session.enhancedHandler.DeployTroop(playerIndex, troopID)
+	if err != nil {
+		log.Printf("Error deploying troop for player %s in game %s: %v", client.Name(), session.ID, err)
+		sendErr := client.Send(network.MessageTypeError, &network.ErrorPayload{Code: 400, Message: err.Error()})
+		if sendErr != nil {
+			log.Printf("Error sending deploy error to %s: %v", client.Name(), sendErr)
+		}
+		return nil // Not enough mana etc. is a normal game outcome, already reported to the client
+	}
+	session.recordDeploy(playerIndex, troopID)
+	session.countDeploy(playerIndex, troopID)
+
+	sm.broadcastGameEvents(session, events)
+	sm.sendUpdatedGameState(session)
 	return nil
 }
 
 // sendUpdatedGameState sends the current game state to both players
 func (sm *SessionManager) sendUpdatedGameState(session *GameSession) {
-	// Get game state for player 1's perspective
-	p1GameState := convertGameStateToPayload(session.Game, session.Player1.Username)
-	err := session.Player1.Codec.Send(network.MessageTypeStateUpdate, p1GameState)
+	// Snapshot both perspectives under the game lock; Enhanced mode mutates state from its tick loop.
+	// Spectators are checked first: Spectate takes the spectator lock before the game lock.
+	watched := session.hasSpectators()
+	player1, player2 := session.players()
+	session.Game.Mutex.Lock()
+	p1GameState := convertGameStateToPayload(session.Game, player1.Name())
+	p2GameState := convertGameStateToPayload(session.Game, player2.Name())
+	var spectatorState *network.GameStatePayload
+	if watched {
+		spectatorState = convertGameStateToPayload(session.Game, "")
+	}
+	session.Game.Mutex.Unlock()
+
+	// Send game state from player 1's perspective
+	err := player1.Send(network.MessageTypeStateUpdate, p1GameState)
 	if err != nil {
 		log.Printf("Error sending state update to player 1: %v", err)
 	}
 
-	// Get game state for player 2's perspective
-	p2GameState := convertGameStateToPayload(session.Game, session.Player2.Username)
-	err = session.Player2.Codec.Send(network.MessageTypeStateUpdate, p2GameState)
+	// Send game state from player 2's perspective
+	err = player2.Send(network.MessageTypeStateUpdate, p2GameState)
 	if err != nil {
 		log.Printf("Error sending state update to player 2: %v", err)
 	}
+
+	if spectatorState != nil {
+		session.sendToSpectators(network.MessageTypeStateUpdate, spectatorState)
+	}
 }
 
 // notifyTurnChange notifies players about whose turn it is
@@ -483,27 +912,45 @@ func (sm *SessionManager) notifyTurnChange(session *GameSession) {
 	}
 
 	// Create turn change payloads for both players
+	timeoutAt := session.currentTurnDeadline()
 	p1TurnChange := &network.TurnChangePayload{
-		YourTurn: session.Game.CurrentTurnPlayerIndex == 0,
+		YourTurn:  session.Game.CurrentTurnPlayerIndex == 0,
+		TimeoutAt: timeoutAt,
 	}
 	p2TurnChange := &network.TurnChangePayload{
-		YourTurn: session.Game.CurrentTurnPlayerIndex == 1,
+		YourTurn:  session.Game.CurrentTurnPlayerIndex == 1,
+		TimeoutAt: timeoutAt,
 	}
 
 	// Send turn change messages
-	err := session.Player1.Codec.Send(network.MessageTypeTurnChange, p1TurnChange)
+	player1, player2 := session.players()
+	err := player1.Send(network.MessageTypeTurnChange, p1TurnChange)
 	if err != nil {
 		log.Printf("Error sending turn change to player 1: %v", err)
 	}
 
-	err = session.Player2.Codec.Send(network.MessageTypeTurnChange, p2TurnChange)
+	err = player2.Send(network.MessageTypeTurnChange, p2TurnChange)
 	if err != nil {
 		log.Printf("Error sending turn change to player 2: %v", err)
 	}
+
+	// Spectators are told whose turn it is by name
+	session.sendToSpectators(network.MessageTypeTurnChange, &network.TurnChangePayload{
+		TimeoutAt: timeoutAt,
+		Player:    session.playerClient(session.Game.CurrentTurnPlayerIndex).Name(),
+	})
 }
 
-// handleGameOver handles the end of a game
+// handleGameOver handles the end of a game. It may be reached from several paths
+// (a played turn, a turn timeout, the Enhanced tick loop) but only runs once.
 func (sm *SessionManager) handleGameOver(session *GameSession) {
+	session.gameOverOnce.Do(func() {
+		sm.finishGame(session)
+	})
+}
+
+// finishGame awards EXP, notifies both players of the result and ends the session
+func (sm *SessionManager) finishGame(session *GameSession) {
 	var winnerUsername string
 	// var loserUsername string
 	var reason string
@@ -511,86 +958,63 @@ func (sm *SessionManager) handleGameOver(session *GameSession) {
 	// Determine winner/loser from Game struct
 	if session.Game.WinnerID != "" {
 		if session.Game.WinnerID == session.Game.Players[0].ID { // Use Game.Players for ID
-			winnerUsername = session.Player1.Username
-			// loserUsername = session.Player2.Username
+			winnerUsername = session.playerClient(0).Name()
+			// loserUsername = session.playerClient(1).Name()
 		} else {
-			winnerUsername = session.Player2.Username
-			// loserUsername = session.Player1.Username
+			winnerUsername = session.playerClient(1).Name()
+			// loserUsername = session.playerClient(0).Name()
 		}
 		reason = "King Tower destroyed"
 	} else {
 		// Handle draw or other conditions if needed (not applicable for Simple mode win)
 		reason = "Game ended (unknown reason)" // Placeholder
 	}
+	if session.Game.EndReason != "" {
+		reason = session.Game.EndReason
+	}
 
 	// --- Calculate EXP --- (Simple Mode: Only EXP from destroyed towers)
 	p1ExpEarned := calculateDestroyedTowerExp(session.Game.BoardState, session.Game.Players[1].ID, session.Game.TowerSpecs)
 	p2ExpEarned := calculateDestroyedTowerExp(session.Game.BoardState, session.Game.Players[0].ID, session.Game.TowerSpecs)
 
-	// --- Update Player Data ---
-	// Load player data
-	p1Data, err1 := persistence.LoadPlayerData(sm.server.basePath, session.Player1.Username)
-	p2Data, err2 := persistence.LoadPlayerData(sm.server.basePath, session.Player2.Username)
-
-	if err1 != nil {
-		log.Printf("Error loading player data for %s: %v", session.Player1.Username, err1)
-	} else {
-		p1Data.EXP += p1ExpEarned
-	}
-	if err2 != nil {
-		log.Printf("Error loading player data for %s: %v", session.Player2.Username, err2)
-	} else {
-		p2Data.EXP += p2ExpEarned
-	}
-
-	// Check for level ups and save data
-	p1LeveledUp := false
-	p2LeveledUp := false
-
-	if p1Data != nil {
-		for {
-			requiredExp := models.CalculateRequiredExp(p1Data.Level)
-			if p1Data.EXP >= requiredExp {
-				p1Data.Level++
-				p1Data.EXP -= requiredExp
-				p1LeveledUp = true
-			} else {
-				break
-			}
-		}
-		if err := persistence.SavePlayerData(sm.server.basePath, p1Data); err != nil {
-			log.Printf("Error saving player data for %s: %v", p1Data.Username, err)
+	// Enhanced mode also awards EXP for the match result
+	if session.GameMode == game.GameModeEnhanced {
+		switch session.Game.WinnerID {
+		case "":
+			p1ExpEarned += enhancedDrawExp
+			p2ExpEarned += enhancedDrawExp
+		case session.Game.Players[0].ID:
+			p1ExpEarned += enhancedWinExp
+		default:
+			p2ExpEarned += enhancedWinExp
 		}
 	}
 
-	if p2Data != nil {
-		for {
-			requiredExp := models.CalculateRequiredExp(p2Data.Level)
-			if p2Data.EXP >= requiredExp {
-				p2Data.Level++
-				p2Data.EXP -= requiredExp
-				p2LeveledUp = true
-			} else {
-				break
-			}
-		}
-		if err := persistence.SavePlayerData(sm.server.basePath, p2Data); err != nil {
-			log.Printf("Error saving player data for %s: %v", p2Data.Username, err)
-		}
-	}
+	// --- Calculate rating changes --- (from the ratings both players had going in)
+	p1RatingChange, p2RatingChange := sm.ratingChanges(session)
+
+	// --- Update Player Data ---
+	// Each update is a locked read-modify-write, so concurrent games can't lose EXP or stats
+	p1Data, p1LeveledUp := sm.updatePlayerAfterGame(session, 0, p1ExpEarned, p1RatingChange)
+	p2Data, p2LeveledUp := sm.updatePlayerAfterGame(session, 1, p2ExpEarned, p2RatingChange)
+	sm.server.leaderboard.Invalidate()
 
 	// Prepare game over payloads with updated data
 	p1NewTotalExp := 0
 	p1NewLevel := 0
+	p1NewRating := 0
 	if p1Data != nil {
 		p1NewTotalExp = p1Data.EXP
 		p1NewLevel = p1Data.Level
+		p1NewRating = p1Data.CurrentRating()
 	}
 	p2NewTotalExp := 0
 	p2NewLevel := 0
+	p2NewRating := 0
 	if p2Data != nil {
 		p2NewTotalExp = p2Data.EXP
 		p2NewLevel = p2Data.Level
+		p2NewRating = p2Data.CurrentRating()
 	}
 
 	p1GameOver := &network.GameOverPayload{
@@ -600,6 +1024,9 @@ func (sm *SessionManager) handleGameOver(session *GameSession) {
 		NewTotalExp: p1NewTotalExp,
 		NewLevel:    p1NewLevel,
 		LeveledUp:   p1LeveledUp,
+
+		NewRating:    p1NewRating,
+		RatingChange: p1RatingChange,
 	}
 
 	p2GameOver := &network.GameOverPayload{
@@ -609,25 +1036,184 @@ func (sm *SessionManager) handleGameOver(session *GameSession) {
 		NewTotalExp: p2NewTotalExp,
 		NewLevel:    p2NewLevel,
 		LeveledUp:   p2LeveledUp,
+
+		NewRating:    p2NewRating,
+		RatingChange: p2RatingChange,
 	}
 
 	// Send game over messages
-	if session.Player1 != nil && session.Player1.Codec != nil {
-		err := session.Player1.Codec.Send(network.MessageTypeGameOver, p1GameOver)
-		if err != nil {
-			log.Printf("Error sending game over to player %s: %v", session.Player1.Username, err)
+	player1, player2 := session.players()
+	if err := player1.Send(network.MessageTypeGameOver, p1GameOver); err != nil {
+		log.Printf("Error sending game over to player %s: %v", player1.Name(), err)
+	}
+	if err := player2.Send(network.MessageTypeGameOver, p2GameOver); err != nil {
+		log.Printf("Error sending game over to player %s: %v", player2.Name(), err)
+	}
+
+	// Spectators only learn the result
+	session.sendToSpectators(network.MessageTypeGameOver, &network.GameOverPayload{
+		Winner: winnerUsername,
+		Reason: reason,
+	})
+
+	// End the session (cleanup)
+	sm.EndSession(session.ID)
+}
+
+// matchResult returns the result of the finished match for the player at playerIndex
+func matchResult(session *GameSession, playerIndex int) string {
+	switch session.Game.WinnerID {
+	case "":
+		return models.MatchResultDraw
+	case session.Game.Players[playerIndex].ID:
+		return models.MatchResultWin
+	default:
+		return models.MatchResultLoss
+	}
+}
+
+// ratingChanges computes both players' rating changes from the ratings they had before the
+// match. If either player can't be loaded or is a bot, ratings are left unchanged.
+func (sm *SessionManager) ratingChanges(session *GameSession) (int, int) {
+	if player1, player2 := session.players(); isBot(player1) || isBot(player2) {
+		return 0, 0 // Games against bots are unrated
+	}
+
+	var players [2]*models.PlayerData
+	for i := range players {
+		username := session.playerClient(i).Name()
+		playerData, err := sm.server.PlayerStore.LoadPlayer(username)
+		if err != nil || playerData == nil {
+			log.Printf("Error loading rating of %s, ratings of game %s unchanged: %v", username, session.ID, err)
+			return 0, 0
 		}
+		players[i] = playerData
+	}
+
+	p1Rating, p2Rating := players[0].CurrentRating(), players[1].CurrentRating()
+	p1Change := models.RatingChange(p1Rating, p2Rating, models.MatchScore(matchResult(session, 0)), players[0].Stats.GamesPlayed())
+	p2Change := models.RatingChange(p2Rating, p1Rating, models.MatchScore(matchResult(session, 1)), players[1].Stats.GamesPlayed())
+	return p1Change, p2Change
+}
+
+// updatePlayerAfterGame adds the EXP the player at playerIndex earned to their saved data,
+// applies level ups and the rating change and records the match in their stats. It returns
+// the saved data (nil if it could not be updated) and whether the player leveled up.
+func (sm *SessionManager) updatePlayerAfterGame(session *GameSession, playerIndex int, expEarned int, ratingChange int) (*models.PlayerData, bool) {
+	player := session.playerClient(playerIndex)
+	if isBot(player) {
+		return nil, false // Bots have no account to update
+	}
+	opponentIndex := 1 - playerIndex
+
+	record := models.MatchRecord{
+		GameID:    session.ID,
+		GameMode:  string(session.GameMode),
+		Opponent:  session.playerClient(opponentIndex).Name(),
+		Result:    matchResult(session, playerIndex),
+		ExpEarned: expEarned,
+		EndedAt:   time.Now(),
 	}
+	towersDestroyed := countDestroyedTowers(session.Game.BoardState, session.Game.Players[opponentIndex].ID)
+	troopsDeployed := session.deployCounts(playerIndex)
 
-	if session.Player2 != nil && session.Player2.Codec != nil {
-		err := session.Player2.Codec.Send(network.MessageTypeGameOver, p2GameOver)
-		if err != nil {
-			log.Printf("Error sending game over to player %s: %v", session.Player2.Username, err)
+	leveledUp := false
+	playerData, err := sm.server.PlayerStore.UpdatePlayer(player.Name(), func(playerData *models.PlayerData) error {
+		leveledUp = false // The update is rerun if the player changed underneath it
+		playerData.EXP += expEarned
+		for {
+			requiredExp := models.CalculateRequiredExp(playerData.Level)
+			if playerData.EXP < requiredExp {
+				break
+			}
+			playerData.Level++
+			playerData.EXP -= requiredExp
+			leveledUp = true
 		}
+		playerData.Rating = playerData.CurrentRating() + ratingChange
+		playerData.Stats.RecordMatch(record, towersDestroyed, troopsDeployed)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error updating player data for %s: %v", player.Name(), err)
+		return nil, false
 	}
+	return playerData, leveledUp
+}
 
-	// End the session (cleanup)
-	sm.EndSession(session.ID)
+// countDeploy counts a troop the player at playerIndex successfully deployed
+func (session *GameSession) countDeploy(playerIndex int, troopID string) {
+	session.statsMutex.Lock()
+	defer session.statsMutex.Unlock()
+	if session.troopsDeployed[playerIndex] == nil {
+		session.troopsDeployed[playerIndex] = make(map[string]int)
+	}
+	session.troopsDeployed[playerIndex][troopID]++
+}
+
+// deployCounts returns a copy of the troops the player at playerIndex deployed, by troop spec ID
+func (session *GameSession) deployCounts(playerIndex int) map[string]int {
+	session.statsMutex.Lock()
+	defer session.statsMutex.Unlock()
+	counts := make(map[string]int, len(session.troopsDeployed[playerIndex]))
+	for troopID, count := range session.troopsDeployed[playerIndex] {
+		counts[troopID] = count
+	}
+	return counts
+}
+
+// recordDeploy appends a deploy_troop command to the session's replay log
+func (session *GameSession) recordDeploy(playerIndex int, troopID string) {
+	session.recordCommand(playerIndex, string(network.MessageTypeDeployTroop), troopID, "")
+}
+
+// recordCommand appends a command to the session's replay log
+func (session *GameSession) recordCommand(playerIndex int, cmdType string, troopID string, reason string) {
+	session.replayMutex.Lock()
+	defer session.replayMutex.Unlock()
+
+	session.replay.Commands = append(session.replay.Commands, models.ReplayCommand{
+		Time:        time.Now(),
+		PlayerIndex: playerIndex,
+		Type:        cmdType,
+		TroopID:     troopID,
+		Reason:      reason,
+	})
+}
+
+// currentTurnDeadline returns when the current turn times out, zero if turns are untimed
+func (session *GameSession) currentTurnDeadline() time.Time {
+	session.turnMutex.Lock()
+	defer session.turnMutex.Unlock()
+	return session.turnDeadline
+}
+
+// saveReplay fills in the match result and writes the session's replay to data/replays
+func (sm *SessionManager) saveReplay(session *GameSession) {
+	session.Game.Mutex.Lock()
+	startTime, endTime, endReason := session.Game.StartTime, session.Game.EndTime, session.Game.EndReason
+	var winner string
+	for _, player := range session.Game.Players {
+		if player.ID == session.Game.WinnerID {
+			winner = player.Username
+		}
+	}
+	session.Game.Mutex.Unlock()
+
+	session.replayMutex.Lock()
+	defer session.replayMutex.Unlock()
+
+	replay := session.replay
+	replay.StartTime = startTime
+	replay.EndTime = endTime
+	replay.EndReason = endReason
+	replay.Winner = winner
+
+	if err := persistence.SaveReplay(sm.server.basePath, replay); err != nil {
+		log.Printf("Error saving replay for game %s: %v", session.ID, err)
+		return
+	}
+	log.Printf("Replay for game %s saved (%d commands)", session.ID, len(replay.Commands))
 }
 
 // calculateDestroyedTowerExp calculates EXP earned from destroying opponent towers
@@ -644,5 +1230,26 @@ func calculateDestroyedTowerExp(board *game.BoardState, opponentPlayerID string,
 	return expGained
 }
 
-// broadcastGameEvent sends a game event message to both players in a session.
-// ... existing code ...
+// countDestroyedTowers counts the opponent's towers that were destroyed
+func countDestroyedTowers(board *game.BoardState, opponentPlayerID string) int {
+	destroyed := 0
+	for _, tower := range board.Towers {
+		if tower.OwnerPlayerID == opponentPlayerID && tower.CurrentHP <= 0 {
+			destroyed++
+		}
+	}
+	return destroyed
+}
+
+// broadcastGameEvents sends game event messages to both players and the spectators of a session
+func (sm *SessionManager) broadcastGameEvents(session *GameSession, events []network.GameEventPayload) {
+	player1, player2 := session.players()
+	for _, event := range events {
+		for _, p := range []Player{player1, player2} {
+			if err := p.Send(network.MessageTypeGameEvent, &event); err != nil {
+				log.Printf("Error sending game event to %s: %v", p.Name(), err)
+			}
+		}
+		session.sendToSpectators(network.MessageTypeGameEvent, &event)
+	}
+}
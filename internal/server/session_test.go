@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+// waitForReplay waits until the replay of the game has been saved and returns it
+func waitForReplay(t *testing.T, s *Server, gameID string) *models.Replay {
+	t.Helper()
+	deadline := time.Now().Add(testWait)
+	for {
+		replay, err := persistence.LoadReplay(persistence.ReplayPath(s.basePath, gameID))
+		if err == nil {
+			return replay
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replay of game %s was not saved: %v", gameID, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTurnTimeoutAutoDeploysThenForfeits(t *testing.T) {
+	s := newTestServer(t, func(s *Server) {
+		s.TurnTimeout = 100 * time.Millisecond
+		s.MaxTurnTimeouts = 2
+	})
+	addAccount(t, s, "alice", models.DefaultRating)
+	addAccount(t, s, "bob", models.DefaultRating)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+
+	aliceStart, _ := startMatch(t, alice, bob)
+	first, second := alice, bob
+	if !aliceStart.YourTurn {
+		first, second = bob, alice
+	}
+
+	// Neither player acts: each turn is played for them until the first player has
+	// timed out twice in a row
+	first.expectEvent(fmt.Sprintf("%s's turn timed out (1/2)", first.username))
+	first.expectEvent(fmt.Sprintf("%s deployed ", first.username))
+	first.expectEvent(fmt.Sprintf("%s's turn timed out (1/2)", second.username))
+	first.expectEvent(fmt.Sprintf("%s deployed ", second.username))
+
+	for _, c := range []*testClient{first, second} {
+		gameOver := expect[network.GameOverPayload](c, network.MessageTypeGameOver)
+		if gameOver.Winner != second.username {
+			t.Errorf("%s was told %q won, want %q", c.username, gameOver.Winner, second.username)
+		}
+		if want := fmt.Sprintf("%s forfeited after 2 turn timeouts", first.username); gameOver.Reason != want {
+			t.Errorf("%s was told the reason %q, want %q", c.username, gameOver.Reason, want)
+		}
+	}
+
+	replay := waitForReplay(t, s, aliceStart.GameID)
+	var commands []string
+	for _, cmd := range replay.Commands {
+		commands = append(commands, fmt.Sprintf("%d:%s", cmd.PlayerIndex, cmd.Type))
+	}
+	if want := "[0:auto_deploy 1:auto_deploy 0:forfeit]"; fmt.Sprint(commands) != want {
+		t.Errorf("replay commands = %v, want %s", commands, want)
+	}
+}
+
+func TestDisconnectedPlayerForfeitsAfterGracePeriod(t *testing.T) {
+	s := newTestServer(t, func(s *Server) {
+		s.TurnTimeout = 0
+		s.DisconnectGracePeriod = 200 * time.Millisecond
+	})
+	addAccount(t, s, "alice", models.DefaultRating)
+	addAccount(t, s, "bob", models.DefaultRating)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+	aliceStart, _ := startMatch(t, alice, bob)
+
+	alice.conn.Close()
+
+	bob.expectEvent("alice disconnected")
+	gameOver := expect[network.GameOverPayload](bob, network.MessageTypeGameOver)
+	if gameOver.Winner != "bob" || gameOver.Reason != "Opponent disconnected" {
+		t.Errorf("game over = %+v, want bob to win because the opponent disconnected", gameOver)
+	}
+
+	// The game is over once its replay is saved
+	replay := waitForReplay(t, s, aliceStart.GameID)
+	if replay.Winner != "bob" {
+		t.Errorf("replay winner = %q, want bob", replay.Winner)
+	}
+	if games := s.sessionManager.ListGames(); len(games) != 0 {
+		t.Errorf("games still in progress: %+v", games)
+	}
+}
+
+func TestReconnectingPlayerResumesGame(t *testing.T) {
+	s := newTestServer(t, func(s *Server) {
+		s.TurnTimeout = 0
+		s.DisconnectGracePeriod = time.Minute
+	})
+	addAccount(t, s, "alice", models.DefaultRating)
+	addAccount(t, s, "bob", models.DefaultRating)
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+	aliceStart, _ := startMatch(t, alice, bob)
+	first, second := alice, bob
+	if !aliceStart.YourTurn {
+		first, second = bob, alice
+	}
+	offered := expect[network.TroopChoicesPayload](first, network.MessageTypeTroopChoices)
+
+	first.conn.Close()
+	second.expectEvent(first.username + " disconnected")
+	for deadline := time.Now().Add(testWait); s.authManager.IsUserActive(first.username); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is still logged in after disconnecting", first.username)
+		}
+	}
+
+	again := connect(t, s, first.username)
+	start := expect[network.GameStartPayload](again, network.MessageTypeGameStart)
+	if start.GameID != aliceStart.GameID || !start.YourTurn || start.OpponentUsername != second.username {
+		t.Errorf("resumed game start = %+v, want game %s on %s's turn against %s", start, aliceStart.GameID, first.username, second.username)
+	}
+	reoffered := expect[network.TroopChoicesPayload](again, network.MessageTypeTroopChoices)
+	if fmt.Sprint(reoffered.Choices) != fmt.Sprint(offered.Choices) {
+		t.Errorf("re-offered troops %v, want the interrupted turn's %v", reoffered.Choices, offered.Choices)
+	}
+	second.expectEvent(first.username + " reconnected")
+
+	// The match carries on with the new connection
+	again.send(network.MessageTypeDeployTroop, &network.DeployTroopPayload{TroopID: offered.Choices[0].ID})
+	second.expectEvent(first.username + " deployed ")
+	if turn := expect[network.TurnChangePayload](second, network.MessageTypeTurnChange); !turn.YourTurn {
+		t.Errorf("%s was not given the turn after the resumed player deployed", second.username)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"log"
+	"sort"
+
+	"github.com/NP-Dat/net-centric-project/internal/game"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// ListGames returns the games in progress, oldest first
+func (sm *SessionManager) ListGames() []network.GameSummary {
+	sm.sessionsMutex.RLock()
+	sessions := make([]*GameSession, 0, len(sm.sessions))
+	for _, session := range sm.sessions {
+		if session.Active {
+			sessions = append(sessions, session)
+		}
+	}
+	sm.sessionsMutex.RUnlock()
+
+	games := make([]network.GameSummary, 0, len(sessions))
+	for _, session := range sessions {
+		player1, player2 := session.players()
+		session.spectatorMutex.Lock()
+		spectators := len(session.spectators)
+		session.spectatorMutex.Unlock()
+
+		games = append(games, network.GameSummary{
+			GameID:     session.ID,
+			GameMode:   modeName(session.GameMode),
+			Player1:    player1.Name(),
+			Player2:    player2.Name(),
+			Spectators: spectators,
+			StartedAt:  session.Game.StartTime,
+		})
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].StartedAt.Before(games[j].StartedAt) })
+	return games
+}
+
+// Spectate lets the client watch a game in progress and sends it the game as it stands. A
+// client watches one game at a time, so it stops watching any other game. Problems are
+// reported to the client; the returned error is only for failed sends.
+func (sm *SessionManager) Spectate(client *Client, gameID string) error {
+	if client.CurrentGameID() != "" {
+		return sendError(client, 409, "You cannot watch a game while playing one")
+	}
+	session, exists := sm.GetSession(gameID)
+	if !exists || !session.Active {
+		return sendError(client, 404, "No game in progress with ID "+gameID)
+	}
+	sm.StopSpectating(client)
+
+	// The start goes out before the client joins the spectator set, and while it is locked,
+	// so sendToSpectators cannot send the client an update before the start
+	session.spectatorMutex.Lock()
+	defer session.spectatorMutex.Unlock()
+
+	player1, player2 := session.players()
+	start := &network.SpectateStartPayload{
+		GameID:   session.ID,
+		GameMode: modeName(session.GameMode),
+		Player1:  player1.Name(),
+		Player2:  player2.Name(),
+	}
+	if session.GameMode == game.GameModeSimple {
+		start.TurnTimeoutAt = session.currentTurnDeadline()
+	}
+	session.Game.Mutex.Lock()
+	start.State = convertGameStateToPayload(session.Game, "")
+	if session.GameMode == game.GameModeSimple {
+		start.CurrentTurn = session.playerClient(session.Game.CurrentTurnPlayerIndex).Name()
+	}
+	session.Game.Mutex.Unlock()
+
+	if err := client.Codec.Send(network.MessageTypeSpectateStart, start); err != nil {
+		return err
+	}
+	session.spectators[client.ID] = client
+	log.Printf("[Session %s] %s is now spectating", session.ID, client.Username)
+	return nil
+}
+
+// StopSpectating removes the client from the spectators of the game it watches. It reports
+// whether the client was watching one.
+func (sm *SessionManager) StopSpectating(client *Client) bool {
+	session := sm.spectatedSession(client)
+	if session == nil {
+		return false
+	}
+
+	session.spectatorMutex.Lock()
+	delete(session.spectators, client.ID)
+	session.spectatorMutex.Unlock()
+	log.Printf("[Session %s] %s stopped spectating", session.ID, client.Username)
+	return true
+}
+
+// spectatedSession returns the session the client watches, or nil
+func (sm *SessionManager) spectatedSession(client *Client) *GameSession {
+	sm.sessionsMutex.RLock()
+	defer sm.sessionsMutex.RUnlock()
+
+	for _, session := range sm.sessions {
+		session.spectatorMutex.Lock()
+		_, watching := session.spectators[client.ID]
+		session.spectatorMutex.Unlock()
+		if watching {
+			return session
+		}
+	}
+	return nil
+}
+
+// sendToSpectators sends a message to everyone watching the session. The sends happen
+// outside spectatorMutex so a slow spectator holds up nobody else; spectators whose send
+// fails stop watching.
+func (session *GameSession) sendToSpectators(msgType network.MessageType, payload interface{}) {
+	session.spectatorMutex.Lock()
+	spectators := make([]*Client, 0, len(session.spectators))
+	for _, spectator := range session.spectators {
+		spectators = append(spectators, spectator)
+	}
+	session.spectatorMutex.Unlock()
+
+	var failed []*Client
+	for _, spectator := range spectators {
+		if err := spectator.Codec.Send(msgType, payload); err != nil {
+			log.Printf("[Session %s] Error sending %s to spectator %s, dropping them: %v", session.ID, msgType, spectator.Username, err)
+			failed = append(failed, spectator)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	session.spectatorMutex.Lock()
+	defer session.spectatorMutex.Unlock()
+	for _, spectator := range failed {
+		if session.spectators[spectator.ID] == spectator {
+			delete(session.spectators, spectator.ID)
+		}
+	}
+}
+
+// hasSpectators reports whether anyone is watching the session
+func (session *GameSession) hasSpectators() bool {
+	session.spectatorMutex.Lock()
+	defer session.spectatorMutex.Unlock()
+	return len(session.spectators) > 0
+}
+
+// dropSpectators stops everyone from watching the session once it has ended
+func (session *GameSession) dropSpectators() {
+	session.spectatorMutex.Lock()
+	defer session.spectatorMutex.Unlock()
+	session.spectators = make(map[string]*Client)
+}
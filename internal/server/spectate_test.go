@@ -0,0 +1,63 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+)
+
+// listGames asks for the games in progress
+func listGames(c *testClient) []network.GameSummary {
+	c.t.Helper()
+	c.send(network.MessageTypeListGames, &network.ListGamesPayload{})
+	return expect[network.GameListPayload](c, network.MessageTypeGameList).Games
+}
+
+func TestSpectatorJoinsAndLeaves(t *testing.T) {
+	s := newTestServer(t, func(s *Server) { s.TurnTimeout = 0 })
+	for _, username := range []string{"alice", "bob", "carol"} {
+		addAccount(t, s, username, models.DefaultRating)
+	}
+	alice := connect(t, s, "alice")
+	bob := connect(t, s, "bob")
+	aliceStart, _ := startMatch(t, alice, bob)
+	first := alice
+	if !aliceStart.YourTurn {
+		first = bob
+	}
+	carol := connect(t, s, "carol")
+
+	games := listGames(carol)
+	if len(games) != 1 || games[0].GameID != aliceStart.GameID || games[0].Spectators != 0 {
+		t.Fatalf("games = %+v, want game %s without spectators", games, aliceStart.GameID)
+	}
+
+	carol.send(network.MessageTypeSpectate, &network.SpectatePayload{GameID: "no-such-game"})
+	carol.expectError(404)
+	alice.send(network.MessageTypeSpectate, &network.SpectatePayload{GameID: aliceStart.GameID})
+	alice.expectError(409)
+
+	carol.send(network.MessageTypeSpectate, &network.SpectatePayload{GameID: aliceStart.GameID})
+	start := expect[network.SpectateStartPayload](carol, network.MessageTypeSpectateStart)
+	if start.GameID != aliceStart.GameID || start.CurrentTurn != first.username || start.State == nil || start.State.Towers == nil {
+		t.Errorf("spectate start = %+v, want game %s on %s's turn with its towers", start, aliceStart.GameID, first.username)
+	}
+	if games := listGames(carol); len(games) != 1 || games[0].Spectators != 1 {
+		t.Errorf("games = %+v, want one spectator", games)
+	}
+
+	// The spectator follows the match
+	choices := expect[network.TroopChoicesPayload](first, network.MessageTypeTroopChoices)
+	first.send(network.MessageTypeDeployTroop, &network.DeployTroopPayload{TroopID: choices.Choices[0].ID})
+	carol.expectEvent(first.username + " deployed ")
+	expect[network.TurnChangePayload](carol, network.MessageTypeTurnChange)
+
+	carol.send(network.MessageTypeStopSpectating, &network.StopSpectatingPayload{})
+	carol.expectEvent("You stopped watching the game")
+	if games := listGames(carol); len(games) != 1 || games[0].Spectators != 0 {
+		t.Errorf("games = %+v, want no spectators", games)
+	}
+	carol.send(network.MessageTypeStopSpectating, &network.StopSpectatingPayload{})
+	carol.expectError(400)
+}
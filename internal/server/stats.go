@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/NP-Dat/net-centric-project/internal/models"
+	"github.com/NP-Dat/net-centric-project/internal/network"
+	"github.com/NP-Dat/net-centric-project/pkg/logger"
+)
+
+// sendStats answers a stats request with the statistics of username, or of the client's
+// own account if username is empty
+func (s *Server) sendStats(client *Client, username string) error {
+	if username == "" {
+		username = client.Username
+	}
+	username, err := models.CanonicalUsername(username)
+	if err != nil {
+		return sendError(client, 400, err.Error())
+	}
+
+	playerData, err := s.PlayerStore.LoadPlayer(username)
+	if err != nil {
+		logger.Server.Error("Failed to load stats of %s: %v", username, err)
+		return sendError(client, 500, "Could not load player stats")
+	}
+	if playerData == nil {
+		return sendError(client, 404, "No player named "+username)
+	}
+
+	return client.Codec.Send(network.MessageTypeStatsResult, statsPayload(playerData))
+}
+
+// statsPayload converts a player's saved statistics to their protocol form
+func statsPayload(playerData *models.PlayerData) *network.StatsPayload {
+	stats := playerData.Stats
+	payload := &network.StatsPayload{
+		Username:        playerData.Username,
+		Level:           playerData.Level,
+		EXP:             playerData.EXP,
+		Rating:          playerData.CurrentRating(),
+		Wins:            stats.Wins,
+		Losses:          stats.Losses,
+		Draws:           stats.Draws,
+		TowersDestroyed: stats.TowersDestroyed,
+		TroopsDeployed:  stats.TroopsDeployed,
+	}
+	for _, match := range stats.RecentMatches {
+		payload.RecentMatches = append(payload.RecentMatches, network.MatchSummary{
+			GameID:    match.GameID,
+			GameMode:  match.GameMode,
+			Opponent:  match.Opponent,
+			Result:    match.Result,
+			ExpEarned: match.ExpEarned,
+			EndedAt:   match.EndedAt,
+		})
+	}
+	return payload
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTokenTTL is how long a session token stays valid after login
+const DefaultSessionTokenTTL = 12 * time.Hour
+
+// SessionSecretEnv is the environment variable that can hold the secret signing session
+// tokens. It takes precedence over the secret file.
+const SessionSecretEnv = "TCR_SESSION_SECRET"
+
+// minSessionSecretLength is the shortest secret, in bytes, accepted for signing tokens
+const minSessionSecretLength = 16
+
+// Errors returned when a session token cannot be used
+var (
+	ErrInvalidToken = errors.New("invalid session token")
+	ErrExpiredToken = errors.New("session token has expired")
+	ErrRevokedToken = errors.New("session token has been revoked")
+)
+
+// TokenClaims is the signed content of a session token
+type TokenClaims struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	ExpiresAt int64  `json:"exp"` // Unix seconds
+}
+
+// IssueSessionToken creates a signed token that lets the user log in again without their
+// password until it expires or is revoked
+func (am *AuthManager) IssueSessionToken(username string) (string, time.Time, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(am.TokenTTL)
+	claims := TokenClaims{
+		ID:        hex.EncodeToString(idBytes),
+		Username:  username,
+		ExpiresAt: expiresAt.Unix(),
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encodedClaims := base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return encodedClaims + "." + am.signToken(encodedClaims), expiresAt, nil
+}
+
+// ValidateSessionToken checks a token's signature, expiry and revocation and returns its claims
+func (am *AuthManager) ValidateSessionToken(token string) (*TokenClaims, error) {
+	encodedClaims, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(am.signToken(encodedClaims))) {
+		return nil, ErrInvalidToken
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(encodedClaims)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims TokenClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil || claims.Username == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	am.tokensMutex.Lock()
+	defer am.tokensMutex.Unlock()
+	if _, revoked := am.revokedTokens[claims.ID]; revoked {
+		return nil, ErrRevokedToken
+	}
+	return &claims, nil
+}
+
+// RevokeSessionToken makes a token unusable, e.g. when its user logs out
+func (am *AuthManager) RevokeSessionToken(token string) {
+	claims, err := am.ValidateSessionToken(token)
+	if err != nil {
+		return // Invalid, expired or already revoked
+	}
+
+	am.tokensMutex.Lock()
+	defer am.tokensMutex.Unlock()
+
+	// Forget revocations of tokens that have expired anyway
+	now := time.Now().Unix()
+	for id, expiresAt := range am.revokedTokens {
+		if now >= expiresAt {
+			delete(am.revokedTokens, id)
+		}
+	}
+	am.revokedTokens[claims.ID] = claims.ExpiresAt
+	log.Printf("Session token of user %s revoked", claims.Username)
+}
+
+// LoadSessionSecret returns the secret that signs session tokens, so that tokens stay valid
+// across server restarts: the SessionSecretEnv variable if it is set, otherwise the secret
+// kept in path. If neither exists a new secret is generated and, unless path is empty,
+// written to path; generated reports whether that happened.
+func LoadSessionSecret(path string) (secret []byte, generated bool, err error) {
+	if value := os.Getenv(SessionSecretEnv); value != "" {
+		if len(value) < minSessionSecretLength {
+			return nil, false, fmt.Errorf("%s must be at least %d bytes long", SessionSecretEnv, minSessionSecretLength)
+		}
+		return []byte(value), false, nil
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+			if err != nil || len(secret) < minSessionSecretLength {
+				return nil, false, fmt.Errorf("session secret file %s does not hold a hex-encoded secret of at least %d bytes", path, minSessionSecretLength)
+			}
+			return secret, false, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, false, fmt.Errorf("failed to read session secret file: %w", err)
+		}
+	}
+
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, false, fmt.Errorf("failed to generate session token secret: %w", err)
+	}
+	if path == "" {
+		return secret, true, nil
+	}
+
+	// Only the server's user may read the secret; never replace one written meanwhile
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, false, fmt.Errorf("failed to create session secret directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create session secret file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(hex.EncodeToString(secret) + "\n"); err != nil {
+		return nil, false, fmt.Errorf("failed to write session secret file: %w", err)
+	}
+	return secret, true, nil
+}
+
+// signToken returns the HMAC signature of the encoded token claims
+func (am *AuthManager) signToken(encodedClaims string) string {
+	mac := hmac.New(sha256.New, am.tokenSecret)
+	mac.Write([]byte(encodedClaims))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
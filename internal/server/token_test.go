@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NP-Dat/net-centric-project/internal/persistence"
+)
+
+func TestSessionTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   func(am *AuthManager) string
+		wantErr error
+	}{
+		{
+			name:  "issued token",
+			token: func(am *AuthManager) string { return issueToken(t, am) },
+		},
+		{
+			name: "tampered claims",
+			token: func(am *AuthManager) string {
+				claims, signature, _ := strings.Cut(issueToken(t, am), ".")
+				return claims + "x." + signature
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "not a token",
+			token:   func(am *AuthManager) string { return "not-a-token" },
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "signed by another server",
+			token:   func(am *AuthManager) string { return issueToken(t, NewAuthManager(persistence.NewMemoryStore())) },
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "expired",
+			token: func(am *AuthManager) string {
+				am.TokenTTL = -time.Second
+				return issueToken(t, am)
+			},
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name: "revoked",
+			token: func(am *AuthManager) string {
+				token := issueToken(t, am)
+				am.RevokeSessionToken(token)
+				return token
+			},
+			wantErr: ErrRevokedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := NewAuthManager(persistence.NewMemoryStore())
+			claims, err := am.ValidateSessionToken(tt.token(am))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateSessionToken error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Username != "alice" {
+				t.Errorf("token is for %q, want alice", claims.Username)
+			}
+		})
+	}
+}
+
+// issueToken issues a session token for alice
+func issueToken(t *testing.T, am *AuthManager) string {
+	t.Helper()
+	token, expiresAt, err := am.IssueSessionToken("alice")
+	if err != nil {
+		t.Fatalf("IssueSessionToken: %v", err)
+	}
+	if want := time.Now().Add(am.TokenTTL); expiresAt.Sub(want).Abs() > time.Second {
+		t.Errorf("token expires at %v, want about %v", expiresAt, want)
+	}
+	return token
+}
+
+func TestLoadSessionSecret(t *testing.T) {
+	t.Run("generated once and kept", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data", "session_secret")
+		secret, generated, err := LoadSessionSecret(path)
+		if err != nil || !generated {
+			t.Fatalf("LoadSessionSecret = generated %v, %v; want a generated secret", generated, err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Fatalf("secret file not written with mode 0600: %v %v", info, err)
+		}
+
+		again, generated, err := LoadSessionSecret(path)
+		if err != nil || generated || !bytes.Equal(again, secret) {
+			t.Fatalf("second load = generated %v, %v; want the saved secret", generated, err)
+		}
+
+		// Tokens issued before a restart still work after it
+		before := NewAuthManager(persistence.NewMemoryStore())
+		before.tokenSecret = secret
+		after := NewAuthManager(persistence.NewMemoryStore())
+		after.tokenSecret = again
+		if _, err := after.ValidateSessionToken(issueToken(t, before)); err != nil {
+			t.Errorf("token from before the restart rejected: %v", err)
+		}
+	})
+
+	t.Run("environment first", func(t *testing.T) {
+		t.Setenv(SessionSecretEnv, "a-secret-of-enough-length")
+		path := filepath.Join(t.TempDir(), "session_secret")
+		secret, generated, err := LoadSessionSecret(path)
+		if err != nil || generated || string(secret) != "a-secret-of-enough-length" {
+			t.Fatalf("LoadSessionSecret = %q, generated %v, %v; want the environment secret", secret, generated, err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("secret file written although the environment had one: %v", err)
+		}
+	})
+
+	t.Run("short environment secret", func(t *testing.T) {
+		t.Setenv(SessionSecretEnv, "short")
+		if _, _, err := LoadSessionSecret(""); err == nil {
+			t.Error("short secret accepted")
+		}
+	})
+
+	t.Run("damaged file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session_secret")
+		if err := os.WriteFile(path, []byte("not hex"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := LoadSessionSecret(path); err == nil {
+			t.Error("damaged secret file accepted")
+		}
+	})
+
+	t.Run("memory only", func(t *testing.T) {
+		secret, generated, err := LoadSessionSecret("")
+		if err != nil || !generated || len(secret) < minSessionSecretLength {
+			t.Fatalf("LoadSessionSecret = %d bytes, generated %v, %v; want a generated secret", len(secret), generated, err)
+		}
+	})
+}